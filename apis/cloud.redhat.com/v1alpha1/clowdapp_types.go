@@ -14,6 +14,7 @@ package v1alpha1
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -25,8 +26,10 @@ import (
 	apps "k8s.io/api/apps/v1"
 	batch "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -80,6 +83,116 @@ type DatabaseSpec struct {
 	DBResourceSize string `json:"dbResourceSize,omitempty"`
 }
 
+// ObjectStoreBucketSpec defines a storage bucket requested by a ClowdApp. A bucket
+// that only has a name can also be given as a plain string.
+// +kubebuilder:validation:Type=""
+// +kubebuilder:validation:XPreserveUnknownFields
+type ObjectStoreBucketSpec struct {
+	// The name of the bucket.
+	Name string `json:"name"`
+
+	// Rules deleting the objects in the bucket some time after their creation.
+	Expiration []ObjectStoreExpirationRule `json:"expiration,omitempty"`
+
+	// Keeps previous versions of the objects in the bucket when they are overwritten
+	// or deleted.
+	Versioning bool `json:"versioning,omitempty"`
+
+	// The maximum size of the objects in the bucket, for example 5Gi. Only used in
+	// (*_minio_*) mode.
+	Quota *resource.Quantity `json:"quota,omitempty"`
+
+	// Files uploaded to the bucket when it is created, and again whenever they
+	// change. Only used in (*_minio_*) mode.
+	Seed *ObjectStoreSeedSpec `json:"seed,omitempty"`
+
+	// Publishes events on the objects in the bucket to the app's Kafka topics. Only
+	// used in (*_minio_*) mode.
+	Notifications []ObjectStoreNotificationSpec `json:"notifications,omitempty"`
+}
+
+// ObjectStoreEventType is a kind of event on the objects in a bucket.
+// +kubebuilder:validation:Enum={"objectCreated", "objectDeleted"}
+type ObjectStoreEventType string
+
+const (
+	// ObjectStoreEventObjectCreated is sent when an object is created or overwritten.
+	ObjectStoreEventObjectCreated ObjectStoreEventType = "objectCreated"
+	// ObjectStoreEventObjectDeleted is sent when an object is deleted.
+	ObjectStoreEventObjectDeleted ObjectStoreEventType = "objectDeleted"
+)
+
+// ObjectStoreNotificationSpec publishes events on the objects in a bucket to a Kafka topic.
+type ObjectStoreNotificationSpec struct {
+	// The events published.
+	// +kubebuilder:validation:MinItems:=1
+	Events []ObjectStoreEventType `json:"events"`
+
+	// The topic the events are published to. It must be one of the app's kafkaTopics
+	// that the app can produce to, as the events are published with its credentials.
+	Topic string `json:"topic"`
+
+	// Only events on objects whose keys start with the prefix are published.
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// ObjectStoreSeedSpec defines where the files seeding a bucket come from. Exactly one
+// of configMap and url must be set.
+type ObjectStoreSeedSpec struct {
+	// The name of a ConfigMap in the app's namespace, each key of which is uploaded
+	// as an object of the same name.
+	ConfigMap string `json:"configMap,omitempty"`
+
+	// The URL of a tar archive, optionally gzipped, served by an in-cluster service
+	// or by a host the operator allows. Each file in the archive is uploaded as an
+	// object named after its path. The archive is only downloaded again when the URL
+	// changes, so a new version should be served at a new URL.
+	URL string `json:"url,omitempty"`
+}
+
+// ObjectStoreExpirationRule deletes the objects under a prefix some days after their creation.
+type ObjectStoreExpirationRule struct {
+	// Only objects whose keys start with the prefix expire. If unset, every object does.
+	Prefix string `json:"prefix,omitempty"`
+
+	// How many days after their creation the objects are deleted.
+	// +kubebuilder:validation:Minimum:=1
+	Days int32 `json:"days"`
+}
+
+// UnmarshalJSON accepts either a bucket name or a bucket object.
+func (b *ObjectStoreBucketSpec) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*b = ObjectStoreBucketSpec{Name: name}
+		return nil
+	}
+
+	type plain ObjectStoreBucketSpec
+	return json.Unmarshal(data, (*plain)(b))
+}
+
+// MarshalJSON writes buckets that only have a name as a plain string, as they were before
+// buckets could have options.
+func (b ObjectStoreBucketSpec) MarshalJSON() ([]byte, error) {
+	if !b.HasOptions() {
+		return json.Marshal(b.Name)
+	}
+
+	type plain ObjectStoreBucketSpec
+	return json.Marshal(plain(b))
+}
+
+// HasOptions returns whether the bucket sets anything besides its name.
+func (b ObjectStoreBucketSpec) HasOptions() bool {
+	return b.HasSettings() || b.Seed != nil || len(b.Notifications) > 0
+}
+
+// HasSettings returns whether the bucket sets expiration rules, versioning or a quota.
+func (b ObjectStoreBucketSpec) HasSettings() bool {
+	return len(b.Expiration) > 0 || b.Versioning || b.Quota != nil
+}
+
 // Job defines a ClowdJob
 // A Job struct will deploy as a CronJob if `schedule` is set
 // and will deploy as a Job if it is not set. Unsupported fields
@@ -96,6 +209,11 @@ type Job struct {
 	// Defines the schedule for the job to run
 	Schedule string `json:"schedule,omitempty"`
 
+	// Phase marks the job as one Clowder runs itself as part of a rollout. A preDeploy job is
+	// run whenever the image or spec of the app changes, and updates to the app's deployments
+	// are held back until it succeeds. A preDeploy job cannot have a schedule.
+	Phase JobPhase `json:"phase,omitempty"`
+
 	// Defines the parallelism of the job
 	Parallelism *int32 `json:"parallelism,omitempty"`
 
@@ -135,6 +253,19 @@ type Job struct {
 	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
 }
 
+// JobPhase defines when Clowder runs a job on its own.
+// +kubebuilder:validation:Enum=preDeploy
+type JobPhase string
+
+// JobPhasePreDeploy jobs run before the app's deployments are updated, typically to migrate a
+// database schema.
+const JobPhasePreDeploy JobPhase = "preDeploy"
+
+// IsPreDeploy returns true if the job runs ahead of a rollout.
+func (j *Job) IsPreDeploy() bool {
+	return j.Phase == JobPhasePreDeploy
+}
+
 // WebDeprecated defines a boolean flag to help distinguish from the newer WebServices
 type WebDeprecated bool
 
@@ -257,6 +388,58 @@ type Deployment struct {
 	DeploymentStrategy *DeploymentStrategy `json:"deploymentStrategy,omitempty"`
 
 	Metadata DeploymentMetadata `json:"metadata,omitempty"`
+
+	// Kind sets the type of workload the deployment is rendered as, either a Deployment (the
+	// default) or a StatefulSet. A StatefulSet gives each replica a stable identity, its own
+	// volumes from VolumeClaimTemplates and a headless service named <app>-<pod>-headless.
+	// +kubebuilder:validation:Enum={"Deployment","StatefulSet"}
+	Kind WorkloadKind `json:"kind,omitempty"`
+
+	// VolumeClaimTemplates defines the per-replica volumes of a StatefulSet, they can be
+	// mounted with PodSpec.VolumeMounts using the template name. Kubernetes does not allow
+	// these to change once the StatefulSet has been created. Only used when Kind is StatefulSet.
+	VolumeClaimTemplates []VolumeClaimTemplate `json:"volumeClaimTemplates,omitempty"`
+
+	// DisruptionBudget creates a PodDisruptionBudget for the deployment, overriding the
+	// default set in the ClowdEnvironment. An empty disruptionBudget opts the deployment out
+	// of the environment default.
+	DisruptionBudget *DisruptionBudget `json:"disruptionBudget,omitempty"`
+}
+
+// DisruptionBudget defines the PodDisruptionBudget for a deployment, only one of MinAvailable
+// and MaxUnavailable may be set.
+type DisruptionBudget struct {
+	// The number or percentage of pods that must stay available during a voluntary disruption
+	// such as a node drain.
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// The number or percentage of pods that can be unavailable during a voluntary disruption
+	// such as a node drain.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// IsEmpty returns true if neither MinAvailable nor MaxUnavailable is set
+func (d *DisruptionBudget) IsEmpty() bool {
+	return d.MinAvailable == nil && d.MaxUnavailable == nil
+}
+
+// WorkloadKind is the type of Kubernetes workload a Deployment is rendered as
+type WorkloadKind string
+
+const (
+	// WorkloadDeployment renders the deployment as an apps/v1 Deployment
+	WorkloadDeployment WorkloadKind = "Deployment"
+	// WorkloadStatefulSet renders the deployment as an apps/v1 StatefulSet
+	WorkloadStatefulSet WorkloadKind = "StatefulSet"
+)
+
+// VolumeClaimTemplate defines a persistent volume claim created for each replica of a StatefulSet
+type VolumeClaimTemplate struct {
+	// Name of the claim template, used as the volume name in VolumeMounts
+	Name string `json:"name"`
+
+	// Spec of the persistent volume claim created for each replica
+	Spec v1.PersistentVolumeClaimSpec `json:"spec"`
 }
 
 // GetWebServices returns the web services configuration for this deployment
@@ -276,6 +459,11 @@ func (d *Deployment) GetReplicaCount() *int32 {
 	return &retVal
 }
 
+// IsStatefulSet returns true if this deployment is rendered as a StatefulSet
+func (d *Deployment) IsStatefulSet() bool {
+	return d.Kind == WorkloadStatefulSet
+}
+
 // HasAutoScaler returns true if this deployment has autoscaling configured
 func (d *Deployment) HasAutoScaler() bool {
 	return d.AutoScaler != nil || d.AutoScalerSimple != nil
@@ -474,6 +662,143 @@ type KafkaTopicSpec struct {
 	// +kubebuilder:validation:MaxLength:=249
 	// +kubebuilder:validation:Pattern:="[a-zA-Z0-9\\._\\-]"
 	TopicName string `json:"topicName"`
+
+	// The app's role on this topic, produce, consume or both. Apps which set no role and no
+	// consumer groups on any of their topics keep unrestricted access to their topics and to all
+	// consumer groups.
+	// +optional
+	Role KafkaTopicRole `json:"role,omitempty"`
+
+	// The consumer groups the app uses to consume this topic. The app is granted access to every
+	// group whose name starts with one of these.
+	// +optional
+	ConsumerGroups []string `json:"consumerGroups,omitempty"`
+
+	// Creates a dead-letter topic, <topicName>.dlq, and optionally retry topics alongside this
+	// topic, with the same partitions, replicas and consumer groups.
+	// +optional
+	DeadLetter *KafkaDeadLetterSpec `json:"deadLetter,omitempty"`
+}
+
+// KafkaDeadLetterSpec defines the dead-letter and retry topics created for a topic
+type KafkaDeadLetterSpec struct {
+	// The number of retry topics, <topicName>.retry.1 to <topicName>.retry.N, to create in front
+	// of the dead-letter topic.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=5
+	RetryTopics int32 `json:"retryTopics,omitempty"`
+
+	// A key/value pair describing the configuration of the dead-letter and retry topics, such as
+	// their retention.
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+}
+
+// KafkaTopicRole is the role an app has on a topic.
+// +kubebuilder:validation:Enum={"produce", "consume", "both"}
+type KafkaTopicRole string
+
+const (
+	// KafkaTopicRoleProduce allows the app to write to the topic.
+	KafkaTopicRoleProduce KafkaTopicRole = "produce"
+	// KafkaTopicRoleConsume allows the app to read from the topic.
+	KafkaTopicRoleConsume KafkaTopicRole = "consume"
+	// KafkaTopicRoleBoth allows the app to read from and write to the topic.
+	KafkaTopicRoleBoth KafkaTopicRole = "both"
+)
+
+// CanProduce returns true if the app may write to the topic, apps with no role set may.
+func (t *KafkaTopicSpec) CanProduce() bool {
+	return t.Role != KafkaTopicRoleConsume
+}
+
+// CanConsume returns true if the app may read from the topic, apps with no role set may.
+func (t *KafkaTopicSpec) CanConsume() bool {
+	return t.Role != KafkaTopicRoleProduce
+}
+
+// DeadLetterTopicName returns the name requested for the dead-letter topic of the topic.
+func (t *KafkaTopicSpec) DeadLetterTopicName() string {
+	return fmt.Sprintf("%s.dlq", t.TopicName)
+}
+
+// RetryTopicName returns the name requested for one of the retry topics of the topic, counting
+// from 1.
+func (t *KafkaTopicSpec) RetryTopicName(tier int32) string {
+	return fmt.Sprintf("%s.retry.%d", t.TopicName, tier)
+}
+
+// CompanionTopics returns the retry topics, in order, followed by the dead-letter topic declared by
+// the topic's deadLetter option.
+func (t *KafkaTopicSpec) CompanionTopics() []KafkaTopicSpec {
+	if t.DeadLetter == nil {
+		return nil
+	}
+
+	companion := func(name string) KafkaTopicSpec {
+		return KafkaTopicSpec{
+			TopicName:      name,
+			Partitions:     t.Partitions,
+			Replicas:       t.Replicas,
+			Config:         t.DeadLetter.Config,
+			ConsumerGroups: t.ConsumerGroups,
+		}
+	}
+
+	topics := []KafkaTopicSpec{}
+	for tier := int32(1); tier <= t.DeadLetter.RetryTopics; tier++ {
+		topics = append(topics, companion(t.RetryTopicName(tier)))
+	}
+	return append(topics, companion(t.DeadLetterTopicName()))
+}
+
+// KafkaConnectorSpec defines a Kafka Connect connector that Clowder runs for a ClowdApp on the
+// environment's Kafka Connect cluster
+type KafkaConnectorSpec struct {
+	// The name of the connector, the KafkaConnector is named <app>-<name>.
+	// +kubebuilder:validation:MinLength:=1
+	// +kubebuilder:validation:MaxLength:=63
+	// +kubebuilder:validation:Pattern:="^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"
+	Name string `json:"name"`
+
+	// The Java class of the connector.
+	// +kubebuilder:validation:MinLength:=1
+	Class string `json:"class"`
+
+	// The maximum number of tasks for the connector. If unset, default is '1'
+	// +optional
+	// +kubebuilder:validation:Minimum:=1
+	TasksMax int32 `json:"tasksMax,omitempty"`
+
+	// The connector configuration. Values are Go templates rendered against the app's
+	// cdappconfig, so that a connector can refer to the app's database and buckets, e.g.
+	// '{{ .Database.Hostname }}' or '{{ (bucket "my-bucket").Name }}'. Templated values are
+	// passed to the connector through a secret rather than in the KafkaConnector spec.
+	// +optional
+	Config map[string]string `json:"config,omitempty"`
+
+	// Pause stops the connector without deleting it.
+	// +optional
+	Pause bool `json:"pause,omitempty"`
+}
+
+// KafkaQuotaSpec defines the Kafka client quotas of an app. Byte rates are per broker.
+type KafkaQuotaSpec struct {
+	// The bytes per second the app can produce to each broker before it is throttled.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	ProducerByteRate *int32 `json:"producerByteRate,omitempty"`
+
+	// The bytes per second the app can consume from each broker before it is throttled.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	ConsumerByteRate *int32 `json:"consumerByteRate,omitempty"`
+
+	// The percentage of the network and I/O threads of each broker the app can use.
+	// +optional
+	// +kubebuilder:validation:Minimum:=0
+	RequestPercentage *int32 `json:"requestPercentage,omitempty"`
 }
 
 // TestingSpec defines the testing configuration for a ClowdApp
@@ -499,13 +824,23 @@ type ClowdAppSpec struct {
 	// the pods listed in the ClowdApp.
 	KafkaTopics []KafkaTopicSpec `json:"kafkaTopics,omitempty"`
 
+	// A list of Kafka Connect connectors that will be run for the app on the
+	// environment's Kafka Connect cluster.
+	KafkaConnectors []KafkaConnectorSpec `json:"kafkaConnectors,omitempty"`
+
+	// The Kafka client quotas of the app. Fields that are not set fall back to the
+	// environment's default quotas. Only used in (*_operator_*) mode.
+	// +optional
+	KafkaQuotas *KafkaQuotaSpec `json:"kafkaQuotas,omitempty"`
+
 	// The database specification defines a single database, the configuration
 	// of which will be made available to all the pods in the ClowdApp.
 	Database DatabaseSpec `json:"database,omitempty"`
 
-	// A list of string names defining storage buckets. In certain modes,
-	// defined by the ClowdEnvironment, Clowder will create those buckets.
-	ObjectStore []string `json:"objectStore,omitempty"`
+	// A list of storage buckets, each either a bucket name or a bucket with
+	// options. In certain modes, defined by the ClowdEnvironment, Clowder will
+	// create those buckets.
+	ObjectStore []ObjectStoreBucketSpec `json:"objectStore,omitempty"`
 
 	// If inMemoryDb is set to true, Clowder will pass configuration
 	// of an In Memory Database to the pods in the ClowdApp. This single
@@ -553,6 +888,24 @@ const (
 	ReconciliationFailed string = "ReconciliationFailed"
 	// JobInvocationComplete means all the Jobs have finished
 	JobInvocationComplete string = "JobInvocationComplete"
+	// DependenciesResolved means no dependency cycles or dependencies on unknown apps were found
+	DependenciesResolved string = "DependenciesResolved"
+	// PreDeployJobsComplete means the preDeploy jobs for the current spec have succeeded and the
+	// deployments have been allowed to roll out
+	PreDeployJobsComplete string = "PreDeployJobsComplete"
+	// KafkaConnectorsReady means every Kafka Connect connector declared by the app is running
+	KafkaConnectorsReady string = "KafkaConnectorsReady"
+	// KafkaTopicsInSync means the live settings of the app's topics match those declared by the
+	// apps in the environment
+	KafkaTopicsInSync string = "KafkaTopicsInSync"
+	// KafkaConsumersCaughtUp means the lag of every consumer group declared by the app is within the
+	// threshold set by the environment
+	KafkaConsumersCaughtUp string = "KafkaConsumersCaughtUp"
+	// ObjectStoreBucketsInSync means the settings of the app's provisioned buckets match the options
+	// declared for them
+	ObjectStoreBucketsInSync string = "ObjectStoreBucketsInSync"
+	// ObjectStoreBucketsSeeded means the seed of each of the app's seeded buckets has been uploaded
+	ObjectStoreBucketsSeeded string = "ObjectStoreBucketsSeeded"
 )
 
 // ClowdAppStatus defines the observed state of ClowdApp
@@ -564,12 +917,68 @@ type ClowdAppStatus struct {
 	Ready       bool               `json:"ready"`
 	Conditions  []metav1.Condition `json:"conditions,omitempty"`
 	Generation  int64              `json:"generation,omitempty"`
+
+	// The state of each Kafka Connect connector declared by the ClowdApp, sorted by name.
+	// +optional
+	KafkaConnectors []KafkaConnectorReadiness `json:"kafkaConnectors,omitempty"`
 }
 
 // AppResourceStatus defines the status of an app resource
 type AppResourceStatus struct {
 	ManagedDeployments int32 `json:"managedDeployments"`
 	ReadyDeployments   int32 `json:"readyDeployments"`
+
+	// A breakdown of every deployment managed by the ClowdApp, sorted by name.
+	// +optional
+	Details []DeploymentReadiness `json:"details,omitempty"`
+}
+
+// DeploymentReadiness describes the rollout state of a single deployment managed by a ClowdApp.
+type DeploymentReadiness struct {
+	// The name of the Kubernetes Deployment.
+	Name string `json:"name"`
+
+	// The number of replicas requested in the deployment spec.
+	DesiredReplicas int32 `json:"desiredReplicas"`
+
+	// The number of replicas reporting ready.
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// The number of replicas running the latest pod template.
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+
+	// The generation of the deployment last observed by the deployment controller.
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// Whether the deployment is fully rolled out and available.
+	Ready bool `json:"ready"`
+
+	// The most recent failure reason found in the deployment's pods, such as
+	// ImagePullBackOff, CrashLoopBackOff or Unschedulable.
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// The message accompanying the failure reason.
+	// +optional
+	FailureMessage string `json:"failureMessage,omitempty"`
+}
+
+// KafkaConnectorReadiness describes the state of a single Kafka Connect connector declared by a
+// ClowdApp.
+type KafkaConnectorReadiness struct {
+	// The name of the KafkaConnector.
+	Name string `json:"name"`
+
+	// The connector state reported by Kafka Connect, such as RUNNING, PAUSED or FAILED.
+	// +optional
+	State string `json:"state,omitempty"`
+
+	// Whether Strimzi reports the connector as ready.
+	Ready bool `json:"ready"`
+
+	// The message accompanying the connector's condition, if it is not ready.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -606,6 +1015,26 @@ func init() {
 }
 
 // GetConditions returns the conditions for this ClowdApp
+// GetKafkaTopics returns the app's topics, each followed by the retry and dead-letter topics it
+// declares.
+func (i *ClowdApp) GetKafkaTopics() []KafkaTopicSpec {
+	topics := []KafkaTopicSpec{}
+	for j := range i.Spec.KafkaTopics {
+		topics = append(topics, i.Spec.KafkaTopics[j])
+		topics = append(topics, i.Spec.KafkaTopics[j].CompanionTopics()...)
+	}
+	return topics
+}
+
+// GetObjectStoreBucketNames returns the names of the buckets requested by the app.
+func (i *ClowdApp) GetObjectStoreBucketNames() []string {
+	names := []string{}
+	for _, bucket := range i.Spec.ObjectStore {
+		names = append(names, bucket.Name)
+	}
+	return names
+}
+
 func (i *ClowdApp) GetConditions() []metav1.Condition {
 	return i.Status.Conditions
 }
@@ -0,0 +1,34 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestObjectStoreBucketSpecJSON(t *testing.T) {
+	spec := ClowdAppSpec{}
+	err := json.Unmarshal([]byte(`{
+		"envName": "env",
+		"objectStore": [
+			"plain-bucket",
+			{"name": "uploads", "versioning": true, "quota": "5Gi", "expiration": [{"prefix": "tmp/", "days": 7}]}
+		]
+	}`), &spec)
+	assert.NoError(t, err)
+
+	quota := resource.MustParse("5Gi")
+	assert.Equal(t, []ObjectStoreBucketSpec{
+		{Name: "plain-bucket"},
+		{Name: "uploads", Versioning: true, Quota: &quota, Expiration: []ObjectStoreExpirationRule{{Prefix: "tmp/", Days: 7}}},
+	}, spec.ObjectStore)
+
+	data, err := json.Marshal(spec.ObjectStore)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		"plain-bucket",
+		{"name": "uploads", "versioning": true, "quota": "5Gi", "expiration": [{"prefix": "tmp/", "days": 7}]}
+	]`, string(data), "buckets without options are written back as plain names")
+}
@@ -19,13 +19,19 @@ package v1alpha1
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
 
 	apps "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -37,7 +43,7 @@ var clowdapplog = logf.Log.WithName("clowdapp-resource")
 func (i *ClowdApp) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(i).
-		WithValidator(i).
+		WithValidator(&ClowdAppValidator{Reader: mgr.GetAPIReader()}).
 		Complete()
 }
 
@@ -57,6 +63,14 @@ func (i *ClowdApp) ValidateCreate(_ context.Context, obj runtime.Object) (admiss
 		validateSidecars,
 		validateInit,
 		validateDeploymentStrategy,
+		validateStatefulSets,
+		validateDisruptionBudgets,
+		validatePreDeployJobs,
+		validateKafkaTopicRoles,
+		validateKafkaDeadLetterTopics,
+		validateKafkaConnectors,
+		validateObjectStoreSeeds,
+		validateObjectStoreNotifications,
 	)
 }
 
@@ -73,6 +87,14 @@ func (i *ClowdApp) ValidateUpdate(_ context.Context, _ runtime.Object, newObj ru
 		validateSidecars,
 		validateInit,
 		validateDeploymentStrategy,
+		validateStatefulSets,
+		validateDisruptionBudgets,
+		validatePreDeployJobs,
+		validateKafkaTopicRoles,
+		validateKafkaDeadLetterTopics,
+		validateKafkaConnectors,
+		validateObjectStoreSeeds,
+		validateObjectStoreNotifications,
 	)
 }
 
@@ -82,6 +104,87 @@ func (i *ClowdApp) ValidateDelete(_ context.Context, _ runtime.Object) (admissio
 	return []string{}, nil
 }
 
+// ClowdAppValidator validates ClowdApps, on their own and against the limits set by the
+// ClowdEnvironment they are deployed in.
+// +kubebuilder:object:generate=false
+type ClowdAppValidator struct {
+	Reader client.Reader
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdAppValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clowdApp, ok := obj.(*ClowdApp)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdApp but got %T", obj)
+	}
+	if warnings, err := clowdApp.ValidateCreate(ctx, obj); err != nil {
+		return warnings, err
+	}
+	return v.validateAgainstEnv(ctx, clowdApp, nil)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdAppValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	clowdApp, ok := newObj.(*ClowdApp)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdApp but got %T", newObj)
+	}
+	// An app being deleted only sees updates that remove its finalizers, which must not be held
+	// up by the env's current limits.
+	if clowdApp.GetDeletionTimestamp() != nil {
+		return []string{}, nil
+	}
+	oldApp, ok := oldObj.(*ClowdApp)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdApp but got %T", oldObj)
+	}
+	if warnings, err := clowdApp.ValidateUpdate(ctx, oldObj, newObj); err != nil {
+		return warnings, err
+	}
+	return v.validateAgainstEnv(ctx, clowdApp, oldApp)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdAppValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clowdApp, ok := obj.(*ClowdApp)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdApp but got %T", obj)
+	}
+	return clowdApp.ValidateDelete(ctx, obj)
+}
+
+// validateAgainstEnv checks the app against the limits of its ClowdEnvironment. On update, oldApp
+// is the app being replaced, and only the settings that change are checked so that lowering a
+// limit does not block unrelated updates of the apps already above it.
+func (v *ClowdAppValidator) validateAgainstEnv(ctx context.Context, app *ClowdApp, oldApp *ClowdApp) (admission.Warnings, error) {
+	if oldApp != nil && equality.Semantic.DeepEqual(oldApp.Spec.KafkaQuotas, app.Spec.KafkaQuotas) {
+		return []string{}, nil
+	}
+
+	env := &ClowdEnvironment{}
+	if err := v.Reader.Get(ctx, types.NamespacedName{Name: app.Spec.EnvName}, env); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The reconciler waits for the environment to show up, the app is checked again on
+			// its next update.
+			return []string{}, nil
+		}
+		// The env limits are also enforced by the provider, so a failed read does not hold up
+		// the app.
+		clowdapplog.Error(err, "could not read environment", "name", app.Name, "env", app.Spec.EnvName)
+		return []string{fmt.Sprintf("spec.envName: could not check the app against ClowdEnvironment %s: %s", app.Spec.EnvName, err)}, nil
+	}
+
+	allErrs := validateKafkaQuotas(app, env)
+	if len(allErrs) == 0 {
+		return []string{}, nil
+	}
+
+	return []string{}, apierrors.NewInvalid(
+		schema.GroupKind{Group: "cloud.redhat.com", Kind: "ClowdApp"},
+		app.Name, allErrs,
+	)
+}
+
 type appValidationFunc func(*ClowdApp) field.ErrorList
 
 func (i *ClowdApp) processValidations(o *ClowdApp, vfns ...appValidationFunc) error {
@@ -191,3 +294,250 @@ func validateDeploymentStrategy(i *ClowdApp) field.ErrorList {
 	}
 	return allErrs
 }
+
+func validateStatefulSets(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for depIndex, deployment := range i.Spec.Deployments {
+		if len(deployment.VolumeClaimTemplates) > 0 && !deployment.IsStatefulSet() {
+			allErrs = append(
+				allErrs,
+				field.Forbidden(
+					field.NewPath(fmt.Sprintf("spec.Deployment[%d]", depIndex)),
+					"volumeClaimTemplates can only be set when kind is StatefulSet",
+				),
+			)
+		}
+		if deployment.IsStatefulSet() && deployment.DeploymentStrategy != nil {
+			allErrs = append(
+				allErrs,
+				field.Forbidden(
+					field.NewPath(fmt.Sprintf("spec.Deployment[%d]", depIndex)),
+					"deploymentStrategy cannot be set when kind is StatefulSet",
+				),
+			)
+		}
+	}
+	return allErrs
+}
+
+func validateDisruptionBudgets(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for depIndex, deployment := range i.Spec.Deployments {
+		budget := deployment.DisruptionBudget
+		if budget != nil && budget.MinAvailable != nil && budget.MaxUnavailable != nil {
+			allErrs = append(
+				allErrs,
+				field.Forbidden(
+					field.NewPath(fmt.Sprintf("spec.Deployment[%d]", depIndex)),
+					"disruptionBudget can only set one of minAvailable and maxUnavailable",
+				),
+			)
+		}
+	}
+	return allErrs
+}
+
+func validatePreDeployJobs(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for jobIndex, job := range i.Spec.Jobs {
+		if job.IsPreDeploy() && job.Schedule != "" {
+			allErrs = append(
+				allErrs,
+				field.Forbidden(
+					field.NewPath(fmt.Sprintf("spec.Jobs[%d]", jobIndex)),
+					"a preDeploy job cannot have a schedule",
+				),
+			)
+		}
+	}
+	return allErrs
+}
+
+func validateKafkaTopicRoles(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for topicIndex, topic := range i.Spec.KafkaTopics {
+		if !topic.CanConsume() && len(topic.ConsumerGroups) != 0 {
+			allErrs = append(
+				allErrs,
+				field.Forbidden(
+					field.NewPath(fmt.Sprintf("spec.KafkaTopics[%d]", topicIndex)),
+					"consumer groups cannot be set on a topic the app only produces to",
+				),
+			)
+		}
+		// An empty name would become a prefix ACL matching every consumer group in the cluster
+		for groupIndex, group := range topic.ConsumerGroups {
+			if strings.TrimSpace(group) == "" {
+				allErrs = append(
+					allErrs,
+					field.Invalid(
+						field.NewPath(fmt.Sprintf("spec.KafkaTopics[%d]", topicIndex)).Child("consumerGroups").Index(groupIndex),
+						group,
+						"consumer group names cannot be empty",
+					),
+				)
+			}
+		}
+		if !topic.CanConsume() && topic.DeadLetter != nil {
+			allErrs = append(
+				allErrs,
+				field.Forbidden(
+					field.NewPath(fmt.Sprintf("spec.KafkaTopics[%d]", topicIndex)).Child("deadLetter"),
+					"dead-letter topics cannot be set on a topic the app only produces to",
+				),
+			)
+		}
+	}
+	return allErrs
+}
+
+func validateKafkaDeadLetterTopics(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	declared := map[string]bool{}
+	for _, topic := range i.Spec.KafkaTopics {
+		declared[topic.TopicName] = true
+	}
+	for topicIndex, topic := range i.Spec.KafkaTopics {
+		for _, companion := range topic.CompanionTopics() {
+			if declared[companion.TopicName] {
+				allErrs = append(
+					allErrs,
+					field.Duplicate(
+						field.NewPath(fmt.Sprintf("spec.KafkaTopics[%d]", topicIndex)).Child("deadLetter"),
+						companion.TopicName,
+					),
+				)
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateKafkaConnectors(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	seen := map[string]bool{}
+	for connectorIndex, connector := range i.Spec.KafkaConnectors {
+		if seen[connector.Name] {
+			allErrs = append(
+				allErrs,
+				field.Duplicate(
+					field.NewPath(fmt.Sprintf("spec.KafkaConnectors[%d]", connectorIndex)).Child("name"),
+					connector.Name,
+				),
+			)
+		}
+		seen[connector.Name] = true
+	}
+	return allErrs
+}
+
+// SeedURLHostsEnv names the environment variable holding a comma separated list of the hosts,
+// besides in-cluster services, that bucket seed archives may be downloaded from. An entry starting
+// with a dot allows every host under that domain.
+const SeedURLHostsEnv = "CLOWDER_SEED_URL_HOSTS"
+
+// ValidateSeedURL returns an error unless the URL is an http or https URL whose host is an
+// in-cluster service, one ending in .svc or .svc.cluster.local, or is allowed by SeedURLHostsEnv.
+func ValidateSeedURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("the scheme must be http or https")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if strings.HasSuffix(host, ".svc") || strings.HasSuffix(host, ".svc.cluster.local") {
+		return nil
+	}
+
+	for _, allowed := range strings.Split(os.Getenv(SeedURLHostsEnv), ",") {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the host must be an in-cluster service or be listed in %s", SeedURLHostsEnv)
+}
+
+func validateObjectStoreSeeds(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	for bucketIndex, bucket := range i.Spec.ObjectStore {
+		if bucket.Seed == nil {
+			continue
+		}
+
+		seedPath := field.NewPath(fmt.Sprintf("spec.ObjectStore[%d]", bucketIndex)).Child("seed")
+		if (bucket.Seed.ConfigMap == "") == (bucket.Seed.URL == "") {
+			allErrs = append(
+				allErrs,
+				field.Invalid(
+					seedPath,
+					bucket.Seed,
+					"a seed must set exactly one of configMap and url",
+				),
+			)
+			continue
+		}
+
+		if bucket.Seed.URL != "" {
+			if err := ValidateSeedURL(bucket.Seed.URL); err != nil {
+				allErrs = append(allErrs, field.Invalid(seedPath.Child("url"), bucket.Seed.URL, err.Error()))
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateObjectStoreNotifications(i *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+	topics := map[string]KafkaTopicSpec{}
+	for _, topic := range i.Spec.KafkaTopics {
+		topics[topic.TopicName] = topic
+	}
+	for bucketIndex, bucket := range i.Spec.ObjectStore {
+		for notificationIndex, notification := range bucket.Notifications {
+			path := field.NewPath(fmt.Sprintf("spec.ObjectStore[%d]", bucketIndex)).Child("notifications").Index(notificationIndex).Child("topic")
+			topic, ok := topics[notification.Topic]
+			if !ok {
+				allErrs = append(allErrs, field.NotFound(path, notification.Topic))
+				continue
+			}
+			if !topic.CanProduce() {
+				allErrs = append(
+					allErrs,
+					field.Forbidden(path, "events can only be published to a topic the app can produce to"),
+				)
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateKafkaQuotas(i *ClowdApp, env *ClowdEnvironment) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if i.Spec.KafkaQuotas == nil {
+		return allErrs
+	}
+
+	path := field.NewPath("spec", "kafkaQuotas")
+	limits := env.Spec.Providers.Kafka.Quotas.Max
+	check := func(name string, value, limit *int32) {
+		if value != nil && limit != nil && *value > *limit {
+			allErrs = append(allErrs, field.Invalid(
+				path.Child(name), *value, fmt.Sprintf("must be no more than %d, the maximum set by ClowdEnvironment %s", *limit, env.Name),
+			))
+		}
+	}
+	check("producerByteRate", i.Spec.KafkaQuotas.ProducerByteRate, limits.ProducerByteRate)
+	check("consumerByteRate", i.Spec.KafkaQuotas.ConsumerByteRate, limits.ConsumerByteRate)
+	check("requestPercentage", i.Spec.KafkaQuotas.RequestPercentage, limits.RequestPercentage)
+
+	return allErrs
+}
@@ -0,0 +1,186 @@
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func appTestValidator(t *testing.T, objs ...runtime.Object) *ClowdAppValidator {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, AddToScheme(scheme))
+
+	return &ClowdAppValidator{
+		Reader: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build(),
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestClowdAppValidateKafkaQuotas(t *testing.T) {
+	env := &ClowdEnvironment{ObjectMeta: metav1.ObjectMeta{Name: "env"}}
+	env.Spec.Providers.Kafka.Quotas.Max = KafkaQuotaSpec{
+		ProducerByteRate:  int32Ptr(1048576),
+		RequestPercentage: int32Ptr(50),
+	}
+
+	app := &ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Spec: ClowdAppSpec{
+			EnvName: "env",
+			KafkaQuotas: &KafkaQuotaSpec{
+				ProducerByteRate: int32Ptr(1048576),
+				ConsumerByteRate: int32Ptr(8388608),
+			},
+		},
+	}
+
+	v := appTestValidator(t, env)
+	_, err := v.ValidateCreate(context.Background(), app)
+	assert.NoError(t, err, "quotas at the maximum or without one are allowed")
+
+	old := app.DeepCopy()
+	app.Spec.KafkaQuotas.RequestPercentage = int32Ptr(75)
+	_, err = v.ValidateUpdate(context.Background(), old, app)
+	assert.ErrorContains(t, err, "spec.kafkaQuotas.requestPercentage")
+
+	_, err = appTestValidator(t).ValidateCreate(context.Background(), app)
+	assert.NoError(t, err, "apps are not checked until their environment exists")
+
+	updated := app.DeepCopy()
+	updated.Spec.Deployments = []Deployment{{Name: "api"}}
+	_, err = v.ValidateUpdate(context.Background(), app, updated)
+	assert.NoError(t, err, "quotas are only checked when they change")
+
+	deleting := old.DeepCopy()
+	deleting.DeletionTimestamp = &metav1.Time{}
+	deleting.Spec.KafkaQuotas.RequestPercentage = int32Ptr(75)
+	_, err = v.ValidateUpdate(context.Background(), old, deleting)
+	assert.NoError(t, err, "apps being deleted are not checked")
+}
+
+type failingReader struct {
+	client.Reader
+}
+
+func (failingReader) Get(_ context.Context, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+	return errors.New("connection refused")
+}
+
+func (failingReader) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
+	return errors.New("connection refused")
+}
+
+func TestClowdAppValidateEnvUnreadable(t *testing.T) {
+	app := &ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Spec: ClowdAppSpec{
+			EnvName:     "env",
+			KafkaQuotas: &KafkaQuotaSpec{RequestPercentage: int32Ptr(75)},
+		},
+	}
+
+	v := &ClowdAppValidator{Reader: failingReader{}}
+	warnings, err := v.ValidateCreate(context.Background(), app)
+	assert.NoError(t, err, "the app is let through when the env cannot be read")
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "connection refused")
+}
+
+func TestClowdAppValidateObjectStoreSeeds(t *testing.T) {
+	app := &ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Spec: ClowdAppSpec{
+			ObjectStore: []ObjectStoreBucketSpec{
+				{Name: "fixtures", Seed: &ObjectStoreSeedSpec{ConfigMap: "fixtures"}},
+				{Name: "archive", Seed: &ObjectStoreSeedSpec{URL: "http://fixtures.test.svc/archive.tar.gz"}},
+			},
+		},
+	}
+	assert.Empty(t, validateObjectStoreSeeds(app))
+
+	app.Spec.ObjectStore[1].Seed.ConfigMap = "fixtures"
+	app.Spec.ObjectStore = append(app.Spec.ObjectStore, ObjectStoreBucketSpec{Name: "empty", Seed: &ObjectStoreSeedSpec{}})
+	errs := validateObjectStoreSeeds(app)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "spec.ObjectStore[1].seed", errs[0].Field)
+	assert.Equal(t, "spec.ObjectStore[2].seed", errs[1].Field)
+}
+
+func TestValidateSeedURL(t *testing.T) {
+	t.Setenv(SeedURLHostsEnv, "fixtures.example.com, .internal.example.com")
+
+	assert.NoError(t, ValidateSeedURL("http://fixtures.test.svc:8080/archive.tar.gz"))
+	assert.NoError(t, ValidateSeedURL("https://fixtures.test.svc.cluster.local/archive.tar.gz"))
+	assert.NoError(t, ValidateSeedURL("https://fixtures.example.com/archive.tar.gz"))
+	assert.NoError(t, ValidateSeedURL("https://cdn.internal.example.com/archive.tar.gz"))
+
+	assert.ErrorContains(t, ValidateSeedURL("http://169.254.169.254/latest/meta-data"), SeedURLHostsEnv)
+	assert.ErrorContains(t, ValidateSeedURL("https://other.example.com/archive.tar.gz"), SeedURLHostsEnv)
+	assert.ErrorContains(t, ValidateSeedURL("https://fixtures.test.svc.evil.com/archive.tar.gz"), SeedURLHostsEnv)
+	assert.ErrorContains(t, ValidateSeedURL("file:///etc/passwd"), "scheme")
+
+	app := &ClowdApp{Spec: ClowdAppSpec{ObjectStore: []ObjectStoreBucketSpec{
+		{Name: "archive", Seed: &ObjectStoreSeedSpec{URL: "http://169.254.169.254/archive.tar.gz"}},
+	}}}
+	errs := validateObjectStoreSeeds(app)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "spec.ObjectStore[0].seed.url", errs[0].Field)
+}
+
+func TestClowdAppValidateObjectStoreNotifications(t *testing.T) {
+	app := &ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Spec: ClowdAppSpec{
+			KafkaTopics: []KafkaTopicSpec{
+				{TopicName: "uploads"},
+				{TopicName: "announcements", Role: KafkaTopicRoleConsume},
+			},
+			ObjectStore: []ObjectStoreBucketSpec{{
+				Name: "uploads",
+				Notifications: []ObjectStoreNotificationSpec{
+					{Events: []ObjectStoreEventType{ObjectStoreEventObjectCreated}, Topic: "uploads"},
+				},
+			}},
+		},
+	}
+	assert.Empty(t, validateObjectStoreNotifications(app))
+
+	app.Spec.ObjectStore[0].Notifications = append(app.Spec.ObjectStore[0].Notifications,
+		ObjectStoreNotificationSpec{Events: []ObjectStoreEventType{ObjectStoreEventObjectDeleted}, Topic: "announcements"},
+		ObjectStoreNotificationSpec{Events: []ObjectStoreEventType{ObjectStoreEventObjectDeleted}, Topic: "missing"},
+	)
+	errs := validateObjectStoreNotifications(app)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "spec.ObjectStore[0].notifications[1].topic", errs[0].Field)
+	assert.Equal(t, "spec.ObjectStore[0].notifications[2].topic", errs[1].Field)
+}
+
+func TestClowdAppValidateKafkaConsumerGroups(t *testing.T) {
+	app := &ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Spec: ClowdAppSpec{
+			KafkaTopics: []KafkaTopicSpec{
+				{TopicName: "uploads", ConsumerGroups: []string{"puptoo", "puptoo-replay"}},
+			},
+		},
+	}
+	assert.Empty(t, validateKafkaTopicRoles(app))
+
+	app.Spec.KafkaTopics[0].ConsumerGroups = []string{"puptoo", "", "  "}
+	errs := validateKafkaTopicRoles(app)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "spec.KafkaTopics[0].consumerGroups[1]", errs[0].Field)
+	assert.Equal(t, "spec.KafkaTopics[0].consumerGroups[2]", errs[1].Field)
+
+	_, err := appTestValidator(t).ValidateCreate(context.Background(), app)
+	assert.ErrorContains(t, err, "consumer group names cannot be empty")
+}
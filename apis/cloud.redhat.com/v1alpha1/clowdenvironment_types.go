@@ -189,7 +189,7 @@ type MetricsConfig struct {
 }
 
 // KafkaMode details the mode of operation of the Clowder Kafka Provider
-// +kubebuilder:validation:Enum=ephem-msk;managed;operator;app-interface;local;none
+// +kubebuilder:validation:Enum=ephem-msk;managed;operator;app-interface;local;redpanda;none
 type KafkaMode string
 
 // KafkaClusterConfig defines options related to the Kafka cluster managed/monitored by Clowder
@@ -250,6 +250,69 @@ type KafkaConnectClusterConfig struct {
 	Resources strimzi.KafkaConnectSpecResources `json:"resources,omitempty"`
 }
 
+// KafkaSchemaRegistryConfig defines options related to the schema registry made available to apps
+// alongside Kafka
+type KafkaSchemaRegistryConfig struct {
+	// Enables the schema registry. In (*_operator_*) mode an Apicurio Registry is deployed next to the
+	// Kafka cluster, in the other modes the registry at URL is passed through to apps.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// Image. If unset, default is 'quay.io/apicurio/apicurio-registry-mem'. Only used in (*_operator_*) mode.
+	Image string `json:"image,omitempty"`
+
+	// The URL of an existing schema registry. Used in all modes except (*_operator_*).
+	// +kubebuilder:validation:Pattern=`^https?:\/\/.+$`
+	URL string `json:"url,omitempty"`
+
+	// Defines a secret holding the username and password keys used to authenticate to the schema
+	// registry at URL. Used in all modes except (*_operator_*).
+	SecretRef *NamespacedName `json:"secretRef,omitempty"`
+}
+
+// KafkaQuotasConfig defines the Kafka client quotas of the apps in the environment
+type KafkaQuotasConfig struct {
+	// The quotas of apps that do not set their own.
+	Default KafkaQuotaSpec `json:"default,omitempty"`
+
+	// The highest quotas an app can be given. ClowdApps requesting more are rejected.
+	Max KafkaQuotaSpec `json:"max,omitempty"`
+}
+
+// KafkaConsumerLagConfig defines the collection of the consumer lag of the apps in the environment
+type KafkaConsumerLagConfig struct {
+	// Enables collecting the lag of the consumer groups declared on each app's topics. The lag is read
+	// from the brokers with the app's own credentials, unless prometheusURL is set.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// The URL of a Prometheus to read the lag from instead of the brokers, from the
+	// kafka_consumergroup_lag metric of a Kafka Exporter. In (*_operator_*) mode the Strimzi Kafka
+	// Exporter is then deployed alongside the Kafka cluster.
+	// +kubebuilder:validation:Pattern=`^https?:\/\/.+$`
+	PrometheusURL string `json:"prometheusURL,omitempty"`
+
+	// The lag, in messages, of a consumer group on a topic above which the app is reported as
+	// degraded. If unset, the lag is exported as a metric only.
+	// +kubebuilder:validation:Minimum:=0
+	Threshold int64 `json:"threshold,omitempty"`
+}
+
+// KafkaCredentialRotationConfig defines the rotation of the SCRAM credentials of the KafkaUsers
+// created for the apps and the Kafka Connect cluster
+type KafkaCredentialRotationConfig struct {
+	// Enables rotating the credentials. Besides on the interval, a rotation can be requested by
+	// changing the value of the clowder/rotate-kafka-credentials annotation, on a ClowdApp for the
+	// app's user or on the ClowdEnvironment for the Kafka Connect user.
+	Enabled bool `json:"enabled,omitempty"`
+
+	// How often the credentials are rotated, for example 720h. If unset, they are only rotated
+	// on request.
+	Interval metav1.Duration `json:"interval,omitempty"`
+
+	// How long the previous credential stays valid after a rotation, so that running pods can keep
+	// using it until they have been rolled out. Defaults to 1h.
+	Overlap metav1.Duration `json:"overlap,omitempty"`
+}
+
 // NamespacedName type to represent a real Namespaced Name
 type NamespacedName struct {
 	// Name defines the Name of a resource.
@@ -267,8 +330,10 @@ type KafkaConfig struct {
 	// KafkaTopic CRs and place them in the Kafka cluster's namespace described in the configuration,
 	// (*_app-interface_*) which simply passes the topic names through to the App's
 	// cdappconfig.json and expects app-interface to have created the relevant
-	// topics, and (*_local_*) where a small instance of Kafka is created in the desired cluster namespace
-	// and configured to auto-create topics.
+	// topics, (*_local_*) where a small instance of Kafka is created in the desired cluster namespace
+	// and configured to auto-create topics, and (*_redpanda_*) where a single Redpanda broker is
+	// deployed in the environment's namespace, without any operator, and app topics are created
+	// through its admin API.
 	Mode KafkaMode `json:"mode"`
 
 	// EnableLegacyStrimzi disables TLS + user auth
@@ -284,6 +349,19 @@ type KafkaConfig struct {
 	// Defines options related to the Kafka Connect cluster for this environment. Ignored for (*_local_*) mode.
 	Connect KafkaConnectClusterConfig `json:"connect,omitempty"`
 
+	// Defines options related to the schema registry for this environment.
+	SchemaRegistry KafkaSchemaRegistryConfig `json:"schemaRegistry,omitempty"`
+
+	// Defines the Kafka client quotas of the apps in this environment. Only used in (*_operator_*) mode.
+	Quotas KafkaQuotasConfig `json:"quotas,omitempty"`
+
+	// Defines the collection of the consumer lag of the apps in this environment.
+	ConsumerLag KafkaConsumerLagConfig `json:"consumerLag,omitempty"`
+
+	// Defines the rotation of the credentials of the apps and the Kafka Connect cluster. Only used
+	// in (*_operator_*) mode.
+	CredentialRotation KafkaCredentialRotationConfig `json:"credentialRotation,omitempty"`
+
 	// Defines the secret reference for the Managed Kafka mode. Only used in (*_managed_*) mode.
 	ManagedSecretRef NamespacedName `json:"managedSecretRef,omitempty"`
 
@@ -371,7 +449,7 @@ type ObjectStoreImages struct {
 
 // ObjectStoreMode details the mode of operation of the Clowder ObjectStore
 // Provider
-// +kubebuilder:validation:Enum=minio;app-interface;none
+// +kubebuilder:validation:Enum=minio;app-interface;s3;none
 type ObjectStoreMode string
 
 // ObjectStoreConfig configures the Clowder provider controlling the creation of
@@ -379,8 +457,8 @@ type ObjectStoreMode string
 type ObjectStoreConfig struct {
 	// The mode of operation of the Clowder ObjectStore Provider. Valid options are:
 	// (*_app-interface_*) where the provider will pass through Amazon S3 credentials
-	// to the app configuration, and (*_minio_*) where a local Minio instance will
-	// be created.
+	// to the app configuration, (*_minio_*) where a local Minio instance will
+	// be created, and (*_s3_*) where an existing S3 compatible server will be used.
 	Mode ObjectStoreMode `json:"mode"`
 
 	// Currently unused.
@@ -392,6 +470,38 @@ type ObjectStoreConfig struct {
 
 	// Override the object store images
 	Images ObjectStoreImages `json:"images,omitempty"`
+
+	// Defines the secret holding the accessKey and secretKey used to connect to the
+	// server. Only used in (*_s3_*) mode.
+	CredentialsSecretRef NamespacedName `json:"credentialsSecretRef,omitempty"`
+
+	// The endpoint of the server, as a hostname with an optional port. Only used in
+	// (*_s3_*) mode.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Connect to the server over TLS. Only used in (*_s3_*) mode.
+	TLS bool `json:"tls,omitempty"`
+
+	// Defines the secret holding, under ca.crt, the CA bundle used to verify the
+	// server's certificate instead of the system's. Only used in (*_s3_*) mode with tls.
+	CASecretRef NamespacedName `json:"caSecretRef,omitempty"`
+
+	// Do not verify the server's certificate. Only used in (*_s3_*) mode with tls.
+	TLSSkipVerify bool `json:"tlsSkipVerify,omitempty"`
+
+	// The region of the buckets. Only used in (*_s3_*) mode.
+	Region string `json:"region,omitempty"`
+
+	// If set, buckets requested by apps are created when they do not exist,
+	// otherwise they must already exist. Not allowed when another environment
+	// uses the same endpoint. Only used in (*_s3_*) mode.
+	CreateBuckets bool `json:"createBuckets,omitempty"`
+
+	// If set, each app is given the accessKey and secretKey of the secret named
+	// <app>-<appCredentialsSecretSuffix> in its namespace, instead of those of
+	// credentialsSecretRef, so that it only reaches the buckets its credentials are
+	// granted. Only used in (*_s3_*) mode.
+	AppCredentialsSecretSuffix string `json:"appCredentialsSecretSuffix,omitempty"`
 }
 
 // ReverseProxyImages defines the container images used for the reverse proxy
@@ -690,6 +800,10 @@ type Sidecars struct {
 // DeploymentConfig defines the deployment configuration for a ClowdEnvironment
 type DeploymentConfig struct {
 	OmitPullPolicy bool `json:"omitPullPolicy,omitempty"`
+
+	// DefaultDisruptionBudget is the PodDisruptionBudget given to every ClowdApp deployment
+	// with more than one replica that does not define its own disruptionBudget.
+	DefaultDisruptionBudget *DisruptionBudget `json:"defaultDisruptionBudget,omitempty"`
 }
 
 // ProvidersConfig defines a group of providers configuration for a ClowdEnvironment.
@@ -764,6 +878,40 @@ type ClowdEnvironmentStatus struct {
 	Generation      int64              `json:"generation,omitempty"`
 	Hostname        string             `json:"hostname,omitempty"`
 	Prometheus      PrometheusStatus   `json:"prometheus,omitempty"`
+
+	// The outcome of the most recent run of each registered provider.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	ProviderConditions []ProviderCondition `json:"providerConditions,omitempty"`
+}
+
+// ProviderCondition records the outcome of running a single provider against the environment.
+type ProviderCondition struct {
+	// The name the provider is registered under, e.g. kafka or database.
+	Name string `json:"name"`
+
+	// True if the provider last ran successfully, False if it failed and Unknown if it was not
+	// run because an earlier provider failed.
+	Status metav1.ConditionStatus `json:"status"`
+
+	// A machine readable reason for the current status.
+	Reason string `json:"reason"`
+
+	// The error returned by the provider the last time it failed, cleared on success.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+
+	// The last time the provider ran successfully.
+	// +optional
+	LastSuccessTime *metav1.Time `json:"lastSuccessTime,omitempty"`
+
+	// The last time the status changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// How long the provider took on its last recorded run.
+	// +optional
+	Duration metav1.Duration `json:"duration,omitempty"`
 }
 
 // EnvResourceStatus describes the status of ClowdEnvironment resources
@@ -0,0 +1,330 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var clowdenvironmentlog = logf.Log.WithName("clowdenvironment-resource")
+
+// SetupWebhookWithManager configures the webhook for this ClowdEnvironment resource
+func (i *ClowdEnvironment) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(i).
+		WithValidator(&ClowdEnvironmentValidator{Reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-cloud-redhat-com-v1alpha1-clowdenvironment,mutating=false,failurePolicy=fail,sideEffects=None,groups=cloud.redhat.com,resources=clowdenvironments,verbs=create;update,versions=v1alpha1,name=vclowdenvironment.kb.io,admissionReviewVersions={v1}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (i *ClowdEnvironment) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clowdEnv, ok := obj.(*ClowdEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdEnvironment but got %T", obj)
+	}
+	clowdenvironmentlog.Info("validate create", "name", clowdEnv.Name)
+
+	return []string{}, i.processValidations(clowdEnv, envValidations...)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (i *ClowdEnvironment) ValidateUpdate(_ context.Context, oldObj runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	clowdEnv, ok := newObj.(*ClowdEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdEnvironment but got %T", newObj)
+	}
+	oldEnv, ok := oldObj.(*ClowdEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdEnvironment but got %T", oldObj)
+	}
+	clowdenvironmentlog.Info("validate update", "name", clowdEnv.Name)
+
+	// Environments created before a validation was added must still be able to have their
+	// finalizers removed, or their metadata and status changed.
+	if clowdEnv.GetDeletionTimestamp() != nil || equality.Semantic.DeepEqual(oldEnv.Spec, clowdEnv.Spec) {
+		return []string{}, nil
+	}
+
+	return []string{}, i.processValidations(clowdEnv, envValidations...)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (i *ClowdEnvironment) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	clowdenvironmentlog.Info("validate delete", "name", i.Name)
+	return []string{}, nil
+}
+
+// ClowdEnvironmentValidator validates ClowdEnvironments, on their own and against the other
+// ClowdEnvironments in the cluster.
+// +kubebuilder:object:generate=false
+type ClowdEnvironmentValidator struct {
+	Reader client.Reader
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdEnvironmentValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clowdEnv, ok := obj.(*ClowdEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdEnvironment but got %T", obj)
+	}
+	if warnings, err := clowdEnv.ValidateCreate(ctx, obj); err != nil {
+		return warnings, err
+	}
+	return v.validateAgainstEnvs(ctx, clowdEnv)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdEnvironmentValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	clowdEnv, ok := newObj.(*ClowdEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdEnvironment but got %T", newObj)
+	}
+	oldEnv, ok := oldObj.(*ClowdEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdEnvironment but got %T", oldObj)
+	}
+	if warnings, err := clowdEnv.ValidateUpdate(ctx, oldObj, newObj); err != nil {
+		return warnings, err
+	}
+	// Only the environment taking up an endpoint is rejected, one already using it is left alone
+	// whatever the others do.
+	if clowdEnv.GetDeletionTimestamp() != nil || !objectStoreEndpointChanged(oldEnv, clowdEnv) {
+		return []string{}, nil
+	}
+	return v.validateAgainstEnvs(ctx, clowdEnv)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdEnvironmentValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	clowdEnv, ok := obj.(*ClowdEnvironment)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdEnvironment but got %T", obj)
+	}
+	return clowdEnv.ValidateDelete(ctx, obj)
+}
+
+// objectStoreEndpointChanged returns whether the update changes which s3 endpoint the environment
+// creates buckets on.
+func objectStoreEndpointChanged(oldEnv *ClowdEnvironment, env *ClowdEnvironment) bool {
+	oldStore, store := oldEnv.Spec.Providers.ObjectStore, env.Spec.Providers.ObjectStore
+	return oldStore.Mode != store.Mode ||
+		!strings.EqualFold(oldStore.Endpoint, store.Endpoint) ||
+		oldStore.CreateBuckets != store.CreateBuckets
+}
+
+// validateAgainstEnvs checks that an environment creating buckets on an s3 endpoint does not share
+// it with another environment. Buckets are created and configured with the environment's
+// credentials, which would reach the buckets of every environment on the endpoint.
+func (v *ClowdEnvironmentValidator) validateAgainstEnvs(ctx context.Context, env *ClowdEnvironment) (admission.Warnings, error) {
+	objectStore := env.Spec.Providers.ObjectStore
+	if objectStore.Mode != "s3" || !objectStore.CreateBuckets {
+		return []string{}, nil
+	}
+
+	envs := &ClowdEnvironmentList{}
+	if err := v.Reader.List(ctx, envs); err != nil {
+		clowdenvironmentlog.Error(err, "could not list environments", "name", env.Name)
+		return []string{fmt.Sprintf("spec.providers.objectStore.createBuckets: could not check the endpoint against other ClowdEnvironments: %s", err)}, nil
+	}
+
+	allErrs := field.ErrorList{}
+	for _, other := range envs.Items {
+		if other.Name == env.Name || other.Spec.Providers.ObjectStore.Mode != "s3" {
+			continue
+		}
+		if strings.EqualFold(other.Spec.Providers.ObjectStore.Endpoint, objectStore.Endpoint) {
+			allErrs = append(allErrs, field.Forbidden(
+				providersPath().Child("objectStore", "createBuckets"),
+				fmt.Sprintf("not allowed as environment %s uses the same s3 endpoint", other.Name)),
+			)
+			break
+		}
+	}
+	if len(allErrs) == 0 {
+		return []string{}, nil
+	}
+
+	return []string{}, apierrors.NewInvalid(
+		schema.GroupKind{Group: "cloud.redhat.com", Kind: "ClowdEnvironment"},
+		env.Name, allErrs,
+	)
+}
+
+type envValidationFunc func(*ClowdEnvironment) field.ErrorList
+
+var envValidations = []envValidationFunc{
+	validateEnvKafka,
+	validateEnvWeb,
+	validateEnvFeatureFlags,
+	validateEnvObjectStore,
+	validateEnvDeployment,
+}
+
+func (i *ClowdEnvironment) processValidations(o *ClowdEnvironment, vfns ...envValidationFunc) error {
+	var allErrs field.ErrorList
+
+	for _, validation := range vfns {
+		fieldList := validation(o)
+		if fieldList != nil {
+			allErrs = append(allErrs, fieldList...)
+		}
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: "cloud.redhat.com", Kind: "ClowdEnvironment"},
+		o.Name, allErrs,
+	)
+}
+
+func providersPath() *field.Path {
+	return field.NewPath("spec", "providers")
+}
+
+func validateEnvKafka(i *ClowdEnvironment) field.ErrorList {
+	allErrs := field.ErrorList{}
+	kafka := i.Spec.Providers.Kafka
+	path := providersPath().Child("kafka")
+
+	switch kafka.Mode {
+	case "managed", "ephem-msk":
+		if kafka.ManagedSecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(
+				path.Child("managedSecretRef", "name"), fmt.Sprintf("required in %s mode", kafka.Mode)),
+			)
+		}
+		if kafka.ManagedSecretRef.Namespace == "" {
+			allErrs = append(allErrs, field.Required(
+				path.Child("managedSecretRef", "namespace"), fmt.Sprintf("required in %s mode", kafka.Mode)),
+			)
+		}
+	}
+
+	return allErrs
+}
+
+func validateEnvWeb(i *ClowdEnvironment) field.ErrorList {
+	allErrs := field.ErrorList{}
+	cert := i.Spec.Providers.Web.GatewayCert
+	path := providersPath().Child("web", "gatewayCert")
+
+	if cert.CertMode == "acme" && cert.EmailAddress == "" {
+		allErrs = append(allErrs, field.Required(
+			path.Child("emailAddress"), "required when certMode is acme"),
+		)
+	}
+
+	return allErrs
+}
+
+func validateEnvFeatureFlags(i *ClowdEnvironment) field.ErrorList {
+	allErrs := field.ErrorList{}
+	ff := i.Spec.Providers.FeatureFlags
+	path := providersPath().Child("featureFlags")
+
+	if ff.Mode != "app-interface" {
+		return allErrs
+	}
+
+	if ff.CredentialRef == (NamespacedName{}) {
+		allErrs = append(allErrs, field.Required(
+			path.Child("credentialRef"), "required in app-interface mode"),
+		)
+	}
+	if ff.Hostname == "" {
+		allErrs = append(allErrs, field.Required(
+			path.Child("hostname"), "required in app-interface mode"),
+		)
+	}
+	if ff.Port == 0 {
+		allErrs = append(allErrs, field.Required(
+			path.Child("port"), "required in app-interface mode"),
+		)
+	}
+
+	return allErrs
+}
+
+func validateEnvObjectStore(i *ClowdEnvironment) field.ErrorList {
+	allErrs := field.ErrorList{}
+	objectStore := i.Spec.Providers.ObjectStore
+	path := providersPath().Child("objectStore")
+
+	if objectStore.Mode != "s3" {
+		return allErrs
+	}
+
+	if objectStore.CredentialsSecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(
+			path.Child("credentialsSecretRef", "name"), "required in s3 mode"),
+		)
+	}
+	if objectStore.CredentialsSecretRef.Namespace == "" {
+		allErrs = append(allErrs, field.Required(
+			path.Child("credentialsSecretRef", "namespace"), "required in s3 mode"),
+		)
+	}
+	if objectStore.Endpoint == "" {
+		allErrs = append(allErrs, field.Required(
+			path.Child("endpoint"), "required in s3 mode"),
+		)
+	}
+	if (objectStore.CASecretRef.Name == "") != (objectStore.CASecretRef.Namespace == "") {
+		allErrs = append(allErrs, field.Invalid(
+			path.Child("caSecretRef"), objectStore.CASecretRef, "name and namespace must be set together"),
+		)
+	}
+	if !objectStore.TLS && (objectStore.CASecretRef.Name != "" || objectStore.TLSSkipVerify) {
+		allErrs = append(allErrs, field.Forbidden(
+			path.Child("tls"), "caSecretRef and tlsSkipVerify are only used with tls"),
+		)
+	}
+
+	return allErrs
+}
+
+func validateEnvDeployment(i *ClowdEnvironment) field.ErrorList {
+	allErrs := field.ErrorList{}
+	budget := i.Spec.Providers.Deployment.DefaultDisruptionBudget
+	path := providersPath().Child("deployment", "defaultDisruptionBudget")
+
+	if budget != nil && budget.MinAvailable != nil && budget.MaxUnavailable != nil {
+		allErrs = append(allErrs, field.Forbidden(
+			path, "only one of minAvailable and maxUnavailable can be set"),
+		)
+	}
+
+	return allErrs
+}
@@ -0,0 +1,245 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func envTestEnvironment(mutate func(*ClowdEnvironment)) *ClowdEnvironment {
+	env := &ClowdEnvironment{ObjectMeta: metav1.ObjectMeta{Name: "env"}}
+	mutate(env)
+	return env
+}
+
+// errorFields returns the paths of the fields the errors are about, nil when there are none.
+func errorFields(errs field.ErrorList) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestClowdEnvironmentValidateKafka(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    *ClowdEnvironment
+		fields []string
+	}{
+		{
+			name: "operator mode needs no secret",
+			env:  envTestEnvironment(func(e *ClowdEnvironment) { e.Spec.Providers.Kafka.Mode = "operator" }),
+		},
+		{
+			name: "managed mode with secret",
+			env: envTestEnvironment(func(e *ClowdEnvironment) {
+				e.Spec.Providers.Kafka.Mode = "managed"
+				e.Spec.Providers.Kafka.ManagedSecretRef = NamespacedName{Name: "kafka", Namespace: "secrets"}
+			}),
+		},
+		{
+			name:   "managed mode without secret",
+			env:    envTestEnvironment(func(e *ClowdEnvironment) { e.Spec.Providers.Kafka.Mode = "managed" }),
+			fields: []string{"spec.providers.kafka.managedSecretRef.name", "spec.providers.kafka.managedSecretRef.namespace"},
+		},
+		{
+			name: "ephem-msk mode without secret namespace",
+			env: envTestEnvironment(func(e *ClowdEnvironment) {
+				e.Spec.Providers.Kafka.Mode = "ephem-msk"
+				e.Spec.Providers.Kafka.ManagedSecretRef = NamespacedName{Name: "kafka"}
+			}),
+			fields: []string{"spec.providers.kafka.managedSecretRef.namespace"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.fields, errorFields(validateEnvKafka(tt.env)))
+		})
+	}
+}
+
+func TestClowdEnvironmentValidateWeb(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    *ClowdEnvironment
+		fields []string
+	}{
+		{
+			name: "self-signed certs need no email",
+			env:  envTestEnvironment(func(e *ClowdEnvironment) { e.Spec.Providers.Web.GatewayCert.CertMode = "self-signed" }),
+		},
+		{
+			name: "acme with email",
+			env: envTestEnvironment(func(e *ClowdEnvironment) {
+				e.Spec.Providers.Web.GatewayCert.CertMode = "acme"
+				e.Spec.Providers.Web.GatewayCert.EmailAddress = "ops@example.com"
+			}),
+		},
+		{
+			name:   "acme without email",
+			env:    envTestEnvironment(func(e *ClowdEnvironment) { e.Spec.Providers.Web.GatewayCert.CertMode = "acme" }),
+			fields: []string{"spec.providers.web.gatewayCert.emailAddress"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.fields, errorFields(validateEnvWeb(tt.env)))
+		})
+	}
+}
+
+func TestClowdEnvironmentValidateFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    *ClowdEnvironment
+		fields []string
+	}{
+		{
+			name: "local mode needs nothing",
+			env:  envTestEnvironment(func(e *ClowdEnvironment) { e.Spec.Providers.FeatureFlags.Mode = "local" }),
+		},
+		{
+			name: "app-interface mode fully set",
+			env: envTestEnvironment(func(e *ClowdEnvironment) {
+				e.Spec.Providers.FeatureFlags.Mode = "app-interface"
+				e.Spec.Providers.FeatureFlags.CredentialRef = NamespacedName{Name: "unleash", Namespace: "secrets"}
+				e.Spec.Providers.FeatureFlags.Hostname = "unleash.example.com"
+				e.Spec.Providers.FeatureFlags.Port = 443
+			}),
+		},
+		{
+			name: "app-interface mode empty",
+			env:  envTestEnvironment(func(e *ClowdEnvironment) { e.Spec.Providers.FeatureFlags.Mode = "app-interface" }),
+			fields: []string{
+				"spec.providers.featureFlags.credentialRef",
+				"spec.providers.featureFlags.hostname",
+				"spec.providers.featureFlags.port",
+			},
+		},
+		{
+			name: "app-interface mode without port",
+			env: envTestEnvironment(func(e *ClowdEnvironment) {
+				e.Spec.Providers.FeatureFlags.Mode = "app-interface"
+				e.Spec.Providers.FeatureFlags.CredentialRef = NamespacedName{Name: "unleash", Namespace: "secrets"}
+				e.Spec.Providers.FeatureFlags.Hostname = "unleash.example.com"
+			}),
+			fields: []string{"spec.providers.featureFlags.port"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.fields, errorFields(validateEnvFeatureFlags(tt.env)))
+		})
+	}
+}
+
+func TestClowdEnvironmentValidateCreate(t *testing.T) {
+	env := envTestEnvironment(func(e *ClowdEnvironment) {
+		e.Spec.Providers.Kafka.Mode = "managed"
+		e.Spec.Providers.Web.GatewayCert.CertMode = "acme"
+	})
+
+	_, err := env.ValidateCreate(context.Background(), env)
+	assert.ErrorContains(t, err, "spec.providers.kafka.managedSecretRef.name: Required value: required in managed mode")
+	assert.ErrorContains(t, err, "spec.providers.web.gatewayCert.emailAddress: Required value: required when certMode is acme")
+
+	env.Spec.Providers.Kafka.ManagedSecretRef = NamespacedName{Name: "kafka", Namespace: "secrets"}
+	env.Spec.Providers.Web.GatewayCert.EmailAddress = "ops@example.com"
+	_, err = env.ValidateUpdate(context.Background(), env, env)
+	assert.NoError(t, err)
+}
+
+func TestClowdEnvironmentValidateUpdateSkipsUnchanged(t *testing.T) {
+	oldEnv := envTestEnvironment(func(e *ClowdEnvironment) { e.Spec.Providers.Kafka.Mode = "managed" })
+
+	newEnv := oldEnv.DeepCopy()
+	newEnv.Finalizers = []string{}
+	_, err := newEnv.ValidateUpdate(context.Background(), oldEnv, newEnv)
+	assert.NoError(t, err, "an update leaving the spec alone must not be validated")
+
+	newEnv = oldEnv.DeepCopy()
+	newEnv.Spec.Providers.Kafka.Cluster.Name = "kafka"
+	_, err = newEnv.ValidateUpdate(context.Background(), oldEnv, newEnv)
+	assert.ErrorContains(t, err, "spec.providers.kafka.managedSecretRef.name")
+
+	now := metav1.Now()
+	newEnv.DeletionTimestamp = &now
+	_, err = newEnv.ValidateUpdate(context.Background(), oldEnv, newEnv)
+	assert.NoError(t, err, "an environment being deleted must not be validated")
+}
+
+func envTestValidator(t *testing.T, objs ...runtime.Object) *ClowdEnvironmentValidator {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, AddToScheme(scheme))
+
+	return &ClowdEnvironmentValidator{
+		Reader: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build(),
+	}
+}
+
+func s3TestEnvironment(name string, createBuckets bool) *ClowdEnvironment {
+	env := envTestEnvironment(func(e *ClowdEnvironment) {
+		e.Spec.Providers.ObjectStore = ObjectStoreConfig{
+			Mode:                 "s3",
+			CredentialsSecretRef: NamespacedName{Name: "rgw", Namespace: "storage"},
+			Endpoint:             "rgw.example.com",
+			CreateBuckets:        createBuckets,
+		}
+	})
+	env.Name = name
+	return env
+}
+
+func TestClowdEnvironmentValidateObjectStore(t *testing.T) {
+	env := s3TestEnvironment("env", false)
+	assert.Empty(t, validateEnvObjectStore(env))
+
+	env.Spec.Providers.ObjectStore.CASecretRef = NamespacedName{Name: "rgw-ca"}
+	env.Spec.Providers.ObjectStore.TLSSkipVerify = true
+	assert.Equal(t, []string{
+		"spec.providers.objectStore.caSecretRef",
+		"spec.providers.objectStore.tls",
+	}, errorFields(validateEnvObjectStore(env)))
+
+	env.Spec.Providers.ObjectStore.CASecretRef.Namespace = "storage"
+	env.Spec.Providers.ObjectStore.TLS = true
+	assert.Empty(t, validateEnvObjectStore(env))
+}
+
+func TestClowdEnvironmentValidateSharedEndpoint(t *testing.T) {
+	other := s3TestEnvironment("other", false)
+	other.Spec.Providers.ObjectStore.Endpoint = "RGW.example.com"
+	v := envTestValidator(t, other)
+
+	env := s3TestEnvironment("env", true)
+	_, err := v.ValidateCreate(context.Background(), env)
+	assert.ErrorContains(t, err, "spec.providers.objectStore.createBuckets: Forbidden: not allowed as environment other uses the same s3 endpoint")
+
+	_, err = envTestValidator(t).ValidateCreate(context.Background(), env)
+	assert.NoError(t, err, "an endpoint of its own is allowed")
+
+	_, err = v.ValidateCreate(context.Background(), s3TestEnvironment("env", false))
+	assert.NoError(t, err, "environments may share an endpoint whose buckets they do not create")
+
+	updated := env.DeepCopy()
+	updated.Spec.Providers.ObjectStore.Region = "us-east-1"
+	_, err = v.ValidateUpdate(context.Background(), env, updated)
+	assert.NoError(t, err, "an environment already on the endpoint is not rejected for what others do")
+
+	_, err = v.ValidateUpdate(context.Background(), s3TestEnvironment("env", false), env)
+	assert.Error(t, err, "turning on createBuckets is checked")
+
+	warnings, err := (&ClowdEnvironmentValidator{Reader: failingReader{}}).ValidateCreate(context.Background(), env)
+	assert.NoError(t, err, "the environment is let through when the others cannot be listed")
+	assert.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "connection refused")
+}
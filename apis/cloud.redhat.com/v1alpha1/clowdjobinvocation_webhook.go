@@ -0,0 +1,180 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var clowdjobinvocationlog = logf.Log.WithName("clowdjobinvocation-resource")
+
+// SetupWebhookWithManager configures the webhook for this ClowdJobInvocation resource
+func (i *ClowdJobInvocation) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(i).
+		WithValidator(&ClowdJobInvocationValidator{Reader: mgr.GetAPIReader()}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-cloud-redhat-com-v1alpha1-clowdjobinvocation,mutating=false,failurePolicy=fail,sideEffects=None,groups=cloud.redhat.com,resources=clowdjobinvocations,verbs=create;update,versions=v1alpha1,name=vclowdjobinvocation.kb.io,admissionReviewVersions={v1}
+
+// ClowdJobInvocationValidator validates ClowdJobInvocations against the ClowdApp they target. The
+// app is read straight from the API server, as a CJI is often applied alongside an update to the
+// app it invokes.
+// +kubebuilder:object:generate=false
+type ClowdJobInvocationValidator struct {
+	Reader client.Reader
+}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdJobInvocationValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cji, ok := obj.(*ClowdJobInvocation)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdJobInvocation but got %T", obj)
+	}
+	clowdjobinvocationlog.Info("validate create", "name", cji.Name)
+
+	return v.validateAgainstApp(ctx, cji)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdJobInvocationValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) (admission.Warnings, error) {
+	oldCji, ok := oldObj.(*ClowdJobInvocation)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdJobInvocation but got %T", oldObj)
+	}
+	cji, ok := newObj.(*ClowdJobInvocation)
+	if !ok {
+		return nil, fmt.Errorf("expected ClowdJobInvocation but got %T", newObj)
+	}
+	clowdjobinvocationlog.Info("validate update", "name", cji.Name)
+
+	// Once jobs have been invoked the CJI is a record of what ran, changing the spec afterwards
+	// would not run anything new and would make that record wrong.
+	if len(oldCji.Status.JobMap) > 0 && !equality.Semantic.DeepEqual(oldCji.Spec, cji.Spec) {
+		return []string{}, cjiInvalid(cji, field.ErrorList{field.Forbidden(
+			field.NewPath("spec"), "cannot be changed once the ClowdJobInvocation has started, create a new ClowdJobInvocation instead",
+		)})
+	}
+
+	// Metadata-only changes, such as labels or finalizers, do not need checking against the app.
+	if equality.Semantic.DeepEqual(oldCji.Spec, cji.Spec) {
+		return []string{}, nil
+	}
+
+	return v.validateAgainstApp(ctx, cji)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (v *ClowdJobInvocationValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	if cji, ok := obj.(*ClowdJobInvocation); ok {
+		clowdjobinvocationlog.Info("validate delete", "name", cji.Name)
+	}
+	return []string{}, nil
+}
+
+func (v *ClowdJobInvocationValidator) validateAgainstApp(ctx context.Context, cji *ClowdJobInvocation) (admission.Warnings, error) {
+	app := &ClowdApp{}
+	nn := types.NamespacedName{
+		Name:      cji.Spec.AppName,
+		Namespace: cji.Namespace,
+	}
+
+	if err := v.Reader.Get(ctx, nn, app); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The reconciler waits for the app to show up, so this is not a reason to reject.
+			return []string{fmt.Sprintf("ClowdApp [%s] does not exist yet, jobs will be checked when it is invoked", cji.Spec.AppName)}, nil
+		}
+		return nil, err
+	}
+
+	warnings, allErrs := validateCJIJobs(cji, app)
+	allErrs = append(allErrs, validateCJITesting(cji, app)...)
+
+	if len(allErrs) == 0 {
+		return warnings, nil
+	}
+
+	return warnings, cjiInvalid(cji, allErrs)
+}
+
+func cjiInvalid(cji *ClowdJobInvocation, allErrs field.ErrorList) error {
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: "cloud.redhat.com", Kind: "ClowdJobInvocation"},
+		cji.Name, allErrs,
+	)
+}
+
+// validateCJIJobs checks the invoked jobs can be run. Jobs the app does not define are only
+// warned about, as the app may be updated to add them alongside the CJI, the controller reports
+// them if they are still missing when it invokes the jobs.
+func validateCJIJobs(cji *ClowdJobInvocation, app *ClowdApp) (admission.Warnings, field.ErrorList) {
+	warnings := admission.Warnings{}
+	allErrs := field.ErrorList{}
+
+	appJobs := map[string]Job{}
+	for _, job := range app.Spec.Jobs {
+		appJobs[job.Name] = job
+	}
+
+	for idx, jobName := range cji.Spec.Jobs {
+		path := field.NewPath("spec", "jobs").Index(idx)
+		job, ok := appJobs[jobName]
+		switch {
+		case !ok:
+			warnings = append(warnings, fmt.Sprintf(
+				"%s: ClowdApp [%s] has no job [%s] yet, it will not be run unless the app defines it", path, app.Name, jobName,
+			))
+		case job.Schedule != "":
+			allErrs = append(allErrs, field.Invalid(
+				path, jobName, "is a cron job, only jobs without a schedule can be invoked"),
+			)
+		case job.Disabled:
+			allErrs = append(allErrs, field.Invalid(
+				path, jobName, "is disabled in the ClowdApp"),
+			)
+		}
+	}
+
+	return warnings, allErrs
+}
+
+func validateCJITesting(cji *ClowdJobInvocation, app *ClowdApp) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if cji.Spec.Testing.Iqe != (IqeJobSpec{}) && app.Spec.Testing.IqePlugin == "" {
+		allErrs = append(allErrs, field.Forbidden(
+			field.NewPath("spec", "testing", "iqe"),
+			fmt.Sprintf("ClowdApp [%s] does not define testing.iqePlugin", app.Name)),
+		)
+	}
+
+	return allErrs
+}
@@ -0,0 +1,132 @@
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func cjiTestValidator(t *testing.T, objs ...runtime.Object) *ClowdJobInvocationValidator {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, AddToScheme(scheme))
+
+	return &ClowdJobInvocationValidator{
+		Reader: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build(),
+	}
+}
+
+func cjiTestApp() *ClowdApp {
+	return &ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "puptoo",
+			Namespace: "test",
+		},
+		Spec: ClowdAppSpec{
+			Jobs: []Job{
+				{Name: "once"},
+				{Name: "nightly", Schedule: "0 0 * * *"},
+				{Name: "off", Disabled: true},
+			},
+		},
+	}
+}
+
+func cjiTestInvocation(jobs ...string) *ClowdJobInvocation {
+	return &ClowdJobInvocation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "runner",
+			Namespace: "test",
+		},
+		Spec: ClowdJobInvocationSpec{
+			AppName: "puptoo",
+			Jobs:    jobs,
+		},
+	}
+}
+
+func TestCJIValidateCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		cji       *ClowdJobInvocation
+		errSubstr string
+		warning   string
+	}{
+		{
+			name: "existing job",
+			cji:  cjiTestInvocation("once"),
+		},
+		{
+			name:    "unknown job",
+			cji:     cjiTestInvocation("missing"),
+			warning: "spec.jobs[0]: ClowdApp [puptoo] has no job [missing] yet",
+		},
+		{
+			name:      "cron job",
+			cji:       cjiTestInvocation("once", "nightly"),
+			errSubstr: "spec.jobs[1]: Invalid value: \"nightly\": is a cron job",
+		},
+		{
+			name:      "disabled job",
+			cji:       cjiTestInvocation("off"),
+			errSubstr: "spec.jobs[0]: Invalid value: \"off\": is disabled in the ClowdApp",
+		},
+		{
+			name: "iqe without plugin",
+			cji: func() *ClowdJobInvocation {
+				cji := cjiTestInvocation()
+				cji.Spec.Testing.Iqe.IqePlugins = "host-inventory"
+				return cji
+			}(),
+			errSubstr: "spec.testing.iqe: Forbidden: ClowdApp [puptoo] does not define testing.iqePlugin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := cjiTestValidator(t, cjiTestApp())
+			warnings, err := v.ValidateCreate(context.Background(), tt.cji)
+			if tt.warning != "" {
+				assert.Len(t, warnings, 1)
+				assert.Contains(t, warnings[0], tt.warning)
+			} else {
+				assert.Empty(t, warnings)
+			}
+			if tt.errSubstr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errSubstr)
+		})
+	}
+}
+
+func TestCJIValidateCreateMissingApp(t *testing.T) {
+	v := cjiTestValidator(t)
+	warnings, err := v.ValidateCreate(context.Background(), cjiTestInvocation("anything"))
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+}
+
+func TestCJIValidateUpdateAfterStart(t *testing.T) {
+	v := cjiTestValidator(t, cjiTestApp())
+
+	oldCji := cjiTestInvocation("once")
+	oldCji.Status.JobMap = map[string]JobConditionState{"puptoo-once": JobInvoked}
+
+	newCji := oldCji.DeepCopy()
+	newCji.Labels = map[string]string{"touched": "true"}
+	_, err := v.ValidateUpdate(context.Background(), oldCji, newCji)
+	assert.NoError(t, err, "metadata changes are allowed")
+
+	newCji.Spec.RunOnNotReady = true
+	_, err = v.ValidateUpdate(context.Background(), oldCji, newCji)
+	assert.ErrorContains(t, err, "spec: Forbidden: cannot be changed once the ClowdJobInvocation has started")
+
+	oldCji.Status.JobMap = nil
+	_, err = v.ValidateUpdate(context.Background(), oldCji, newCji)
+	assert.NoError(t, err, "spec changes are allowed before the CJI has started")
+}
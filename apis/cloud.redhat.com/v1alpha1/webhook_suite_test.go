@@ -102,6 +102,12 @@ var _ = ginkgo.BeforeSuite(func() {
 	err = (&ClowdApp{}).SetupWebhookWithManager(mgr)
 	g.Expect(err).NotTo(g.HaveOccurred())
 
+	err = (&ClowdEnvironment{}).SetupWebhookWithManager(mgr)
+	g.Expect(err).NotTo(g.HaveOccurred())
+
+	err = (&ClowdJobInvocation{}).SetupWebhookWithManager(mgr)
+	g.Expect(err).NotTo(g.HaveOccurred())
+
 	//+kubebuilder:scaffold:webhook
 
 	go func() {
@@ -25,6 +25,7 @@ import (
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -50,6 +51,11 @@ func (in *AppInfo) DeepCopy() *AppInfo {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AppResourceStatus) DeepCopyInto(out *AppResourceStatus) {
 	*out = *in
+	if in.Details != nil {
+		in, out := &in.Details, &out.Details
+		*out = make([]DeploymentReadiness, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppResourceStatus.
@@ -387,11 +393,25 @@ func (in *ClowdAppSpec) DeepCopyInto(out *ClowdAppSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.KafkaConnectors != nil {
+		in, out := &in.KafkaConnectors, &out.KafkaConnectors
+		*out = make([]KafkaConnectorSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.KafkaQuotas != nil {
+		in, out := &in.KafkaQuotas, &out.KafkaQuotas
+		*out = new(KafkaQuotaSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Database.DeepCopyInto(&out.Database)
 	if in.ObjectStore != nil {
 		in, out := &in.ObjectStore, &out.ObjectStore
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+		*out = make([]ObjectStoreBucketSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.Dependencies != nil {
 		in, out := &in.Dependencies, &out.Dependencies
@@ -420,7 +440,7 @@ func (in *ClowdAppSpec) DeepCopy() *ClowdAppSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClowdAppStatus) DeepCopyInto(out *ClowdAppStatus) {
 	*out = *in
-	out.Deployments = in.Deployments
+	in.Deployments.DeepCopyInto(&out.Deployments)
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -428,6 +448,11 @@ func (in *ClowdAppStatus) DeepCopyInto(out *ClowdAppStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.KafkaConnectors != nil {
+		in, out := &in.KafkaConnectors, &out.KafkaConnectors
+		*out = make([]KafkaConnectorReadiness, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClowdAppStatus.
@@ -536,6 +561,13 @@ func (in *ClowdEnvironmentStatus) DeepCopyInto(out *ClowdEnvironmentStatus) {
 		}
 	}
 	out.Prometheus = in.Prometheus
+	if in.ProviderConditions != nil {
+		in, out := &in.ProviderConditions, &out.ProviderConditions
+		*out = make([]ProviderCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClowdEnvironmentStatus.
@@ -777,6 +809,18 @@ func (in *Deployment) DeepCopyInto(out *Deployment) {
 		**out = **in
 	}
 	in.Metadata.DeepCopyInto(&out.Metadata)
+	if in.VolumeClaimTemplates != nil {
+		in, out := &in.VolumeClaimTemplates, &out.VolumeClaimTemplates
+		*out = make([]VolumeClaimTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DisruptionBudget != nil {
+		in, out := &in.DisruptionBudget, &out.DisruptionBudget
+		*out = new(DisruptionBudget)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Deployment.
@@ -792,6 +836,11 @@ func (in *Deployment) DeepCopy() *Deployment {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeploymentConfig) DeepCopyInto(out *DeploymentConfig) {
 	*out = *in
+	if in.DefaultDisruptionBudget != nil {
+		in, out := &in.DefaultDisruptionBudget, &out.DefaultDisruptionBudget
+		*out = new(DisruptionBudget)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentConfig.
@@ -841,6 +890,21 @@ func (in *DeploymentMetadata) DeepCopy() *DeploymentMetadata {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeploymentReadiness) DeepCopyInto(out *DeploymentReadiness) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeploymentReadiness.
+func (in *DeploymentReadiness) DeepCopy() *DeploymentReadiness {
+	if in == nil {
+		return nil
+	}
+	out := new(DeploymentReadiness)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeploymentStrategy) DeepCopyInto(out *DeploymentStrategy) {
 	*out = *in
@@ -856,6 +920,31 @@ func (in *DeploymentStrategy) DeepCopy() *DeploymentStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DisruptionBudget) DeepCopyInto(out *DisruptionBudget) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DisruptionBudget.
+func (in *DisruptionBudget) DeepCopy() *DisruptionBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(DisruptionBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EnvResourceStatus) DeepCopyInto(out *EnvResourceStatus) {
 	*out = *in
@@ -1283,6 +1372,10 @@ func (in *KafkaConfig) DeepCopyInto(out *KafkaConfig) {
 	*out = *in
 	in.Cluster.DeepCopyInto(&out.Cluster)
 	in.Connect.DeepCopyInto(&out.Connect)
+	in.SchemaRegistry.DeepCopyInto(&out.SchemaRegistry)
+	in.Quotas.DeepCopyInto(&out.Quotas)
+	out.ConsumerLag = in.ConsumerLag
+	out.CredentialRotation = in.CredentialRotation
 	out.ManagedSecretRef = in.ManagedSecretRef
 }
 
@@ -1312,6 +1405,164 @@ func (in *KafkaConnectClusterConfig) DeepCopy() *KafkaConnectClusterConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaConnectorReadiness) DeepCopyInto(out *KafkaConnectorReadiness) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaConnectorReadiness.
+func (in *KafkaConnectorReadiness) DeepCopy() *KafkaConnectorReadiness {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaConnectorReadiness)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaConnectorSpec) DeepCopyInto(out *KafkaConnectorSpec) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaConnectorSpec.
+func (in *KafkaConnectorSpec) DeepCopy() *KafkaConnectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaConnectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaConsumerLagConfig) DeepCopyInto(out *KafkaConsumerLagConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaConsumerLagConfig.
+func (in *KafkaConsumerLagConfig) DeepCopy() *KafkaConsumerLagConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaConsumerLagConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaCredentialRotationConfig) DeepCopyInto(out *KafkaCredentialRotationConfig) {
+	*out = *in
+	out.Interval = in.Interval
+	out.Overlap = in.Overlap
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaCredentialRotationConfig.
+func (in *KafkaCredentialRotationConfig) DeepCopy() *KafkaCredentialRotationConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaCredentialRotationConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaDeadLetterSpec) DeepCopyInto(out *KafkaDeadLetterSpec) {
+	*out = *in
+	if in.Config != nil {
+		in, out := &in.Config, &out.Config
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaDeadLetterSpec.
+func (in *KafkaDeadLetterSpec) DeepCopy() *KafkaDeadLetterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaDeadLetterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaQuotaSpec) DeepCopyInto(out *KafkaQuotaSpec) {
+	*out = *in
+	if in.ProducerByteRate != nil {
+		in, out := &in.ProducerByteRate, &out.ProducerByteRate
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ConsumerByteRate != nil {
+		in, out := &in.ConsumerByteRate, &out.ConsumerByteRate
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RequestPercentage != nil {
+		in, out := &in.RequestPercentage, &out.RequestPercentage
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaQuotaSpec.
+func (in *KafkaQuotaSpec) DeepCopy() *KafkaQuotaSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaQuotaSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaQuotasConfig) DeepCopyInto(out *KafkaQuotasConfig) {
+	*out = *in
+	in.Default.DeepCopyInto(&out.Default)
+	in.Max.DeepCopyInto(&out.Max)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaQuotasConfig.
+func (in *KafkaQuotasConfig) DeepCopy() *KafkaQuotasConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaQuotasConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KafkaSchemaRegistryConfig) DeepCopyInto(out *KafkaSchemaRegistryConfig) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(NamespacedName)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaSchemaRegistryConfig.
+func (in *KafkaSchemaRegistryConfig) DeepCopy() *KafkaSchemaRegistryConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KafkaSchemaRegistryConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KafkaTopicSpec) DeepCopyInto(out *KafkaTopicSpec) {
 	*out = *in
@@ -1322,6 +1573,16 @@ func (in *KafkaTopicSpec) DeepCopyInto(out *KafkaTopicSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ConsumerGroups != nil {
+		in, out := &in.ConsumerGroups, &out.ConsumerGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeadLetter != nil {
+		in, out := &in.DeadLetter, &out.DeadLetter
+		*out = new(KafkaDeadLetterSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KafkaTopicSpec.
@@ -1427,10 +1688,49 @@ func (in *NamespacedName) DeepCopy() *NamespacedName {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStoreBucketSpec) DeepCopyInto(out *ObjectStoreBucketSpec) {
+	*out = *in
+	if in.Expiration != nil {
+		in, out := &in.Expiration, &out.Expiration
+		*out = make([]ObjectStoreExpirationRule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Seed != nil {
+		in, out := &in.Seed, &out.Seed
+		*out = new(ObjectStoreSeedSpec)
+		**out = **in
+	}
+	if in.Notifications != nil {
+		in, out := &in.Notifications, &out.Notifications
+		*out = make([]ObjectStoreNotificationSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStoreBucketSpec.
+func (in *ObjectStoreBucketSpec) DeepCopy() *ObjectStoreBucketSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStoreBucketSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectStoreConfig) DeepCopyInto(out *ObjectStoreConfig) {
 	*out = *in
 	out.Images = in.Images
+	out.CredentialsSecretRef = in.CredentialsSecretRef
+	out.CASecretRef = in.CASecretRef
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStoreConfig.
@@ -1443,6 +1743,21 @@ func (in *ObjectStoreConfig) DeepCopy() *ObjectStoreConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStoreExpirationRule) DeepCopyInto(out *ObjectStoreExpirationRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStoreExpirationRule.
+func (in *ObjectStoreExpirationRule) DeepCopy() *ObjectStoreExpirationRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStoreExpirationRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectStoreImages) DeepCopyInto(out *ObjectStoreImages) {
 	*out = *in
@@ -1458,6 +1773,41 @@ func (in *ObjectStoreImages) DeepCopy() *ObjectStoreImages {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStoreNotificationSpec) DeepCopyInto(out *ObjectStoreNotificationSpec) {
+	*out = *in
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]ObjectStoreEventType, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStoreNotificationSpec.
+func (in *ObjectStoreNotificationSpec) DeepCopy() *ObjectStoreNotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStoreNotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectStoreSeedSpec) DeepCopyInto(out *ObjectStoreSeedSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectStoreSeedSpec.
+func (in *ObjectStoreSeedSpec) DeepCopy() *ObjectStoreSeedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectStoreSeedSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OtelCollectorConfig) DeepCopyInto(out *OtelCollectorConfig) {
 	*out = *in
@@ -1654,6 +2004,27 @@ func (in *PrometheusStatus) DeepCopy() *PrometheusStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProviderCondition) DeepCopyInto(out *ProviderCondition) {
+	*out = *in
+	if in.LastSuccessTime != nil {
+		in, out := &in.LastSuccessTime, &out.LastSuccessTime
+		*out = (*in).DeepCopy()
+	}
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderCondition.
+func (in *ProviderCondition) DeepCopy() *ProviderCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ProviderCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvidersConfig) DeepCopyInto(out *ProvidersConfig) {
 	*out = *in
@@ -1674,7 +2045,7 @@ func (in *ProvidersConfig) DeepCopyInto(out *ProvidersConfig) {
 	in.Testing.DeepCopyInto(&out.Testing)
 	in.Sidecars.DeepCopyInto(&out.Sidecars)
 	out.AutoScaler = in.AutoScaler
-	out.Deployment = in.Deployment
+	in.Deployment.DeepCopyInto(&out.Deployment)
 	out.ReverseProxy = in.ReverseProxy
 }
 
@@ -1942,6 +2313,22 @@ func (in *TokenRefresherConfig) DeepCopy() *TokenRefresherConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeClaimTemplate) DeepCopyInto(out *VolumeClaimTemplate) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeClaimTemplate.
+func (in *VolumeClaimTemplate) DeepCopy() *VolumeClaimTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeClaimTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WebConfig) DeepCopyInto(out *WebConfig) {
 	*out = *in
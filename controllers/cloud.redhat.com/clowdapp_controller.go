@@ -18,9 +18,11 @@ import (
 	"context"
 	"time"
 
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -52,7 +54,7 @@ import (
 	_ "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/featureflags"
 	_ "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/inmemorydb"
 	_ "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/iqe"
-	_ "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
 	_ "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/logging"
 	_ "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/metrics"
 	_ "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/namespace"
@@ -108,6 +110,8 @@ func (rm *ReconciliationMetrics) stop() {
 // +kubebuilder:rbac:groups="",resources=serviceaccounts;configmaps;services;persistentvolumeclaims;secrets;events;namespaces,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=cronjobs;jobs,verbs=get;list;create;update;watch;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkatopics,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkas,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkausers,verbs=get;list;watch;create;update;patch;delete
@@ -117,7 +121,7 @@ func (rm *ReconciliationMetrics) stop() {
 // +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings;roles,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheuses;servicemonitors,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operators.coreos.com,resources=subscriptions,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkaconnectors,verbs=get;list;watch
+// +kubebuilder:rbac:groups=kafka.strimzi.io,resources=kafkaconnectors,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=endpoints;pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses;networkpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=config.openshift.io,resources=ingresses,verbs=get;list
@@ -129,6 +133,8 @@ func (rm *ReconciliationMetrics) stop() {
 // ClowdAppReconciler reconciles a ClowdApp object
 type ClowdAppReconciler struct {
 	client.Client
+	// APIReader reads straight from the API server, for objects that are not worth caching.
+	APIReader client.Reader
 	Log       logr.Logger
 	Scheme    *runtime.Scheme
 	Recorder  record.EventRecorder
@@ -157,6 +163,7 @@ func (r *ClowdAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	reconciliation := ClowdAppReconciliation{
 		ctx:       ctx,
 		client:    r.Client,
+		apiReader: r.APIReader,
 		recorder:  r.Recorder,
 		app:       &app,
 		log:       &log,
@@ -174,7 +181,7 @@ func (r *ClowdAppReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return res, err
 	}
 
-	return ctrl.Result{}, nil
+	return res, nil
 }
 
 func (r *ClowdAppReconciler) setupWatch(ctrlr *builder.Builder, mgr ctrl.Manager, obj client.Object, handlerBuilder HandlerFuncBuilder) error {
@@ -202,6 +209,10 @@ func (r *ClowdAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	if err := cache.IndexField(context.TODO(), &crd.ClowdApp{}, kafka.AppTopicIndex, kafka.AppTopicIndexValues); err != nil {
+		return err
+	}
+
 	ctrlr := ctrl.NewControllerManagedBy(mgr).For(&crd.ClowdApp{})
 	ctrlr.Watches(
 		&crd.ClowdEnvironment{},
@@ -217,6 +228,8 @@ func (r *ClowdAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	watchers := []Watcher{
 		{obj: &apps.Deployment{}, filter: deploymentFilter},
+		{obj: &apps.StatefulSet{}, filter: statefulSetFilter},
+		{obj: &batch.Job{}, filter: jobFilter},
 		{obj: &core.Service{}, filter: generationOnlyFilter},
 		{obj: &core.ConfigMap{}, filter: generationOnlyFilter},
 		{obj: &core.Secret{}, filter: alwaysFilter},
@@ -229,6 +242,15 @@ func (r *ClowdAppReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}
 	}
 
+	if clowderconfig.LoadedConfig.Features.WatchStrimziResources {
+		// KafkaConnectors live in the Kafka Connect namespace and are owned by the env, so they are
+		// mapped back to their app by label.
+		ctrlr.Watches(
+			&strimzi.KafkaConnector{},
+			handler.EnqueueRequestsFromMapFunc(r.appsToEnqueueUponConnectorUpdate),
+		)
+	}
+
 	ctrlr.WithOptions(controller.Options{
 		RateLimiter: workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](time.Duration(500*time.Millisecond), time.Duration(60*time.Second)),
 	})
@@ -330,6 +352,23 @@ func (r *ClowdAppReconciler) appsToEnqueueUponAppRefUpdate(ctx context.Context,
 	return reqs
 }
 
+func (r *ClowdAppReconciler) appsToEnqueueUponConnectorUpdate(_ context.Context, a client.Object) []reconcile.Request {
+	labels := a.GetLabels()
+	name, ok := labels[kafka.ConnectorAppLabel]
+	if !ok {
+		return nil
+	}
+
+	logMessage(r.Log, "Reconciliation triggered", "ctrl", "app", "type", "update", "resType", "KafkaConnector", "name", a.GetName(), "namespace", a.GetNamespace())
+
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: labels[kafka.ConnectorAppNamespaceLabel],
+		},
+	}}
+}
+
 func contains(list []string, s string) bool {
 	for _, v := range list {
 		if v == s {
@@ -22,6 +22,11 @@ import (
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/confighash"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/cronjob"
+	deployProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/deployment"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/objectstore"
 	provutils "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/utils"
 )
 
@@ -31,6 +36,7 @@ type ClowdAppReconciliation struct {
 	recorder              record.EventRecorder
 	ctx                   context.Context
 	client                client.Client
+	apiReader             client.Reader
 	log                   *logr.Logger
 	app                   *crd.ClowdApp
 	req                   *ctrl.Request
@@ -57,23 +63,34 @@ func (r *ClowdAppReconciliation) steps() []func() (ctrl.Result, error) {
 		r.isEnvReady,
 		r.createCache,
 		r.runProviders,
+		r.holdForPreDeployJobs,
 		r.applyCache,
+		r.reportKafkaTopicDrift,
+		r.reportObjectStoreSettings,
+		r.reportObjectStoreSeeds,
 		r.setAppResourceStatus,
 		r.deletedUnusedResources,
 		r.setReconciliationSuccessful,
+		r.requeueForCredentialRotation,
 		r.stopMetrics,
 	}
 }
 
-// Reconcile is the main function that runs the reconciliation steps for a ClowdApp
+// Reconcile is the main function that runs the reconciliation steps for a ClowdApp. A step can
+// ask for the app to be reconciled again later by returning a RequeueAfter without an error, the
+// soonest of which is kept.
 func (r *ClowdAppReconciliation) Reconcile() (ctrl.Result, error) {
+	final := ctrl.Result{}
 	for _, step := range r.steps() {
 		result, err := step()
 		if err != nil {
 			return result, err
 		}
+		if result.RequeueAfter > 0 && (final.RequeueAfter == 0 || result.RequeueAfter < final.RequeueAfter) {
+			final = result
+		}
 	}
-	return ctrl.Result{}, nil
+	return final, nil
 }
 
 func (r *ClowdAppReconciliation) startMetrics() (ctrl.Result, error) {
@@ -179,6 +196,10 @@ func (r *ClowdAppReconciliation) isAppMarkedForDeletion() (ctrl.Result, error) {
 }
 
 func (r *ClowdAppReconciliation) finalizeApp() error {
+	if err := r.finalizeProviders(); err != nil {
+		return err
+	}
+
 	// We remove it from the managed list because it may have been managed before, but it may not be after this reconcile.
 	delete(managedApps, r.app.GetIdent())
 	managedAppsMetric.Set(float64(len(managedApps)))
@@ -186,10 +207,46 @@ func (r *ClowdAppReconciliation) finalizeApp() error {
 	delete(presentApps, r.app.GetIdent())
 	presentAppsMetric.Set(float64(len(presentApps)))
 
+	kafkaTopicDriftMetrics.DeletePartialMatch(prometheus.Labels{"app": r.app.GetIdent()})
+	kafkaConsumerLagMetrics.DeletePartialMatch(prometheus.Labels{"app": r.app.GetIdent()})
+
 	r.log.Info("Successfully finalized ClowdApp")
 	return nil
 }
 
+// finalizeProviders gives providers a chance to clean up anything they created outside of the
+// cluster for this app. If the env is already gone, or is being deleted, there is nothing left to
+// clean up against.
+func (r *ClowdAppReconciliation) finalizeProviders() error {
+	env := &crd.ClowdEnvironment{}
+	if err := r.client.Get(r.ctx, types.NamespacedName{Name: r.app.Spec.EnvName}, env); err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if env.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	if _, err := r.createCache(); err != nil {
+		return err
+	}
+
+	provider := providers.Provider{
+		Client:    r.client,
+		Ctx:       r.ctx,
+		Env:       env,
+		Cache:     r.cache,
+		Log:       *r.log,
+		Config:    r.config,
+		HashCache: r.hashCache,
+	}
+
+	return runProvidersForAppFinalize(*r.log, provider, r.app)
+}
+
 func (r *ClowdAppReconciliation) addFinalizer() (ctrl.Result, error) {
 	if !contains(r.app.GetFinalizers(), appFinalizer) {
 		if addFinalizeErr := r.addFinalizerImplementation(); addFinalizeErr != nil {
@@ -355,6 +412,106 @@ func (r *ClowdAppReconciliation) runProvidersImplementation(provider *providers.
 	return nil
 }
 
+// holdForPreDeployJobs stops the app's workloads from being updated while its preDeploy jobs for
+// the current spec have not yet succeeded, unless the hold has been released. The job watch
+// requeues the app once they finish.
+func (r *ClowdAppReconciliation) holdForPreDeployJobs() (ctrl.Result, error) {
+	jobs, err := cronjob.GetPreDeployJobs(r.cache)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	released, err := cronjob.PreDeployHoldReleased(r.app)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	if SetPreDeployCondition(r.app, jobs, released) {
+		return ctrl.Result{}, nil
+	}
+
+	workloads, err := deployProvider.ListWorkloads(r.cache)
+	if err != nil {
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	for _, w := range workloads {
+		if err := deployProvider.HoldWorkload(r.ctx, r.client, r.cache, w); err != nil {
+			return ctrl.Result{Requeue: true}, err
+		}
+	}
+
+	if err := confighash.HoldConfig(r.ctx, r.client, r.cache, r.app, r.config); err != nil {
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	r.log.Info("Holding workloads for preDeploy jobs", "jobs", len(jobs))
+	return ctrl.Result{}, nil
+}
+
+// requeueForCredentialRotation has the app reconciled again when the next step of the rotation of
+// its Kafka credentials is due, as nothing about the app changes when it is.
+func (r *ClowdAppReconciliation) requeueForCredentialRotation() (ctrl.Result, error) {
+	wait, ok, err := kafka.GetAppRotationRequeue(r.ctx, r.client, r.env, r.app)
+	if err != nil {
+		r.log.Info("Could not read kafka credential rotation", "err", err)
+		return ctrl.Result{}, nil
+	}
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: wait}, nil
+}
+
+// reportKafkaTopicDrift checks the app's live topics for drift from their declared settings. Drift
+// is only reported, a failure to check it does not fail the reconciliation.
+func (r *ClowdAppReconciliation) reportKafkaTopicDrift() (ctrl.Result, error) {
+	drift, checked, err := kafka.GetTopicDrift(r.ctx, r.client, r.env, r.app, r.config.Kafka)
+	if err != nil {
+		r.log.Info("Could not check kafka topic drift", "err", err)
+		SetKafkaTopicDriftUnchecked(r.app, err)
+		return ctrl.Result{}, nil
+	}
+
+	SetKafkaTopicDriftCondition(r.app, drift, checked)
+
+	kafkaTopicDriftMetrics.DeletePartialMatch(prometheus.Labels{"app": r.app.GetIdent()})
+	for _, d := range drift {
+		kafkaTopicDriftMetrics.With(prometheus.Labels{"app": r.app.GetIdent(), "topic": d.Topic, "setting": d.Setting}).Set(1)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reportObjectStoreSettings checks the settings of the app's provisioned buckets against the
+// options declared for them. Mismatches are only reported, a failure to check them does not fail
+// the reconciliation.
+func (r *ClowdAppReconciliation) reportObjectStoreSettings() (ctrl.Result, error) {
+	mismatches, checked, err := objectstore.CheckBucketSettings(r.ctx, r.env, r.app, r.config.ObjectStore)
+	if err != nil {
+		r.log.Info("Could not check object store bucket settings", "err", err)
+		return ctrl.Result{}, nil
+	}
+
+	SetObjectStoreBucketsCondition(r.app, mismatches, checked)
+	return ctrl.Result{}, nil
+}
+
+// reportObjectStoreSeeds reports on the seeds of the app's buckets, which are uploaded in the
+// background, and has the app reconciled again while any is running or to retry a failed one.
+func (r *ClowdAppReconciliation) reportObjectStoreSeeds() (ctrl.Result, error) {
+	pending, failed, checked := objectstore.GetSeedStatus(r.env, r.app)
+	SetObjectStoreSeedCondition(r.app, pending, failed, checked)
+
+	switch {
+	case len(pending) > 0:
+		return ctrl.Result{RequeueAfter: objectstore.SeedPollInterval}, nil
+	case len(failed) > 0:
+		return ctrl.Result{RequeueAfter: objectstore.SeedRetryInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
 func (r *ClowdAppReconciliation) applyCache() (ctrl.Result, error) {
 
 	cacheErr := r.cache.ApplyAll()
@@ -373,7 +530,7 @@ func (r *ClowdAppReconciliation) applyCache() (ctrl.Result, error) {
 }
 
 func (r *ClowdAppReconciliation) setAppResourceStatus() (ctrl.Result, error) {
-	if statusErr := SetAppResourceStatus(r.ctx, r.client, r.app); statusErr != nil {
+	if statusErr := SetAppResourceStatus(r.ctx, r.client, r.apiReader, r.app); statusErr != nil {
 		r.log.Info("Set status error", "err", statusErr)
 		return ctrl.Result{Requeue: true}, statusErr
 	}
@@ -187,21 +187,26 @@ func (r *ClowdEnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 	managedEnvironments[env.Name] = true
 
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 func runProvidersForEnv(log logr.Logger, provider providers.Provider) error {
-	for _, provAcc := range providers.ProvidersRegistration.Registry {
+	for idx, provAcc := range providers.ProvidersRegistration.Registry {
 		provutils.DebugLog(log, "running provider:", "name", provAcc.Name, "order", provAcc.Order)
 		start := time.Now()
 		prov, err := provAcc.SetupProvider(&provider)
 		if err != nil {
+			SetProviderCondition(provider.Env, provAcc.Name, time.Since(start), err)
+			skipRemainingProviders(provider.Env, idx+1)
 			return errors.Wrap(fmt.Sprintf("getprov: %s", provAcc.Name), err)
 		}
 		err = prov.EnvProvide()
-		elapsed := time.Since(start).Seconds()
+		duration := time.Since(start)
+		elapsed := duration.Seconds()
 		providerMetrics.With(prometheus.Labels{"provider": provAcc.Name, "source": "clowdenv"}).Observe(elapsed)
+		SetProviderCondition(provider.Env, provAcc.Name, duration, err)
 		if err != nil {
+			skipRemainingProviders(provider.Env, idx+1)
 			return errors.Wrap(fmt.Sprintf("runprov: %s", provAcc.Name), err)
 		}
 		provutils.DebugLog(log, "running provider: complete", "name", provAcc.Name, "order", provAcc.Order, "elapsed", fmt.Sprintf("%f", elapsed))
@@ -209,6 +214,14 @@ func runProvidersForEnv(log logr.Logger, provider providers.Provider) error {
 	return nil
 }
 
+// skipRemainingProviders marks every provider from the given index onwards as not run, as the
+// providers are run in order and the later ones can rely on the earlier ones.
+func skipRemainingProviders(env *crd.ClowdEnvironment, from int) {
+	for _, provAcc := range providers.ProvidersRegistration.Registry[from:] {
+		SetProviderNotRun(env, provAcc.Name)
+	}
+}
+
 func runProvidersForEnvFinalize(log logr.Logger, provider providers.Provider) error {
 	for _, provAcc := range providers.ProvidersRegistration.Registry {
 		if provAcc.FinalizeProvider != nil {
@@ -223,6 +236,25 @@ func runProvidersForEnvFinalize(log logr.Logger, provider providers.Provider) er
 	return nil
 }
 
+func runProvidersForAppFinalize(log logr.Logger, provider providers.Provider, app *crd.ClowdApp) error {
+	for _, provAcc := range providers.ProvidersRegistration.Registry {
+		prov, err := provAcc.SetupProvider(&provider)
+		if err != nil {
+			return errors.Wrap(fmt.Sprintf("getprov: %s", provAcc.Name), err)
+		}
+		finalizer, ok := prov.(providers.AppFinalizer)
+		if !ok {
+			continue
+		}
+		provutils.DebugLog(log, "running provider app finalize:", "name", provAcc.Name, "order", provAcc.Order)
+		if err := finalizer.FinalizeApp(app); err != nil {
+			return errors.Wrap(fmt.Sprintf("prov app finalize: %s", provAcc.Name), err)
+		}
+		provutils.DebugLog(log, "running provider app finalize: complete", "name", provAcc.Name, "order", provAcc.Order)
+	}
+	return nil
+}
+
 func (r *ClowdEnvironmentReconciler) setupWatch(ctrlr *builder.Builder, mgr ctrl.Manager, obj client.Object, handlerBuilder HandlerFuncBuilder) error {
 	handler, err := createNewHandler(mgr, r.Scheme, handlerBuilder, r.Log, "app", &crd.ClowdEnvironment{}, r.HashCache)
 	if err != nil {
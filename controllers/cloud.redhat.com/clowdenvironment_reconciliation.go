@@ -9,6 +9,8 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus"
 	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -21,6 +23,8 @@ import (
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/clowderconfig"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/dependencies"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
 )
 
 // SkippedError represents an error that occurred during reconciliation that can be skipped
@@ -85,6 +89,7 @@ func (r *ClowdEnvironmentReconciliation) steps() []func() (ctrl.Result, error) {
 		r.deleteUnusedResources,
 		r.setClowdEnvConditions,
 		r.logSuccess,
+		r.requeueForCredentialRotation,
 	}
 }
 
@@ -96,14 +101,19 @@ func (r *ClowdEnvironmentReconciliation) Reconcile() (ctrl.Result, error) {
 	// where the lock wasn't initated until the target namespace had been initialized
 	SetEnv(r.env.Name)
 	defer ReleaseEnv()
+	final := ctrl.Result{}
 	for _, step := range r.steps() {
 		result, err := step()
 		if err != nil {
 			return result, err
 		}
+		// a step can ask for the env to be reconciled again later
+		if result.RequeueAfter > 0 {
+			final = result
+		}
 	}
 
-	return ctrl.Result{}, nil
+	return final, nil
 }
 
 // Determine if app is marked for deletion, and if so finalize and end resonciliation
@@ -368,6 +378,8 @@ func (r *ClowdEnvironmentReconciliation) setAppInfoImplementation() error {
 
 	sort.Strings(names)
 
+	activeApps := []crd.ClowdApp{}
+
 	// Populate
 	for _, name := range names {
 		app := appMap[name]
@@ -376,6 +388,8 @@ func (r *ClowdEnvironmentReconciliation) setAppInfoImplementation() error {
 			continue
 		}
 
+		activeApps = append(activeApps, app)
+
 		appstatus := crd.AppInfo{
 			Name:        app.Name,
 			Deployments: []crd.DeploymentInfo{},
@@ -407,6 +421,67 @@ func (r *ClowdEnvironmentReconciliation) setAppInfoImplementation() error {
 	}
 
 	r.env.Status.Apps = apps
+
+	return r.setDependencyConditions(activeApps)
+}
+
+// setDependencyConditions checks the dependency graph of every app in the env for cycles and
+// dependencies on apps that don't exist, an app in either state will never have its
+// dependencies satisfied. The result is recorded on the env and on each affected app.
+func (r *ClowdEnvironmentReconciliation) setDependencyConditions(apps []crd.ClowdApp) error {
+	appRefList := &crd.ClowdAppRefList{}
+	if err := r.client.List(r.ctx, appRefList, client.MatchingFields{"spec.envName": r.env.Name}); err != nil {
+		return err
+	}
+
+	report := dependencies.AnalyzeGraph(apps, appRefList.Items)
+
+	envCondition := metav1.Condition{
+		Type:    crd.DependenciesResolved,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DependenciesResolved",
+		Message: "No dependency cycles or unknown dependencies",
+	}
+	if !report.OK() {
+		envCondition.Status = metav1.ConditionFalse
+		envCondition.Reason = "DependencyProblems"
+		envCondition.Message = report.String()
+	}
+	cond.Set(r.env, envCondition)
+
+	for i := range apps {
+		app := &apps[i]
+
+		appCondition := metav1.Condition{
+			Type:    crd.DependenciesResolved,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DependenciesResolved",
+			Message: "No dependency cycles or unknown dependencies",
+		}
+		if msg := report.AppMessage(app.Name); msg != "" {
+			appCondition.Status = metav1.ConditionFalse
+			appCondition.Reason = "UnknownDependency"
+			if report.CycleFor(app.Name) != nil {
+				appCondition.Reason = "DependencyCycle"
+			}
+			appCondition.Message = msg
+		}
+
+		existing := meta.FindStatusCondition(app.Status.Conditions, crd.DependenciesResolved)
+		if existing != nil && existing.Status == appCondition.Status && existing.Reason == appCondition.Reason && existing.Message == appCondition.Message {
+			continue
+		}
+
+		patch := client.MergeFrom(app.DeepCopy())
+		cond.Set(app, appCondition)
+		if err := r.client.Status().Patch(r.ctx, app, patch); err != nil {
+			if k8serr.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -495,6 +570,20 @@ func (r *ClowdEnvironmentReconciliation) logSuccess() (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
+// requeueForCredentialRotation has the env reconciled again when the next step of the rotation of
+// its Kafka Connect credentials is due, as nothing about the env changes when it is.
+func (r *ClowdEnvironmentReconciliation) requeueForCredentialRotation() (ctrl.Result, error) {
+	wait, ok, err := kafka.GetConnectRotationRequeue(r.ctx, r.client, r.env)
+	if err != nil {
+		r.log.Info("Could not read kafka credential rotation", "err", err)
+		return ctrl.Result{}, nil
+	}
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: wait}, nil
+}
+
 func (r *ClowdEnvironmentReconciliation) setToBeDisabled() (ctrl.Result, error) {
 	if r.env.Spec.Disabled {
 		return ctrl.Result{}, NewSkippedError("env is disabled")
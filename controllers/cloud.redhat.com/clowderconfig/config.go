@@ -23,6 +23,8 @@ type ClowderConfig struct {
 		FeatureFlagsUnleashEdge string `json:"featureFlagsUnleashEdge"`
 		TokenRefresher          string `json:"tokenRefresher"`
 		OtelCollector           string `json:"otelCollector"`
+		KafkaSchemaRegistry     string `json:"kafkaSchemaRegistry"`
+		KafkaRedpanda           string `json:"kafkaRedpanda"`
 		InMemoryDB              string `json:"inMemoryDB"`
 		PrometheusGateway       string `json:"prometheusGateway"`
 		ReverseProxy            string `json:"reverseProxy"`
@@ -72,12 +74,12 @@ func getConfig() ClowderConfig {
 		configPath = path
 	}
 
-	fmt.Printf("Loading config from: %s\n", configPath)
+	fmt.Fprintf(os.Stderr, "Loading config from: %s\n", configPath)
 
 	jsonData, err := os.ReadFile(filepath.Clean(configPath))
 
 	if err != nil {
-		fmt.Printf("Config file not found\n")
+		fmt.Fprintf(os.Stderr, "Config file not found\n")
 		return ClowderConfig{}
 	}
 
@@ -85,7 +87,7 @@ func getConfig() ClowderConfig {
 	err = json.Unmarshal(jsonData, &clowderConfig)
 
 	if err != nil {
-		fmt.Printf("Couldn't parse json:\n %s", err.Error())
+		fmt.Fprintf(os.Stderr, "Couldn't parse json:\n %s", err.Error())
 		return ClowderConfig{}
 	}
 
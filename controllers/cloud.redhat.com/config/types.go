@@ -127,6 +127,9 @@ type BrokerConfig struct {
 	// Hostname of kafka broker
 	Hostname string `json:"hostname" yaml:"hostname" mapstructure:"hostname"`
 
+	// Iam corresponds to the JSON schema field "iam".
+	Iam *KafkaIAMConfig `json:"iam,omitempty" yaml:"iam,omitempty" mapstructure:"iam,omitempty"`
+
 	// Port of kafka broker
 	Port *int `json:"port,omitempty" yaml:"port,omitempty" mapstructure:"port,omitempty"`
 
@@ -139,6 +142,7 @@ type BrokerConfig struct {
 
 type BrokerConfigAuthtype string
 
+const BrokerConfigAuthtypeIam BrokerConfigAuthtype = "iam"
 const BrokerConfigAuthtypeSasl BrokerConfigAuthtype = "sasl"
 
 // Cloud Watch configuration
@@ -285,10 +289,26 @@ type KafkaConfig struct {
 	// Defines the brokers the app should connect to for Kafka services.
 	Brokers []BrokerConfig `json:"brokers" yaml:"brokers" mapstructure:"brokers"`
 
+	// SchemaRegistry corresponds to the JSON schema field "schemaRegistry".
+	SchemaRegistry *SchemaRegistryConfig `json:"schemaRegistry,omitempty" yaml:"schemaRegistry,omitempty" mapstructure:"schemaRegistry,omitempty"`
+
 	// Defines a list of the topic configurations available to the application.
 	Topics []TopicConfig `json:"topics" yaml:"topics" mapstructure:"topics"`
 }
 
+// AWS IAM Configuration for Kafka
+type KafkaIAMConfig struct {
+	// AWS region of the MSK cluster
+	Region string `json:"region" yaml:"region" mapstructure:"region"`
+
+	// ARN of the IAM role to assume when authenticating to the broker. If absent,
+	// the client should use the default AWS credentials of the pod
+	RoleArn *string `json:"roleArn,omitempty" yaml:"roleArn,omitempty" mapstructure:"roleArn,omitempty"`
+
+	// Broker SASL mechanism, expect: OAUTHBEARER
+	SaslMechanism *string `json:"saslMechanism,omitempty" yaml:"saslMechanism,omitempty" mapstructure:"saslMechanism,omitempty"`
+}
+
 // SASL Configuration for Kafka
 type KafkaSASLConfig struct {
 	// Broker SASL password
@@ -322,6 +342,10 @@ type ObjectStoreBucket struct {
 	// Defines the endpoint for the Object Storage server configuration.
 	Endpoint *string `json:"endpoint,omitempty" yaml:"endpoint,omitempty" mapstructure:"endpoint,omitempty"`
 
+	// The names of the actual Kafka topics events on the objects in this bucket are
+	// published to.
+	EventTopics []string `json:"eventTopics,omitempty" yaml:"eventTopics,omitempty" mapstructure:"eventTopics,omitempty"`
+
 	// The actual name of the bucket being accessed.
 	Name string `json:"name" yaml:"name" mapstructure:"name"`
 
@@ -486,6 +510,25 @@ func (j *ObjectStoreBucket) UnmarshalJSON(b []byte) error {
 
 var enumValues_BrokerConfigAuthtype = []interface{}{
 	"sasl",
+	"iam",
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *KafkaIAMConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["region"]; !ok || v == nil {
+		return fmt.Errorf("field region in KafkaIAMConfig: required")
+	}
+	type Plain KafkaIAMConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = KafkaIAMConfig(plain)
+	return nil
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -532,11 +575,20 @@ func (j *InMemoryDBConfig) UnmarshalJSON(b []byte) error {
 
 // Topic Configuration
 type TopicConfig struct {
+	// The consumer groups the app declared for this topic.
+	ConsumerGroups []string `json:"consumerGroups,omitempty" yaml:"consumerGroups,omitempty" mapstructure:"consumerGroups,omitempty"`
+
+	// The name of the actual dead-letter topic on the Kafka server for this topic.
+	DeadLetterTopic *string `json:"deadLetterTopic,omitempty" yaml:"deadLetterTopic,omitempty" mapstructure:"deadLetterTopic,omitempty"`
+
 	// The name of the actual topic on the Kafka server.
 	Name string `json:"name" yaml:"name" mapstructure:"name"`
 
 	// The name that the app requested in the ClowdApp definition.
 	RequestedName string `json:"requestedName" yaml:"requestedName" mapstructure:"requestedName"`
+
+	// The names of the actual retry topics on the Kafka server for this topic, in order.
+	RetryTopics []string `json:"retryTopics,omitempty" yaml:"retryTopics,omitempty" mapstructure:"retryTopics,omitempty"`
 }
 
 // UnmarshalJSON implements json.Unmarshaler.
@@ -697,6 +749,36 @@ type PrometheusGatewayConfig struct {
 	Port int `json:"port" yaml:"port" mapstructure:"port"`
 }
 
+// Schema Registry Configuration
+type SchemaRegistryConfig struct {
+	// Schema registry password
+	Password *string `json:"password,omitempty" yaml:"password,omitempty" mapstructure:"password,omitempty"`
+
+	// The URL of the schema registry API.
+	Url string `json:"url" yaml:"url" mapstructure:"url"`
+
+	// Schema registry username
+	Username *string `json:"username,omitempty" yaml:"username,omitempty" mapstructure:"username,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (j *SchemaRegistryConfig) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if v, ok := raw["url"]; !ok || v == nil {
+		return fmt.Errorf("field url in SchemaRegistryConfig: required")
+	}
+	type Plain SchemaRegistryConfig
+	var plain Plain
+	if err := json.Unmarshal(b, &plain); err != nil {
+		return err
+	}
+	*j = SchemaRegistryConfig(plain)
+	return nil
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
 func (j *PrometheusGatewayConfig) UnmarshalJSON(b []byte) error {
 	var raw map[string]interface{}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
+)
+
+// ConsumerLagCollector periodically exports the lag of the consumer groups of the apps in the
+// environments that collect it, and reports on each app whether its lag is within the
+// environment's threshold. Lag changes without any change to the app, so it is collected on its
+// own schedule rather than during reconciliation. Query is used for the environments that read the
+// lag from Prometheus.
+type ConsumerLagCollector struct {
+	Client   client.Client
+	Log      logr.Logger
+	Interval time.Duration
+	Query    kafka.LagQuerier
+}
+
+// Start implements manager.Runnable and collects the lag every interval until ctx is done.
+func (c *ConsumerLagCollector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			c.collect(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the leader updates the apps.
+func (c *ConsumerLagCollector) NeedLeaderElection() bool {
+	return true
+}
+
+func (c *ConsumerLagCollector) collect(ctx context.Context) {
+	envList := crd.ClowdEnvironmentList{}
+	if err := c.Client.List(ctx, &envList); err != nil {
+		c.Log.Info("Could not list environments for consumer lag", "err", err)
+		return
+	}
+
+	for i := range envList.Items {
+		env := &envList.Items[i]
+
+		appList, err := env.GetAppsInEnv(ctx, c.Client)
+		if err != nil {
+			c.Log.Info("Could not list apps for consumer lag", "env", env.Name, "err", err)
+			continue
+		}
+
+		for j := range appList.Items {
+			if err := c.collectApp(ctx, env, &appList.Items[j]); err != nil {
+				c.Log.Info("Could not collect consumer lag", "app", appList.Items[j].GetIdent(), "err", err)
+			}
+		}
+	}
+}
+
+// collectApp exports the lag of an app and sets its condition. Apps in environments that do not
+// collect the lag have their metrics and condition removed. When the lag cannot be read, the
+// condition reports why.
+func (c *ConsumerLagCollector) collectApp(ctx context.Context, env *crd.ClowdEnvironment, app *crd.ClowdApp) error {
+	original := app.DeepCopy()
+	threshold := int64(0)
+	lags := []kafka.ConsumerLag{}
+
+	var lagErr error
+	if env.Spec.Providers.Kafka.ConsumerLag.Enabled && app.GetDeletionTimestamp() == nil {
+		lags, lagErr = c.appLag(ctx, env, app)
+		threshold = env.Spec.Providers.Kafka.ConsumerLag.Threshold
+	}
+
+	kafkaConsumerLagMetrics.DeletePartialMatch(prometheus.Labels{"app": app.GetIdent()})
+	for _, lag := range lags {
+		kafkaConsumerLagMetrics.With(prometheus.Labels{"app": app.GetIdent(), "topic": lag.Topic, "group": lag.Group}).Set(float64(lag.Lag))
+	}
+
+	if lagErr != nil {
+		SetKafkaConsumerLagUnchecked(app, lagErr)
+	} else {
+		SetKafkaConsumerLagCondition(app, lags, threshold)
+	}
+
+	if !equality.Semantic.DeepEqual(original.Status, app.Status) {
+		if err := c.Client.Status().Patch(ctx, app, client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})); err != nil {
+			return err
+		}
+	}
+	return lagErr
+}
+
+// appLag reads the lag of the app's consumer groups, from the Prometheus the environment names or
+// otherwise from the brokers, with the app's own credentials.
+func (c *ConsumerLagCollector) appLag(ctx context.Context, env *crd.ClowdEnvironment, app *crd.ClowdApp) ([]kafka.ConsumerLag, error) {
+	kafkaConfig, err := c.appKafkaConfig(ctx, app)
+	if err != nil || kafkaConfig == nil {
+		return nil, err
+	}
+
+	if url, ok := kafka.GetConsumerLagPrometheusURL(env); ok {
+		return kafka.GetConsumerLag(ctx, c.Query, url, app, kafkaConfig.Topics)
+	}
+
+	admin, err := kafka.NewKafkaAdmin(kafkaConfig.Brokers)
+	if err != nil {
+		return nil, err
+	}
+	defer admin.Close()
+
+	return kafka.GetConsumerLagFromKafka(ctx, admin, app, kafkaConfig.Topics)
+}
+
+// appKafkaConfig returns the kafka section of the app's generated config, none when the config has
+// not been written yet.
+func (c *ConsumerLagCollector) appKafkaConfig(ctx context.Context, app *crd.ClowdApp) (*config.KafkaConfig, error) {
+	secret := &core.Secret{}
+	if err := c.Client.Get(ctx, types.NamespacedName{Name: app.Name, Namespace: app.Namespace}, secret); err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	appConfig := &config.AppConfig{}
+	if err := json.Unmarshal(secret.Data["cdappconfig.json"], appConfig); err != nil {
+		return nil, err
+	}
+	return appConfig.Kafka, nil
+}
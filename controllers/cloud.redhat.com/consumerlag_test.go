@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	cond "sigs.k8s.io/cluster-api/util/conditions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
+)
+
+func TestConsumerLagCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "lag-env"
+	env.Spec.Providers.Kafka.ConsumerLag = crd.KafkaConsumerLagConfig{
+		Enabled:       true,
+		PrometheusURL: "http://prometheus:9090",
+		Threshold:     1000,
+	}
+
+	app := &crd.ClowdApp{}
+	app.Name = "lag-app"
+	app.Namespace = "lag-ns"
+	app.Spec.EnvName = "lag-env"
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{{TopicName: "events", ConsumerGroups: []string{"processor"}}}
+
+	appConfig, err := json.Marshal(&config.AppConfig{Kafka: &config.KafkaConfig{
+		Brokers: []config.BrokerConfig{},
+		Topics:  []config.TopicConfig{{Name: "events-lag-env", RequestedName: "events"}},
+	}})
+	require.NoError(t, err)
+	configSecret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "lag-app", Namespace: "lag-ns"},
+		Data:       map[string][]byte{"cdappconfig.json": appConfig},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(env, app, configSecret).
+		WithStatusSubresource(&crd.ClowdApp{}).
+		WithIndex(&crd.ClowdApp{}, "spec.envName", func(o client.Object) []string {
+			return []string{o.(*crd.ClowdApp).Spec.EnvName}
+		}).
+		Build()
+
+	lag := model.SampleValue(1500)
+	collector := &ConsumerLagCollector{
+		Client: c,
+		Log:    logr.Discard(),
+		Query: func(_ context.Context, _, query string) (model.Vector, error) {
+			assert.Contains(t, query, `topic=~"events-lag-env"`, "the lag is read for the topic names in the app's config")
+			return model.Vector{{Metric: model.Metric{"consumergroup": "processor", "topic": "events-lag-env"}, Value: lag}}, nil
+		},
+	}
+
+	ctx := context.Background()
+	nn := types.NamespacedName{Name: "lag-app", Namespace: "lag-ns"}
+	labels := []string{app.GetIdent(), "events-lag-env", "processor"}
+
+	collector.collect(ctx)
+	assert.Equal(t, 1500.0, testutil.ToFloat64(kafkaConsumerLagMetrics.WithLabelValues(labels...)))
+
+	updated := &crd.ClowdApp{}
+	require.NoError(t, c.Get(ctx, nn, updated))
+	degraded := cond.Get(updated, crd.KafkaConsumersCaughtUp)
+	require.NotNil(t, degraded)
+	assert.Equal(t, metav1.ConditionFalse, degraded.Status)
+	assert.Equal(t, "Kafka consumer groups have a lag over 1000: [processor on events-lag-env: 1500]", degraded.Message)
+
+	lag = 10
+	collector.collect(ctx)
+	require.NoError(t, c.Get(ctx, nn, updated))
+	assert.Equal(t, metav1.ConditionTrue, cond.Get(updated, crd.KafkaConsumersCaughtUp).Status)
+
+	env.Spec.Providers.Kafka.ConsumerLag.Enabled = false
+	require.NoError(t, c.Update(ctx, env))
+	collector.collect(ctx)
+	require.NoError(t, c.Get(ctx, nn, updated))
+	assert.Nil(t, cond.Get(updated, crd.KafkaConsumersCaughtUp), "the condition is dropped when lag is no longer collected")
+	assert.Equal(t, 0, testutil.CollectAndCount(kafkaConsumerLagMetrics, "clowder_kafka_consumer_lag"))
+}
+
+// brokerLag is a kafka.KafkaAdmin serving the lag of a single group.
+type brokerLag struct {
+	group string
+	lag   map[string]int64
+}
+
+func (b *brokerLag) DescribeTopics(context.Context, []string) (map[string]kafka.LiveTopic, error) {
+	return map[string]kafka.LiveTopic{}, nil
+}
+
+func (b *brokerLag) ListGroups(context.Context) ([]string, error) {
+	return []string{b.group}, nil
+}
+
+func (b *brokerLag) GroupLag(context.Context, []string) (map[string]map[string]int64, error) {
+	return map[string]map[string]int64{b.group: b.lag}, nil
+}
+
+func (b *brokerLag) Close() {}
+
+func TestConsumerLagCollectorFromBrokers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "broker-lag-env"
+	env.Spec.Providers.Kafka.ConsumerLag = crd.KafkaConsumerLagConfig{Enabled: true, Threshold: 1000}
+
+	app := &crd.ClowdApp{}
+	app.Name = "broker-lag-app"
+	app.Namespace = "lag-ns"
+	app.Spec.EnvName = "broker-lag-env"
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{{TopicName: "events", ConsumerGroups: []string{"processor"}}}
+
+	appConfig, err := json.Marshal(&config.AppConfig{Kafka: &config.KafkaConfig{
+		Brokers: []config.BrokerConfig{{Hostname: "kafka.example.com"}},
+		Topics:  []config.TopicConfig{{Name: "events-lag-env", RequestedName: "events"}},
+	}})
+	require.NoError(t, err)
+	configSecret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "broker-lag-app", Namespace: "lag-ns"},
+		Data:       map[string][]byte{"cdappconfig.json": appConfig},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(env, app, configSecret).
+		WithStatusSubresource(&crd.ClowdApp{}).
+		WithIndex(&crd.ClowdApp{}, "spec.envName", func(o client.Object) []string {
+			return []string{o.(*crd.ClowdApp).Spec.EnvName}
+		}).
+		Build()
+
+	var adminErr error
+	original := kafka.NewKafkaAdmin
+	kafka.NewKafkaAdmin = func(brokers []config.BrokerConfig) (kafka.KafkaAdmin, error) {
+		assert.Equal(t, "kafka.example.com", brokers[0].Hostname, "the brokers of the app's config are used")
+		if adminErr != nil {
+			return nil, adminErr
+		}
+		return &brokerLag{group: "processor", lag: map[string]int64{"events-lag-env": 1500}}, nil
+	}
+	t.Cleanup(func() { kafka.NewKafkaAdmin = original })
+
+	collector := &ConsumerLagCollector{
+		Client: c,
+		Log:    logr.Discard(),
+		Query: func(context.Context, string, string) (model.Vector, error) {
+			t.Fatal("prometheus is only queried when the environment names it")
+			return nil, nil
+		},
+	}
+
+	ctx := context.Background()
+	nn := types.NamespacedName{Name: "broker-lag-app", Namespace: "lag-ns"}
+
+	collector.collect(ctx)
+	assert.Equal(t, 1500.0, testutil.ToFloat64(kafkaConsumerLagMetrics.WithLabelValues(app.GetIdent(), "events-lag-env", "processor")))
+
+	updated := &crd.ClowdApp{}
+	require.NoError(t, c.Get(ctx, nn, updated))
+	assert.Equal(t, metav1.ConditionFalse, cond.Get(updated, crd.KafkaConsumersCaughtUp).Status)
+
+	adminErr = errors.New("IAM authentication is not supported by the kafka admin client")
+	collector.collect(ctx)
+	require.NoError(t, c.Get(ctx, nn, updated))
+	unchecked := cond.Get(updated, crd.KafkaConsumersCaughtUp)
+	assert.Equal(t, metav1.ConditionUnknown, unchecked.Status)
+	assert.Equal(t, "KafkaConsumerLagUnchecked", unchecked.Reason)
+	assert.Contains(t, unchecked.Message, "IAM authentication")
+}
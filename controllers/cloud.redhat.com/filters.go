@@ -7,6 +7,7 @@ import (
 	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
 	"github.com/go-logr/logr"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -68,6 +69,27 @@ func deploymentUpdateFunc(e event.UpdateEvent) bool {
 	return false
 }
 
+func statefulSetUpdateFunc(e event.UpdateEvent) bool {
+	objOld := e.ObjectOld.(*apps.StatefulSet)
+	objNew := e.ObjectNew.(*apps.StatefulSet)
+	if objNew.GetGeneration() != objOld.GetGeneration() {
+		return true
+	}
+	if objOld.Status.ReadyReplicas != objNew.Status.ReadyReplicas {
+		return true
+	}
+	if objOld.Status.UpdatedReplicas != objNew.Status.UpdatedReplicas {
+		return true
+	}
+	return false
+}
+
+func jobUpdateFunc(e event.UpdateEvent) bool {
+	objOld := e.ObjectOld.(*batch.Job)
+	objNew := e.ObjectNew.(*batch.Job)
+	return len(objOld.Status.Conditions) != len(objNew.Status.Conditions)
+}
+
 func kafkaUpdateFunc(e event.UpdateEvent) bool {
 	objOld := e.ObjectOld.(*strimzi.Kafka)
 	objNew := e.ObjectNew.(*strimzi.Kafka)
@@ -116,6 +138,14 @@ func deploymentFilter(logr logr.Logger, ctrlName string) HandlerFuncs {
 	return genFilterFunc(deploymentUpdateFunc, logr, ctrlName)
 }
 
+func statefulSetFilter(logr logr.Logger, ctrlName string) HandlerFuncs {
+	return genFilterFunc(statefulSetUpdateFunc, logr, ctrlName)
+}
+
+func jobFilter(logr logr.Logger, ctrlName string) HandlerFuncs {
+	return genFilterFunc(jobUpdateFunc, logr, ctrlName)
+}
+
 func kafkaFilter(logr logr.Logger, ctrlName string) HandlerFuncs {
 	return genFilterFunc(kafkaUpdateFunc, logr, ctrlName)
 }
@@ -106,6 +106,20 @@ var (
 		},
 		[]string{"app", "dependency"},
 	)
+	kafkaTopicDriftMetrics = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "clowder_kafka_topic_drift",
+			Help: "Kafka topic settings that differ from those declared, 1 if drifted",
+		},
+		[]string{"app", "topic", "setting"},
+	)
+	kafkaConsumerLagMetrics = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "clowder_kafka_consumer_lag",
+			Help: "Kafka consumer group lag, in messages, summed over the partitions of a topic",
+		},
+		[]string{"app", "topic", "group"},
+	)
 )
 
 func init() {
@@ -120,5 +134,7 @@ func init() {
 		presentEnvsMetric,
 		reconciliationMetrics,
 		dependencyMetrics,
+		kafkaTopicDriftMetrics,
+		kafkaConsumerLagMetrics,
 	)
 }
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	keda "github.com/kedacore/keda/v2/apis/keda/v1alpha1"
-	apps "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/types"
 
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
@@ -22,12 +21,12 @@ func makeAutoScalers(deployment *crd.Deployment, app *crd.ClowdApp, c *config.Ap
 		return err
 	}
 
-	d := &apps.Deployment{}
-	if err := asp.Cache.Get(deployProvider.CoreDeployment, d, nn); err != nil {
+	w, err := deployProvider.GetWorkload(asp.Cache, app, deployment)
+	if err != nil {
 		return err
 	}
 
-	initAutoScaler(asp.Env, app, d, s, nn, deployment, c)
+	initAutoScaler(asp.Env, app, w, s, nn, deployment, c)
 
 	return asp.Cache.Update(CoreAutoScaler, s)
 }
@@ -38,14 +37,14 @@ func ProvideKedaAutoScaler(app *crd.ClowdApp, c *config.AppConfig, asp *provider
 	return err
 }
 
-func initAutoScaler(env *crd.ClowdEnvironment, app *crd.ClowdApp, d *apps.Deployment, s *keda.ScaledObject, nn types.NamespacedName, deployment *crd.Deployment, c *config.AppConfig) {
+func initAutoScaler(env *crd.ClowdEnvironment, app *crd.ClowdApp, w *deployProvider.Workload, s *keda.ScaledObject, nn types.NamespacedName, deployment *crd.Deployment, c *config.AppConfig) {
 	labels := app.GetLabels()
 	labels["pod"] = nn.Name
 	app.SetObjectMeta(s, crd.Name(nn.Name), crd.Labels(labels))
 
-	// Set up the watcher to watch the Deployment we created earlier.
+	// Set up the watcher to watch the Deployment or StatefulSet we created earlier.
 	scalerSpec := keda.ScaledObjectSpec{
-		ScaleTargetRef:  &keda.ScaleTarget{Name: d.Name, Kind: d.Kind, APIVersion: d.APIVersion},
+		ScaleTargetRef:  &keda.ScaleTarget{Name: w.GetName(), Kind: string(w.Kind), APIVersion: DeploymentAPIVersion},
 		PollingInterval: deployment.AutoScaler.PollingInterval,
 		CooldownPeriod:  deployment.AutoScaler.CooldownPeriod,
 		Advanced:        deployment.AutoScaler.Advanced,
@@ -91,7 +90,12 @@ func getTriggerRoute(triggerType string, c *config.AppConfig, env *crd.ClowdEnvi
 				serverAddr = fmt.Sprintf("%s:%d", serverAddr, *broker.Port)
 			}
 			bootstrapServers = append(bootstrapServers, serverAddr)
-			if idx == 0 && broker.Sasl != nil {
+			if idx == 0 && broker.Iam != nil {
+				// the role is given by the trigger's authenticationRef, not in its metadata
+				result["sasl"] = "aws_msk_iam"
+				result["awsRegion"] = broker.Iam.Region
+				result["tls"] = "enable"
+			} else if idx == 0 && broker.Sasl != nil {
 				result["sasl"] = *broker.Sasl.SaslMechanism
 				result["tls"] = "enable"
 			}
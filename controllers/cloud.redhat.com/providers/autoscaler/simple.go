@@ -5,7 +5,6 @@ import (
 
 	res "k8s.io/apimachinery/pkg/api/resource"
 
-	apps "k8s.io/api/apps/v1"
 	v2 "k8s.io/api/autoscaling/v2"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,11 +29,11 @@ const (
 
 // ProvideSimpleAutoScaler creates a simple HPA in the resource cache for the deployment and ClowdApp
 func ProvideSimpleAutoScaler(app *crd.ClowdApp, appConfig *config.AppConfig, sp *providers.Provider, deployment *crd.Deployment) error {
-	cachedDeployment, err := getDeploymentFromCache(deployment, app, sp)
+	workload, err := deployProvider.GetWorkload(sp.Cache, app, deployment)
 	if err != nil {
 		return errors.Wrap("Could not get deployment from resource cache", err)
 	}
-	hpaMaker := newSimpleHPAMaker(deployment, app, appConfig, cachedDeployment)
+	hpaMaker := newSimpleHPAMaker(deployment, app, appConfig, workload)
 	hpaResource := hpaMaker.getResource()
 
 	err = cacheAutoscaler(app, sp, deployment, &hpaResource)
@@ -51,18 +50,8 @@ func cacheAutoscaler(app *crd.ClowdApp, sp *providers.Provider, deployment *crd.
 	return sp.Cache.Create(SimpleAutoScaler, nn, hpaResource)
 }
 
-// Get the core apps.Deployment from the provider cache
-func getDeploymentFromCache(clowdDeployment *crd.Deployment, app *crd.ClowdApp, sp *providers.Provider) (*apps.Deployment, error) {
-	nn := app.GetDeploymentNamespacedName(clowdDeployment)
-	d := &apps.Deployment{}
-	if err := sp.Cache.Get(deployProvider.CoreDeployment, d, nn); err != nil {
-		return d, err
-	}
-	return d, nil
-}
-
 // Factory for the simpleHPAMaker
-func newSimpleHPAMaker(deployment *crd.Deployment, app *crd.ClowdApp, appConfig *config.AppConfig, coreDeployment *apps.Deployment) simpleHPAMaker {
+func newSimpleHPAMaker(deployment *crd.Deployment, app *crd.ClowdApp, appConfig *config.AppConfig, coreDeployment *deployProvider.Workload) simpleHPAMaker {
 	return simpleHPAMaker{
 		deployment:     deployment,
 		app:            app,
@@ -77,7 +66,7 @@ type simpleHPAMaker struct {
 	deployment     *crd.Deployment
 	app            *crd.ClowdApp
 	appConfig      *config.AppConfig
-	coreDeployment *apps.Deployment
+	coreDeployment *deployProvider.Workload
 }
 
 // Constructs the HPA in 2 parts: the HPA itself and the metric spec
@@ -101,13 +90,13 @@ func (d *simpleHPAMaker) makeHPA() v2.HorizontalPodAutoscaler {
 					UID:        d.app.UID,
 				}},
 			Name:      name,
-			Namespace: d.coreDeployment.Namespace,
+			Namespace: d.coreDeployment.GetNamespace(),
 		},
 		Spec: v2.HorizontalPodAutoscalerSpec{
 			ScaleTargetRef: v2.CrossVersionObjectReference{
 				APIVersion: DeploymentAPIVersion,
-				Kind:       DeploymentKind,
-				Name:       d.coreDeployment.Name,
+				Kind:       string(d.coreDeployment.Kind),
+				Name:       d.coreDeployment.GetName(),
 			},
 			MinReplicas: &d.deployment.AutoScalerSimple.Replicas.Min,
 			MaxReplicas: d.deployment.AutoScalerSimple.Replicas.Max,
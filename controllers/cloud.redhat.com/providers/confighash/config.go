@@ -2,18 +2,23 @@
 package confighash
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
 	"github.com/RedHatInsights/rhc-osdk-utils/utils"
-	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	cronjobProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/cronjob"
 	deployProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/deployment"
 )
 
@@ -124,8 +129,8 @@ func (ch *confighashProvider) volSecret(app *crd.ClowdApp, volume *core.Volume)
 	return ch.HashCache.AddClowdObjectToObject(app, sec)
 }
 
-func (ch *confighashProvider) iterateEnvVars(app *crd.ClowdApp, deployment apps.Deployment) error {
-	for _, cont := range deployment.Spec.Template.Spec.Containers {
+func (ch *confighashProvider) iterateEnvVars(app *crd.ClowdApp, template *core.PodTemplateSpec) error {
+	for _, cont := range template.Spec.Containers {
 		for _, env := range cont.Env {
 			if err := ch.envConfigMap(app, env); err != nil {
 				return err
@@ -139,9 +144,9 @@ func (ch *confighashProvider) iterateEnvVars(app *crd.ClowdApp, deployment apps.
 	return nil
 }
 
-func (ch *confighashProvider) iterateVolumes(app *crd.ClowdApp, deployment *apps.Deployment) error {
-	for i := range deployment.Spec.Template.Spec.Volumes {
-		volume := &deployment.Spec.Template.Spec.Volumes[i]
+func (ch *confighashProvider) iterateVolumes(app *crd.ClowdApp, template *core.PodTemplateSpec) error {
+	for i := range template.Spec.Volumes {
+		volume := &template.Spec.Volumes[i]
 		if err := ch.volConfigMap(app, volume); err != nil {
 			return err
 		}
@@ -153,13 +158,12 @@ func (ch *confighashProvider) iterateVolumes(app *crd.ClowdApp, deployment *apps
 	return nil
 }
 
-func (ch *confighashProvider) updateHashCache(dList *apps.DeploymentList, app *crd.ClowdApp) error {
-	for i := range dList.Items {
-		deploy := &dList.Items[i]
-		if err := ch.iterateEnvVars(app, *deploy); err != nil {
+func (ch *confighashProvider) updateHashCache(workloads []*deployProvider.Workload, app *crd.ClowdApp) error {
+	for _, w := range workloads {
+		if err := ch.iterateEnvVars(app, w.Template); err != nil {
 			return err
 		}
-		if err := ch.iterateVolumes(app, deploy); err != nil {
+		if err := ch.iterateVolumes(app, w.Template); err != nil {
 			return err
 		}
 	}
@@ -176,12 +180,12 @@ func (ch *confighashProvider) persistConfig(app *crd.ClowdApp) (string, error) {
 		return "", err
 	}
 
-	dList := apps.DeploymentList{}
-	if err := ch.Cache.List(deployProvider.CoreDeployment, &dList); err != nil {
+	workloads, err := deployProvider.ListWorkloads(ch.Cache)
+	if err != nil {
 		return "", err
 	}
 
-	if err := ch.updateHashCache(&dList, app); err != nil {
+	if err := ch.updateHashCache(workloads, app); err != nil {
 		return "", err
 	}
 
@@ -214,5 +218,110 @@ func (ch *confighashProvider) persistConfig(app *crd.ClowdApp) (string, error) {
 		return "", err
 	}
 
+	if err := ch.persistPreDeployConfig(app, jsonData); err != nil {
+		return "", err
+	}
+
 	return hash, err
 }
+
+// persistPreDeployConfig writes the config into the secret the app's preDeploy jobs are run with.
+func (ch *confighashProvider) persistPreDeployConfig(app *crd.ClowdApp, jsonData []byte) error {
+	if !cronjobProvider.HasPreDeployJobs(app) {
+		return nil
+	}
+
+	hash, err := cronjobProvider.GetPreDeployHash(app)
+	if err != nil {
+		return err
+	}
+
+	nn := types.NamespacedName{
+		Name:      cronjobProvider.GetPreDeployConfigSecretName(app, hash),
+		Namespace: app.Namespace,
+	}
+
+	secret := &core.Secret{}
+	if err := ch.Cache.Create(PreDeployConfigSecret, nn, secret); err != nil {
+		return err
+	}
+
+	secret.Data = map[string][]byte{
+		"cdappconfig.json": jsonData,
+	}
+
+	app.SetObjectMeta(secret, crd.Name(nn.Name))
+
+	return ch.Cache.Update(PreDeployConfigSecret, secret)
+}
+
+// HoldConfig stops the cache from updating the app's config secret, so that the pods of held
+// workloads keep the config matching their spec. A config secret that does not exist yet is
+// created as usual, as no pods can be using it. The Kafka credentials of the current config are
+// carried into the held one, as credentials that have been rotated out stop working whether or not
+// the workloads are held.
+func HoldConfig(ctx context.Context, c client.Client, cache *rc.ObjectCache, app *crd.ClowdApp, current *config.AppConfig) error {
+	live := &core.Secret{}
+	err := c.Get(ctx, app.GetNamespacedName("%s"), live)
+	if k8serr.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := refreshHeldCredentials(live, current); err != nil {
+		return err
+	}
+
+	return cache.Update(CoreConfigSecret, live)
+}
+
+// refreshHeldCredentials updates the SASL credentials of the brokers in the held config secret
+// with those the same brokers have in the current config.
+func refreshHeldCredentials(held *core.Secret, current *config.AppConfig) error {
+	if current == nil || current.Kafka == nil {
+		return nil
+	}
+
+	heldConfig := &config.AppConfig{}
+	if err := json.Unmarshal(held.Data["cdappconfig.json"], heldConfig); err != nil {
+		return errors.Wrap("Failed to read held config JSON", err)
+	}
+	if heldConfig.Kafka == nil {
+		return nil
+	}
+
+	brokerKey := func(b config.BrokerConfig) string {
+		port := 0
+		if b.Port != nil {
+			port = *b.Port
+		}
+		return fmt.Sprintf("%s:%d", b.Hostname, port)
+	}
+
+	sasl := map[string]*config.KafkaSASLConfig{}
+	for _, broker := range current.Kafka.Brokers {
+		sasl[brokerKey(broker)] = broker.Sasl
+	}
+
+	changed := false
+	for i, broker := range heldConfig.Kafka.Brokers {
+		creds, ok := sasl[brokerKey(broker)]
+		if !ok || broker.Sasl == nil || creds == nil || equality.Semantic.DeepEqual(broker.Sasl, creds) {
+			continue
+		}
+		heldConfig.Kafka.Brokers[i].Sasl = creds
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(heldConfig)
+	if err != nil {
+		return errors.Wrap("Failed to marshal config JSON", err)
+	}
+	held.Data["cdappconfig.json"] = jsonData
+	return nil
+}
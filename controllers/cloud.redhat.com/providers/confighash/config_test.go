@@ -0,0 +1,122 @@
+package confighash
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
+	p "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+	cronjobProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/cronjob"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func TestPreDeployConfigAndHold(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	app := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Spec:       crd.ClowdAppSpec{Jobs: []crd.Job{{Name: "migrate", Phase: crd.JobPhasePreDeploy}}},
+	}
+	live := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Data:       map[string][]byte{"cdappconfig.json": []byte(`{"old":true}`)},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(live).Build()
+	log := logr.Discard()
+	cache := rc.NewObjectCache(context.TODO(), c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+	hc := hashcache.NewHashCache()
+
+	ch := &confighashProvider{Provider: p.Provider{
+		Ctx:       context.TODO(),
+		Client:    c,
+		Cache:     &cache,
+		Env:       &crd.ClowdEnvironment{},
+		Config:    &config.AppConfig{},
+		HashCache: &hc,
+	}}
+	_, err := ch.persistConfig(app)
+	require.NoError(t, err)
+
+	hash, err := cronjobProvider.GetPreDeployHash(app)
+	require.NoError(t, err)
+
+	preDeploy := &core.Secret{}
+	require.NoError(t, cache.Get(PreDeployConfigSecret, preDeploy))
+	assert.Equal(t, cronjobProvider.GetPreDeployConfigSecretName(app, hash), preDeploy.Name)
+
+	current := &core.Secret{}
+	require.NoError(t, cache.Get(CoreConfigSecret, current))
+	assert.Equal(t, preDeploy.Data, current.Data, "the preDeploy jobs get the new config")
+
+	require.NoError(t, HoldConfig(context.TODO(), c, &cache, app, ch.Config))
+
+	held := &core.Secret{}
+	require.NoError(t, cache.Get(CoreConfigSecret, held))
+	assert.Equal(t, live.Data, held.Data, "held workloads keep the live config")
+}
+
+func TestHoldConfigRefreshesKafkaCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	app := &crd.ClowdApp{ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"}}
+
+	appConfig := func(username, password, topic string) *config.AppConfig {
+		return &config.AppConfig{
+			Kafka: &config.KafkaConfig{
+				Brokers: []config.BrokerConfig{{
+					Hostname: "kafka-bootstrap.kafka.svc",
+					Port:     utils.IntPtr(9093),
+					Authtype: (*config.BrokerConfigAuthtype)(utils.StringPtr(string(config.BrokerConfigAuthtypeSasl))),
+					Sasl: &config.KafkaSASLConfig{
+						Username:      utils.StringPtr(username),
+						Password:      utils.StringPtr(password),
+						SaslMechanism: utils.StringPtr("SCRAM-SHA-512"),
+					},
+				}},
+				Topics: []config.TopicConfig{{Name: topic, RequestedName: topic}},
+			},
+		}
+	}
+
+	// The held pods were configured before the credentials were rotated and before the spec
+	// that is waiting on its preDeploy jobs added a topic.
+	heldJSON, err := json.Marshal(appConfig("test-puptoo", "old", "events"))
+	require.NoError(t, err)
+	live := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Data:       map[string][]byte{"cdappconfig.json": heldJSON},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(live).Build()
+	log := logr.Discard()
+	cache := rc.NewObjectCache(context.TODO(), c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+
+	require.NoError(t, cache.Create(CoreConfigSecret, app.GetNamespacedName("%s"), &core.Secret{}))
+	require.NoError(t, HoldConfig(context.TODO(), c, &cache, app, appConfig("test-puptoo.alt", "new", "ingress")))
+
+	held := &core.Secret{}
+	require.NoError(t, cache.Get(CoreConfigSecret, held))
+
+	heldConfig := &config.AppConfig{}
+	require.NoError(t, json.Unmarshal(held.Data["cdappconfig.json"], heldConfig))
+	assert.Equal(t, "test-puptoo.alt", *heldConfig.Kafka.Brokers[0].Sasl.Username, "held pods get the rotated credentials")
+	assert.Equal(t, "new", *heldConfig.Kafka.Brokers[0].Sasl.Password)
+	assert.Equal(t, "events", heldConfig.Kafka.Topics[0].Name, "the rest of the config stays held")
+}
@@ -1,7 +1,6 @@
 package confighash
 
 import (
-	apps "k8s.io/api/apps/v1"
 	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 
@@ -21,9 +20,13 @@ type confighashProvider struct {
 // CoreConfigSecret is the config that is presented as the cdappconfig.json file.
 var CoreConfigSecret = rc.NewSingleResourceIdent(ProvName, "core_config_secret", &core.Secret{})
 
+// PreDeployConfigSecret is the config presented to the preDeploy jobs of the app's current spec,
+// which need the new config while the workloads are held on the previous one.
+var PreDeployConfigSecret = rc.NewSingleResourceIdent(ProvName, "predeploy_config_secret", &core.Secret{})
+
 // NewConfigHashProvider returns a new End provider run at the end of the provider set.
 func NewConfigHashProvider(p *p.Provider) (p.ClowderProvider, error) {
-	p.Cache.AddPossibleGVKFromIdent(CoreConfigSecret)
+	p.Cache.AddPossibleGVKFromIdent(CoreConfigSecret, PreDeployConfigSecret)
 	return &confighashProvider{Provider: *p}, nil
 }
 
@@ -39,17 +42,16 @@ func (ch *confighashProvider) Provide(app *crd.ClowdApp) error {
 		return err
 	}
 
-	dList := apps.DeploymentList{}
-	if err := ch.Cache.List(deployProvider.CoreDeployment, &dList); err != nil {
+	workloads, err := deployProvider.ListWorkloads(ch.Cache)
+	if err != nil {
 		return err
 	}
 
-	for i := range dList.Items {
-		depInner := &dList.Items[i]
+	for _, w := range workloads {
 		annotations := map[string]string{"configHash": hash}
-		utils.UpdateAnnotations(&depInner.Spec.Template, annotations)
+		utils.UpdateAnnotations(w.Template, annotations)
 
-		if err := ch.Cache.Update(deployProvider.CoreDeployment, depInner); err != nil {
+		if err := deployProvider.UpdateWorkload(ch.Cache, w); err != nil {
 			return err
 		}
 	}
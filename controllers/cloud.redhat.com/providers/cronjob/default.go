@@ -16,9 +16,12 @@ type cronjobProvider struct {
 // CoreCronJob is the cronjob for the apps cronjobs.
 var CoreCronJob = rc.NewMultiResourceIdent(ProvName, "core_cronjob", &batch.CronJob{})
 
+// CorePreDeployJob is the job run ahead of a rollout for the apps preDeploy jobs.
+var CorePreDeployJob = rc.NewMultiResourceIdent(ProvName, "core_predeploy_job", &batch.Job{})
+
 // NewCronJobProvider creates a new cron job provider instance
 func NewCronJobProvider(p *p.Provider) (p.ClowderProvider, error) {
-	p.Cache.AddPossibleGVKFromIdent(CoreCronJob)
+	p.Cache.AddPossibleGVKFromIdent(CoreCronJob, CorePreDeployJob)
 	return &cronjobProvider{Provider: *p}, nil
 }
 
@@ -28,9 +31,21 @@ func (j *cronjobProvider) EnvProvide() error {
 
 func (j *cronjobProvider) Provide(app *crd.ClowdApp) error {
 
+	hash, err := GetPreDeployHash(app)
+	if err != nil {
+		return err
+	}
+
 	for i := range app.Spec.Jobs {
 		innerCronjob := &app.Spec.Jobs[i]
-		if innerCronjob.Schedule != "" && !innerCronjob.Disabled {
+		if innerCronjob.Disabled {
+			continue
+		}
+		if innerCronjob.IsPreDeploy() {
+			if err := j.makePreDeployJob(innerCronjob, app, hash); err != nil {
+				return err
+			}
+		} else if innerCronjob.Schedule != "" {
 			if err := j.makeCronJob(innerCronjob, app); err != nil {
 				return err
 			}
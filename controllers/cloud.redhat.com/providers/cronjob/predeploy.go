@@ -0,0 +1,166 @@
+package cronjob
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	deployProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/deployment"
+	provutils "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/utils"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// PreDeployHashAnnotation records the spec hash a preDeploy job was rendered for.
+const PreDeployHashAnnotation = "clowder/pre-deploy-hash"
+
+// ReleasePreDeployHoldAnnotation lets the app's workloads roll out even though its preDeploy jobs
+// have not succeeded. It only applies while its value is the hash of the current spec, the suffix
+// of the preDeploy job names, so that the jobs hold the workloads again once the app changes.
+const ReleasePreDeployHoldAnnotation = "clowder/release-predeploy-hold"
+
+// maxPreDeployJobNameLength is the longest name a Job can have, as it is copied into the labels
+// of its pods.
+const maxPreDeployJobNameLength = 63
+
+// GetPreDeployJobName generates a name for a preDeploy job, the hash is part of the name so that
+// a new Job is run each time the app changes. Names that would be too long are truncated, with a
+// hash of the full name added to keep them unique.
+func GetPreDeployJobName(app *crd.ClowdApp, job *crd.Job, hash string) string {
+	name := fmt.Sprintf("%s-%s", app.Name, job.Name)
+	if len(name)+len(hash)+1 <= maxPreDeployJobNameLength {
+		return fmt.Sprintf("%s-%s", name, hash)
+	}
+
+	nameHash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:8]
+	prefix := strings.TrimRight(name[:maxPreDeployJobNameLength-len(nameHash)-len(hash)-2], "-.")
+	return fmt.Sprintf("%s-%s-%s", prefix, nameHash, hash)
+}
+
+// GetPreDeployConfigSecretName returns the name of the secret holding the config the preDeploy
+// jobs for the hash are run with.
+func GetPreDeployConfigSecretName(app *crd.ClowdApp, hash string) string {
+	return fmt.Sprintf("%s-predeploy-%s", app.Name, hash)
+}
+
+// HasPreDeployJobs returns whether the app has any enabled preDeploy jobs.
+func HasPreDeployJobs(app *crd.ClowdApp) bool {
+	for _, job := range app.Spec.Jobs {
+		if job.IsPreDeploy() && !job.Disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPreDeployHash returns a short hash of everything that should cause the preDeploy jobs to be
+// run again, the pod specs of the app's jobs and deployments.
+func GetPreDeployHash(app *crd.ClowdApp) (string, error) {
+	specs := []crd.PodSpec{}
+	for _, job := range app.Spec.Jobs {
+		if job.IsPreDeploy() {
+			specs = append(specs, job.PodSpec)
+		}
+	}
+	for _, deployment := range app.Spec.Deployments {
+		specs = append(specs, deployment.PodSpec)
+	}
+
+	data, err := json.Marshal(specs)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:8], nil
+}
+
+// PreDeployHoldReleased returns whether the hold on the app's workloads has been released for
+// the current spec with ReleasePreDeployHoldAnnotation.
+func PreDeployHoldReleased(app *crd.ClowdApp) (bool, error) {
+	release, ok := app.GetAnnotations()[ReleasePreDeployHoldAnnotation]
+	if !ok {
+		return false, nil
+	}
+	hash, err := GetPreDeployHash(app)
+	if err != nil {
+		return false, err
+	}
+	return release == hash, nil
+}
+
+// GetPreDeployJobs returns the preDeploy jobs rendered into the cache, along with their status as
+// last read from the cluster.
+func GetPreDeployJobs(cache *rc.ObjectCache) ([]batch.Job, error) {
+	jList := batch.JobList{}
+	if err := cache.List(CorePreDeployJob, &jList); err != nil {
+		return nil, err
+	}
+	return jList.Items, nil
+}
+
+func (j *cronjobProvider) makePreDeployJob(job *crd.Job, app *crd.ClowdApp, hash string) error {
+
+	nn := types.NamespacedName{
+		Name:      GetPreDeployJobName(app, job, hash),
+		Namespace: app.Namespace,
+	}
+
+	bj := &batch.Job{}
+	if err := j.Cache.Create(CorePreDeployJob, nn, bj); err != nil {
+		return err
+	}
+
+	// The pod template of a Job is immutable, once it has been created for this hash it is left
+	// exactly as it is.
+	if bj.CreationTimestamp.IsZero() {
+		pt := core.PodTemplateSpec{}
+		if err := buildPodTemplate(app, j.Env, &pt, nn, job); err != nil {
+			return err
+		}
+		// The workloads keep the config secret matching their spec while they are held, the jobs
+		// are given the config of the new spec from a secret of their own.
+		for i := range pt.Spec.Volumes {
+			if vol := &pt.Spec.Volumes[i]; vol.Name == "config-secret" && vol.Secret != nil {
+				vol.Secret.SecretName = GetPreDeployConfigSecretName(app, hash)
+			}
+		}
+		applyPreDeployJob(app, bj, &pt, nn, job, hash)
+	}
+
+	return j.Cache.Update(CorePreDeployJob, bj)
+}
+
+func applyPreDeployJob(app *crd.ClowdApp, bj *batch.Job, pt *core.PodTemplateSpec, nn types.NamespacedName, job *crd.Job, hash string) {
+	labels := app.GetLabels()
+	labels["pod"] = nn.Name
+	labels["job"] = job.Name
+	app.SetObjectMeta(bj, crd.Name(nn.Name), crd.Labels(labels))
+
+	utils.UpdateAnnotations(pt, provutils.KubeLinterAnnotations)
+	utils.UpdateAnnotations(bj, provutils.KubeLinterAnnotations, app.Annotations, map[string]string{PreDeployHashAnnotation: hash})
+
+	bj.Spec.Template = *pt
+	bj.Spec.ActiveDeadlineSeconds = job.ActiveDeadlineSeconds
+
+	if job.RestartPolicy == "" {
+		bj.Spec.Template.Spec.RestartPolicy = core.RestartPolicyNever
+	} else {
+		bj.Spec.Template.Spec.RestartPolicy = job.RestartPolicy
+	}
+
+	if job.Parallelism != nil {
+		bj.Spec.Parallelism = job.Parallelism
+	}
+
+	if job.Completions != nil {
+		bj.Spec.Completions = job.Completions
+	}
+
+	deployProvider.ApplyPodAntiAffinity(&bj.Spec.Template)
+}
@@ -0,0 +1,90 @@
+package cronjob
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+func preDeployTestApp() *crd.ClowdApp {
+	return &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test"},
+		Spec: crd.ClowdAppSpec{
+			Jobs: []crd.Job{
+				{Name: "migrate", Phase: crd.JobPhasePreDeploy, PodSpec: crd.PodSpec{Image: "quay.io/puptoo:1"}},
+				{Name: "nightly", Schedule: "@daily", PodSpec: crd.PodSpec{Image: "quay.io/puptoo:1"}},
+			},
+			Deployments: []crd.Deployment{
+				{Name: "processor", PodSpec: crd.PodSpec{Image: "quay.io/puptoo:1"}},
+			},
+		},
+	}
+}
+
+func TestGetPreDeployHash(t *testing.T) {
+	app := preDeployTestApp()
+	hash, err := GetPreDeployHash(app)
+	require.NoError(t, err)
+	assert.Len(t, hash, 8)
+
+	app.Spec.Jobs[1].PodSpec.Image = "quay.io/puptoo:2"
+	same, err := GetPreDeployHash(app)
+	require.NoError(t, err)
+	assert.Equal(t, hash, same, "cron jobs do not rerun the preDeploy jobs")
+
+	app.Spec.Deployments[0].PodSpec.Image = "quay.io/puptoo:2"
+	changed, err := GetPreDeployHash(app)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changed, "a deployment change reruns the preDeploy jobs")
+
+	app.Spec.Jobs[0].PodSpec.Image = "quay.io/puptoo:2"
+	changedAgain, err := GetPreDeployHash(app)
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, changedAgain, "a preDeploy job change reruns the preDeploy jobs")
+}
+
+func TestGetPreDeployJobName(t *testing.T) {
+	app := preDeployTestApp()
+	assert.Equal(t, "puptoo-migrate-0123abcd", GetPreDeployJobName(app, &app.Spec.Jobs[0], "0123abcd"))
+
+	app.Name = strings.Repeat("a", 40)
+	first := GetPreDeployJobName(app, &crd.Job{Name: strings.Repeat("b", 30) + "-one"}, "0123abcd")
+	second := GetPreDeployJobName(app, &crd.Job{Name: strings.Repeat("b", 30) + "-two"}, "0123abcd")
+
+	assert.LessOrEqual(t, len(first), 63)
+	assert.LessOrEqual(t, len(second), 63)
+	assert.True(t, strings.HasSuffix(first, "-0123abcd"))
+	assert.NotEqual(t, first, second, "truncated names stay unique")
+}
+
+func TestHasPreDeployJobs(t *testing.T) {
+	app := preDeployTestApp()
+	assert.True(t, HasPreDeployJobs(app))
+
+	app.Spec.Jobs[0].Disabled = true
+	assert.False(t, HasPreDeployJobs(app))
+}
+
+func TestPreDeployHoldReleased(t *testing.T) {
+	app := preDeployTestApp()
+	released, err := PreDeployHoldReleased(app)
+	require.NoError(t, err)
+	assert.False(t, released)
+
+	hash, err := GetPreDeployHash(app)
+	require.NoError(t, err)
+	app.Annotations = map[string]string{ReleasePreDeployHoldAnnotation: hash}
+	released, err = PreDeployHoldReleased(app)
+	require.NoError(t, err)
+	assert.True(t, released)
+
+	app.Spec.Deployments[0].PodSpec.Image = "quay.io/puptoo:2"
+	released, err = PreDeployHoldReleased(app)
+	require.NoError(t, err)
+	assert.False(t, released, "a release only applies to the spec it was given for")
+}
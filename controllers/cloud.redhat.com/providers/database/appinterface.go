@@ -62,6 +62,11 @@ func NewAppInterfaceDBProvider(p *providers.Provider) (providers.ClowderProvider
 }
 
 func (a *appInterface) EnvProvide() error {
+	// The CA bundle is fetched from outside the cluster, offline the config is rendered without it.
+	if a.Offline {
+		return nil
+	}
+
 	caURL := a.Env.Spec.Providers.Database.CaBundleURL
 	if caURL == "" {
 		caURL = defaultCaBundleURL
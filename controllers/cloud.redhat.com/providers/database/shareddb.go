@@ -2,14 +2,16 @@ package database
 
 import (
 	"context"
+	errlib "errors"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"time"
 
 	"database/sql"
 
-	_ "github.com/lib/pq" // Required to load postgres
+	"github.com/lib/pq" // Also registers the postgres driver
 
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
@@ -20,6 +22,7 @@ import (
 
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -217,20 +220,8 @@ func (db *sharedDbProvider) Provide(app *crd.ClowdApp) error {
 		return db.processSharedDB(app)
 	}
 
-	version := int32(12)
-	if app.Spec.Database.Version != nil {
-		version = *app.Spec.Database.Version
-	}
-
-	var dbCfg config.DatabaseConfig
-
-	vSec := &core.Secret{}
-	vSecnn := types.NamespacedName{
-		Name:      fmt.Sprintf("%s-db-v%s", db.Env.Name, strconv.Itoa(int(version))),
-		Namespace: db.Env.Status.TargetNamespace,
-	}
-
-	if err := db.Client.Get(db.Ctx, vSecnn, vSec); err != nil {
+	vSec, err := db.getVersionedSecret(app)
+	if err != nil {
 		return err
 	}
 
@@ -239,93 +230,393 @@ func (db *sharedDbProvider) Provide(app *crd.ClowdApp) error {
 		return err
 	}
 
+	var dbCfg config.DatabaseConfig
+
 	dbCfg.AdminUsername = "postgres"
 	dbCfg.AdminPassword = string(vSec.Data["pgPass"])
 	dbCfg.Hostname = string(vSec.Data["hostname"])
 	dbCfg.Name = app.Spec.Database.Name
-	dbCfg.Password = string(vSec.Data["password"])
-	dbCfg.Username = string(vSec.Data["username"])
 	dbCfg.Port = int(port)
 	dbCfg.SslMode = "disable"
 
-	host := dbCfg.Hostname
-	user := dbCfg.AdminUsername
-	password := dbCfg.AdminPassword
-	dbname := app.Spec.Database.Name
+	nn := types.NamespacedName{
+		Name:      fmt.Sprintf("%v-db", app.Name),
+		Namespace: app.Namespace,
+	}
 
-	appSQLConnectionString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, dbname)
+	secret := &core.Secret{}
+	if err := db.Cache.Create(SharedDBAppSecret, nn, secret); err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(db.Ctx, 5*time.Second)
-	defer cancel()
+	// Apps provisioned before per-app roles existed carry the env-wide credentials
+	// in their secret, these are swapped out for a role of their own.
+	dbCfg.Username = string(secret.Data["username"])
+	dbCfg.Password = string(secret.Data["password"])
+	if dbCfg.Username == "" || dbCfg.Username == string(vSec.Data["username"]) {
+		dbCfg.Username = utils.RandString(16)
+		dbCfg.Password, err = utils.RandPassword(16, provutils.RCharSet)
+		if err != nil {
+			return errors.Wrap("password generate failed", err)
+		}
+	}
 
-	dbClient, err := sql.Open("postgres", appSQLConnectionString)
-	if err != nil {
+	if !db.Offline {
+		ctx, cancel := context.WithTimeout(db.Ctx, 5*time.Second)
+		defer cancel()
+
+		if err := ensureSharedDBRole(ctx, &dbCfg, db.Env.Name, string(vSec.Data["username"])); err != nil {
+			return errors.Wrap("couldn't provision app db role", err)
+		}
+	}
+
+	if err := db.updateAppSecret(app, secret, &dbCfg); err != nil {
 		return err
 	}
 
-	defer dbClient.Close() // nolint:errcheck  // no need to check error return value
+	db.Config.Database = &dbCfg
 
-	pErr := dbClient.PingContext(ctx)
-	if pErr != nil {
-		if strings.Contains(pErr.Error(), fmt.Sprintf("database \"%s\" does not exist", app.Spec.Database.Name)) {
+	return nil
+}
 
-			envSQLConnectionString := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, db.Env.Name)
+// updateAppSecret writes the app's credentials for the shared db to its secret.
+func (db *sharedDbProvider) updateAppSecret(app *crd.ClowdApp, secret *core.Secret, dbCfg *config.DatabaseConfig) error {
+	secret.StringData = map[string]string{
+		"hostname": dbCfg.Hostname,
+		"port":     strconv.Itoa(dbCfg.Port),
+		"username": dbCfg.Username,
+		"password": dbCfg.Password,
+		"pgPass":   dbCfg.AdminPassword,
+		"name":     dbCfg.Name,
+	}
 
-			envDbClient, envErr := sql.Open("postgres", envSQLConnectionString)
-			if envErr != nil {
-				return envErr
-			}
+	secret.Name = fmt.Sprintf("%v-db", app.Name)
+	secret.Namespace = app.Namespace
+	secret.OwnerReferences = []metav1.OwnerReference{app.MakeOwnerReference()}
+	secret.Type = core.SecretTypeOpaque
 
-			defer envDbClient.Close() // nolint:errcheck  // no need to check error return value
+	return db.Cache.Update(SharedDBAppSecret, secret)
+}
 
-			sqlStatement := fmt.Sprintf("CREATE DATABASE \"%s\" WITH OWNER=\"%s\";", app.Spec.Database.Name, dbCfg.Username)
-			preppedStatement, err := envDbClient.PrepareContext(ctx, sqlStatement)
-			if err != nil {
-				return err
-			}
-			_, createErr := preppedStatement.Exec()
-			if createErr != nil {
-				return createErr
+// FinalizeApp drops the login role that was created for the app. If the role owns the database,
+// ownership moves to another app's role using it, so that the apps sharing the database keep
+// their access.
+func (db *sharedDbProvider) FinalizeApp(app *crd.ClowdApp) error {
+	if app.Spec.Database.Name == "" && app.Spec.Database.SharedDBAppName == "" {
+		return nil
+	}
+
+	// Apps using sharedDbAppName have the connection details of the database in their own
+	// secret, as the app they share with may already be gone.
+	var vSec *core.Secret
+	if app.Spec.Database.SharedDBAppName == "" {
+		var err error
+		vSec, err = db.getVersionedSecret(app)
+		if err != nil {
+			if k8serr.IsNotFound(err) {
+				return nil
 			}
-		} else {
-			return pErr
+			return err
 		}
 	}
 
+	secret := &core.Secret{}
 	nn := types.NamespacedName{
 		Name:      fmt.Sprintf("%v-db", app.Name),
 		Namespace: app.Namespace,
 	}
 
-	secret := &core.Secret{}
-	if err := db.Cache.Create(SharedDBAppSecret, nn, secret); err != nil {
+	if err := db.Client.Get(db.Ctx, nn, secret); err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil
+		}
 		return err
 	}
 
-	secret.StringData = map[string]string{
-		"hostname": host,
-		"port":     "5432",
-		"username": dbCfg.Username,
-		"password": dbCfg.Password,
-		"pgPass":   dbCfg.AdminPassword,
-		"name":     app.Spec.Database.Name,
+	role := string(secret.Data["username"])
+	if role == "" {
+		return nil
 	}
 
-	secret.Name = nn.Name
-	secret.Namespace = nn.Namespace
-	secret.OwnerReferences = []metav1.OwnerReference{app.MakeOwnerReference()}
-	secret.Type = core.SecretTypeOpaque
+	dbCfg := config.DatabaseConfig{
+		AdminUsername: "postgres",
+		Name:          string(secret.Data["name"]),
+		Username:      role,
+	}
 
-	if err := db.Cache.Update(SharedDBAppSecret, secret); err != nil {
+	connection := secret
+	if vSec != nil {
+		if role == string(vSec.Data["username"]) {
+			return nil
+		}
+		connection = vSec
+		dbCfg.Name = app.Spec.Database.Name
+	}
+
+	dbCfg.AdminPassword = string(connection.Data["pgPass"])
+	dbCfg.Hostname = string(connection.Data["hostname"])
+	port, err := strconv.Atoi(string(connection.Data["port"]))
+	if err != nil {
 		return err
 	}
+	dbCfg.Port = port
 
-	dbCfg.Name = app.Spec.Database.Name
-	db.Config.Database = &dbCfg
+	// The app must still be deletable once the shared db is gone, so the role is left behind
+	// rather than holding the finalizer when the server cannot be reached.
+	if dbCfg.Hostname == "" {
+		db.Log.Info("Skipping removal of app db role, the shared db has no hostname", "role", role)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(db.Ctx, 5*time.Second)
+	defer cancel()
+
+	if err := dropSharedDBRole(ctx, &dbCfg, db.Env.Name); err != nil {
+		var netErr net.Error
+		if errlib.As(err, &netErr) {
+			db.Log.Info("Skipping removal of app db role, the shared db cannot be reached", "role", role, "err", err)
+			return nil
+		}
+		return errors.Wrap("couldn't remove app db role", err)
+	}
+
+	return nil
+}
+
+func (db *sharedDbProvider) getVersionedSecret(app *crd.ClowdApp) (*core.Secret, error) {
+	version := int32(12)
+	if app.Spec.Database.Version != nil {
+		version = *app.Spec.Database.Version
+	}
+
+	vSec := &core.Secret{}
+	vSecnn := types.NamespacedName{
+		Name:      fmt.Sprintf("%s-db-v%s", db.Env.Name, strconv.Itoa(int(version))),
+		Namespace: db.Env.Status.TargetNamespace,
+	}
+
+	if err := db.Client.Get(db.Ctx, vSecnn, vSec); err != nil {
+		return nil, err
+	}
+	return vSec, nil
+}
+
+// openSharedDB connects to a database of the shared db as the admin user, it is replaced in tests.
+var openSharedDB = func(dbCfg *config.DatabaseConfig, dbname string) (*sql.DB, error) {
+	connectionString := fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+		dbCfg.Hostname, dbCfg.Port, dbCfg.AdminUsername, dbCfg.AdminPassword, dbname,
+	)
+	return sql.Open("postgres", connectionString)
+}
+
+// ensureSharedDBRole makes sure the app's login role exists with the current password and can use
+// the app's database. The first role to use a database creates and owns it, roles of other apps
+// sharing it are made members of the owner, so ownership does not move between them. A database
+// still owned by the env-wide legacyOwner, or the admin user, is taken over along with its contents.
+// CONNECT is revoked from PUBLIC so that roles belonging to other apps on the same instance cannot
+// reach the database.
+func ensureSharedDBRole(ctx context.Context, dbCfg *config.DatabaseConfig, envDBName string, legacyOwner string) error {
+	envDbClient, err := openSharedDB(dbCfg, envDBName)
+	if err != nil {
+		return err
+	}
+
+	defer envDbClient.Close() // nolint:errcheck  // no need to check error return value
 
+	role := pq.QuoteIdentifier(dbCfg.Username)
+	dbname := pq.QuoteIdentifier(dbCfg.Name)
+
+	var roleExists bool
+	if err := envDbClient.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)", dbCfg.Username).Scan(&roleExists); err != nil {
+		return err
+	}
+
+	roleStatement := "CREATE ROLE %s WITH LOGIN PASSWORD %s"
+	if roleExists {
+		roleStatement = "ALTER ROLE %s WITH LOGIN PASSWORD %s"
+	}
+	if _, err := envDbClient.ExecContext(ctx, fmt.Sprintf(roleStatement, role, pq.QuoteLiteral(dbCfg.Password))); err != nil {
+		return err
+	}
+
+	var owner string
+	err = envDbClient.QueryRowContext(ctx, "SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1", dbCfg.Name).Scan(&owner)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := envDbClient.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s WITH OWNER=%s", dbname, role)); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case owner == dbCfg.Username:
+	case owner == legacyOwner || owner == dbCfg.AdminUsername:
+		if _, err := envDbClient.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", dbname, role)); err != nil {
+			return err
+		}
+		if err := adoptSharedDBObjects(ctx, dbCfg, owner); err != nil {
+			return err
+		}
+	default:
+		if _, err := envDbClient.ExecContext(ctx, fmt.Sprintf("GRANT %s TO %s", pq.QuoteIdentifier(owner), role)); err != nil {
+			return err
+		}
+	}
+
+	_, err = envDbClient.ExecContext(ctx, fmt.Sprintf("REVOKE ALL ON DATABASE %s FROM PUBLIC", dbname))
+	return err
+}
+
+// adoptSharedDBObjects hands the schemas and relations in the app's database that belong to the
+// previous owner over to the app's role. Sequences backing a column move along with their table.
+func adoptSharedDBObjects(ctx context.Context, dbCfg *config.DatabaseConfig, previousOwner string) error {
+	appDbClient, err := openSharedDB(dbCfg, dbCfg.Name)
+	if err != nil {
+		return err
+	}
+
+	defer appDbClient.Close() // nolint:errcheck  // no need to check error return value
+
+	rows, err := appDbClient.QueryContext(ctx, `
+		SELECT format('ALTER SCHEMA %I OWNER TO %I', n.nspname, $2::text)
+		FROM pg_namespace n
+		WHERE n.nspowner = (SELECT oid FROM pg_roles WHERE rolname = $1)
+		UNION ALL
+		SELECT format('ALTER TABLE %I.%I OWNER TO %I', n.nspname, c.relname, $2::text)
+		FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relowner = (SELECT oid FROM pg_roles WHERE rolname = $1)
+		AND c.relkind IN ('r', 'p', 'v', 'm', 'f', 'S')
+		AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		AND NOT EXISTS (
+			SELECT 1 FROM pg_depend d
+			WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype IN ('a', 'i')
+		)`, previousOwner, dbCfg.Username)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close() // nolint:errcheck  // no need to check error return value
+
+	statements := []string{}
+	for rows.Next() {
+		var statement string
+		if err := rows.Scan(&statement); err != nil {
+			return err
+		}
+		statements = append(statements, statement)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if _, err := appDbClient.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// dropSharedDBRole removes the app's login role, as postgres refuses to drop a role that still
+// owns objects, those are reassigned first. If the role owns the app's database, the database and
+// its contents move to the earliest created of the roles sharing it, which the others are made
+// members of. Otherwise they go to the database's owner, or to the admin user when no other role
+// uses the database.
+func dropSharedDBRole(ctx context.Context, dbCfg *config.DatabaseConfig, envDBName string) error {
+	role := pq.QuoteIdentifier(dbCfg.Username)
+
+	appDbClient, err := openSharedDB(dbCfg, dbCfg.Name)
+	if err != nil {
+		return err
+	}
+
+	defer appDbClient.Close() // nolint:errcheck  // no need to check error return value
+
+	dbExists := true
+	if pErr := appDbClient.PingContext(ctx); pErr != nil {
+		if !strings.Contains(pErr.Error(), fmt.Sprintf("database \"%s\" does not exist", dbCfg.Name)) {
+			return pErr
+		}
+		dbExists = false
+	}
+
+	envDbClient, err := openSharedDB(dbCfg, envDBName)
+	if err != nil {
+		return err
+	}
+
+	defer envDbClient.Close() // nolint:errcheck  // no need to check error return value
+
+	if dbExists {
+		heir, err := sharedDBHeir(ctx, envDbClient, dbCfg)
+		if err != nil {
+			return err
+		}
+
+		if _, err := appDbClient.ExecContext(ctx, fmt.Sprintf("REASSIGN OWNED BY %s TO %s", role, pq.QuoteIdentifier(heir))); err != nil {
+			return err
+		}
+		if _, err := appDbClient.ExecContext(ctx, fmt.Sprintf("DROP OWNED BY %s", role)); err != nil {
+			return err
+		}
+	}
+
+	_, err = envDbClient.ExecContext(ctx, fmt.Sprintf("DROP ROLE IF EXISTS %s", role))
+	return err
+}
+
+// sharedDBHeir returns the role that takes over what the app's role owns in the app's database. If
+// the app's role owns the database, ownership moves to the earliest created role sharing it, and
+// the other roles sharing it are made members of that one so that none of them lose access.
+func sharedDBHeir(ctx context.Context, envDbClient *sql.DB, dbCfg *config.DatabaseConfig) (string, error) {
+	var owner string
+	if err := envDbClient.QueryRowContext(ctx, "SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1", dbCfg.Name).Scan(&owner); err != nil {
+		return "", err
+	}
+
+	if owner != dbCfg.Username {
+		return owner, nil
+	}
+
+	rows, err := envDbClient.QueryContext(ctx, `
+		SELECT r.rolname FROM pg_auth_members m JOIN pg_roles r ON r.oid = m.member
+		WHERE m.roleid = (SELECT oid FROM pg_roles WHERE rolname = $1)
+		ORDER BY r.oid`, dbCfg.Username)
+	if err != nil {
+		return "", err
+	}
+
+	defer rows.Close() // nolint:errcheck  // no need to check error return value
+
+	members := []string{}
+	for rows.Next() {
+		var member string
+		if err := rows.Scan(&member); err != nil {
+			return "", err
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if len(members) == 0 {
+		return dbCfg.AdminUsername, nil
+	}
+
+	heir := pq.QuoteIdentifier(members[0])
+	if _, err := envDbClient.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE %s OWNER TO %s", pq.QuoteIdentifier(dbCfg.Name), heir)); err != nil {
+		return "", err
+	}
+	for _, member := range members[1:] {
+		if _, err := envDbClient.ExecContext(ctx, fmt.Sprintf("GRANT %s TO %s", heir, pq.QuoteIdentifier(member))); err != nil {
+			return "", err
+		}
+	}
+
+	return members[0], nil
+}
+
 func (db *sharedDbProvider) processSharedDB(app *crd.ClowdApp) error {
 	err := checkDependency(app)
 
@@ -367,6 +658,45 @@ func (db *sharedDbProvider) processSharedDB(app *crd.ClowdApp) error {
 	}
 	dbCfg.AdminUsername = "postgres"
 
+	// The app gets a login role of its own which is made a member of the database's owner, so
+	// that it keeps its access when the app it shares with is deleted.
+	vSec, err := db.getVersionedSecret(app)
+	if err != nil {
+		return err
+	}
+
+	appSecret := &core.Secret{}
+	nn := types.NamespacedName{
+		Name:      fmt.Sprintf("%v-db", app.Name),
+		Namespace: app.Namespace,
+	}
+	if err := db.Cache.Create(SharedDBAppSecret, nn, appSecret); err != nil {
+		return err
+	}
+
+	dbCfg.Username = string(appSecret.Data["username"])
+	dbCfg.Password = string(appSecret.Data["password"])
+	if dbCfg.Username == "" || dbCfg.Username == secMap["username"] || dbCfg.Username == string(vSec.Data["username"]) {
+		dbCfg.Username = utils.RandString(16)
+		dbCfg.Password, err = utils.RandPassword(16, provutils.RCharSet)
+		if err != nil {
+			return errors.Wrap("password generate failed", err)
+		}
+	}
+
+	if !db.Offline {
+		ctx, cancel := context.WithTimeout(db.Ctx, 5*time.Second)
+		defer cancel()
+
+		if err := ensureSharedDBRole(ctx, &dbCfg, db.Env.Name, string(vSec.Data["username"])); err != nil {
+			return errors.Wrap("couldn't provision app db role", err)
+		}
+	}
+
+	if err := db.updateAppSecret(app, appSecret, &dbCfg); err != nil {
+		return err
+	}
+
 	db.Config.Database = &dbCfg
 
 	return nil
@@ -0,0 +1,355 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	p "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+)
+
+// mockSharedDB replaces openSharedDB with one returning a mock for each database name.
+func mockSharedDB(t *testing.T, dbnames ...string) map[string]sqlmock.Sqlmock {
+	t.Helper()
+
+	dbs := map[string]*sql.DB{}
+	mocks := map[string]sqlmock.Sqlmock{}
+	for _, name := range dbnames {
+		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(containsQueryMatcher), sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		dbs[name] = db
+		mocks[name] = mock
+	}
+
+	original := openSharedDB
+	openSharedDB = func(_ *config.DatabaseConfig, dbname string) (*sql.DB, error) {
+		db, ok := dbs[dbname]
+		if !ok {
+			t.Fatalf("unexpected connection to database %s", dbname)
+		}
+		return db, nil
+	}
+	t.Cleanup(func() {
+		openSharedDB = original
+		for name, mock := range mocks {
+			assert.NoError(t, mock.ExpectationsWereMet(), "database %s", name)
+		}
+	})
+
+	return mocks
+}
+
+// containsQueryMatcher matches statements containing the expected SQL, so that long queries can
+// be expected by a distinctive part of them.
+var containsQueryMatcher = sqlmock.QueryMatcherFunc(func(expectedSQL, actualSQL string) error {
+	if !strings.Contains(actualSQL, expectedSQL) {
+		return fmt.Errorf("%q does not contain %q", actualSQL, expectedSQL)
+	}
+	return nil
+})
+
+func sharedDBConfig() *config.DatabaseConfig {
+	return &config.DatabaseConfig{
+		AdminUsername: "postgres",
+		AdminPassword: "admin",
+		Hostname:      "env-db-v12.env.svc",
+		Port:          5432,
+		Name:          "app-db",
+		Username:      "approle",
+		Password:      "secret",
+	}
+}
+
+func TestEnsureSharedDBRoleCreates(t *testing.T) {
+	mocks := mockSharedDB(t, "env")
+	env := mocks["env"]
+
+	env.ExpectQuery("SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)").
+		WithArgs("approle").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	env.ExpectExec(`CREATE ROLE "approle" WITH LOGIN PASSWORD 'secret'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectQuery("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1").
+		WithArgs("app-db").
+		WillReturnError(sql.ErrNoRows)
+	env.ExpectExec(`CREATE DATABASE "app-db" WITH OWNER="approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectExec(`REVOKE ALL ON DATABASE "app-db" FROM PUBLIC`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, ensureSharedDBRole(context.Background(), sharedDBConfig(), "env", "envuser"))
+}
+
+func TestEnsureSharedDBRoleAdopts(t *testing.T) {
+	mocks := mockSharedDB(t, "env", "app-db")
+	env, app := mocks["env"], mocks["app-db"]
+
+	env.ExpectQuery("SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)").
+		WithArgs("approle").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	env.ExpectExec(`ALTER ROLE "approle" WITH LOGIN PASSWORD 'secret'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectQuery("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1").
+		WithArgs("app-db").
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("envuser"))
+	env.ExpectExec(`ALTER DATABASE "app-db" OWNER TO "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	app.ExpectQuery("FROM pg_namespace n").WithArgs("envuser", "approle").
+		WillReturnRows(sqlmock.NewRows([]string{"statement"}).
+			AddRow(`ALTER SCHEMA app OWNER TO approle`).
+			AddRow(`ALTER TABLE app.items OWNER TO approle`))
+	app.ExpectExec(`ALTER SCHEMA app OWNER TO approle`).WillReturnResult(sqlmock.NewResult(0, 0))
+	app.ExpectExec(`ALTER TABLE app.items OWNER TO approle`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	env.ExpectExec(`REVOKE ALL ON DATABASE "app-db" FROM PUBLIC`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, ensureSharedDBRole(context.Background(), sharedDBConfig(), "env", "envuser"))
+}
+
+func TestEnsureSharedDBRoleJoinsOwner(t *testing.T) {
+	mocks := mockSharedDB(t, "env")
+	env := mocks["env"]
+
+	env.ExpectQuery("SELECT EXISTS(SELECT 1 FROM pg_roles WHERE rolname = $1)").
+		WithArgs("approle").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	env.ExpectExec(`ALTER ROLE "approle" WITH LOGIN PASSWORD 'secret'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectQuery("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1").
+		WithArgs("app-db").
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("otherrole"))
+	// The database stays with the role of the app that created it.
+	env.ExpectExec(`GRANT "otherrole" TO "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectExec(`REVOKE ALL ON DATABASE "app-db" FROM PUBLIC`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, ensureSharedDBRole(context.Background(), sharedDBConfig(), "env", "envuser"))
+}
+
+func TestDropSharedDBRole(t *testing.T) {
+	mocks := mockSharedDB(t, "env", "app-db")
+	env, app := mocks["env"], mocks["app-db"]
+
+	app.ExpectPing()
+	env.ExpectQuery("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1").
+		WithArgs("app-db").
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("approle"))
+	env.ExpectQuery("FROM pg_auth_members m").WithArgs("approle").
+		WillReturnRows(sqlmock.NewRows([]string{"rolname"}))
+	app.ExpectExec(`REASSIGN OWNED BY "approle" TO "postgres"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	app.ExpectExec(`DROP OWNED BY "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectExec(`DROP ROLE IF EXISTS "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, dropSharedDBRole(context.Background(), sharedDBConfig(), "env"))
+}
+
+func TestDropSharedDBRoleMovesOwnership(t *testing.T) {
+	mocks := mockSharedDB(t, "env", "app-db")
+	env, app := mocks["env"], mocks["app-db"]
+
+	app.ExpectPing()
+	env.ExpectQuery("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1").
+		WithArgs("app-db").
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("approle"))
+	env.ExpectQuery("FROM pg_auth_members m").WithArgs("approle").
+		WillReturnRows(sqlmock.NewRows([]string{"rolname"}).AddRow("firstrole").AddRow("secondrole"))
+	env.ExpectExec(`ALTER DATABASE "app-db" OWNER TO "firstrole"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectExec(`GRANT "firstrole" TO "secondrole"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	app.ExpectExec(`REASSIGN OWNED BY "approle" TO "firstrole"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	app.ExpectExec(`DROP OWNED BY "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectExec(`DROP ROLE IF EXISTS "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, dropSharedDBRole(context.Background(), sharedDBConfig(), "env"))
+}
+
+func TestDropSharedDBRoleMember(t *testing.T) {
+	mocks := mockSharedDB(t, "env", "app-db")
+	env, app := mocks["env"], mocks["app-db"]
+
+	app.ExpectPing()
+	env.ExpectQuery("SELECT pg_get_userbyid(datdba) FROM pg_database WHERE datname = $1").
+		WithArgs("app-db").
+		WillReturnRows(sqlmock.NewRows([]string{"owner"}).AddRow("ownerrole"))
+	app.ExpectExec(`REASSIGN OWNED BY "approle" TO "ownerrole"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	app.ExpectExec(`DROP OWNED BY "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	env.ExpectExec(`DROP ROLE IF EXISTS "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, dropSharedDBRole(context.Background(), sharedDBConfig(), "env"))
+}
+
+func TestDropSharedDBRoleWithoutDatabase(t *testing.T) {
+	mocks := mockSharedDB(t, "env", "app-db")
+	env, app := mocks["env"], mocks["app-db"]
+
+	app.ExpectPing().WillReturnError(errors.New(`pq: database "app-db" does not exist`))
+	env.ExpectExec(`DROP ROLE IF EXISTS "approle"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, dropSharedDBRole(context.Background(), sharedDBConfig(), "env"))
+}
+
+func TestSharedDBFinalizeAppUnreachable(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{
+		ObjectMeta: metav1.ObjectMeta{Name: "env"},
+		Status:     crd.ClowdEnvironmentStatus{TargetNamespace: "env-ns"},
+	}
+	app := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "app-ns"},
+		Spec:       crd.ClowdAppSpec{Database: crd.DatabaseSpec{Name: "app-db"}},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&core.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "env-db-v12", Namespace: "env-ns"},
+			Data: map[string][]byte{
+				"hostname": []byte("env-db-v12.env.svc"),
+				"port":     []byte("5432"),
+				"username": []byte("envuser"),
+				"pgPass":   []byte("admin"),
+			},
+		},
+		&core.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-db", Namespace: "app-ns"},
+			Data:       map[string][]byte{"username": []byte("approle")},
+		},
+	).Build()
+
+	db := &sharedDbProvider{Provider: p.Provider{Ctx: context.Background(), Client: c, Env: env}}
+
+	mocks := mockSharedDB(t, "app-db")
+	mocks["app-db"].ExpectPing().WillReturnError(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("no such host")})
+
+	assert.NoError(t, db.FinalizeApp(app), "an unreachable db must not block the app's deletion")
+
+	mocks = mockSharedDB(t, "app-db")
+	mocks["app-db"].ExpectPing().WillReturnError(errors.New("pq: permission denied"))
+	assert.Error(t, db.FinalizeApp(app))
+}
+
+func TestSharedDBProvideOffline(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{
+		ObjectMeta: metav1.ObjectMeta{Name: "env"},
+		Status:     crd.ClowdEnvironmentStatus{TargetNamespace: "env-ns"},
+	}
+	app := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "app-ns"},
+		Spec:       crd.ClowdAppSpec{Database: crd.DatabaseSpec{Name: "app-db"}},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "env-db-v12", Namespace: "env-ns"},
+		Data: map[string][]byte{
+			"hostname": []byte("env-db-v12.env.svc"),
+			"port":     []byte("5432"),
+			"username": []byte("envuser"),
+			"pgPass":   []byte("admin"),
+		},
+	}).Build()
+
+	log := logr.Discard()
+	cache := rc.NewObjectCache(context.TODO(), c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+
+	db := &sharedDbProvider{Provider: p.Provider{
+		Ctx:     context.Background(),
+		Client:  c,
+		Cache:   &cache,
+		Env:     env,
+		Config:  &config.AppConfig{},
+		Offline: true,
+	}}
+
+	// No databases are mocked, any connection fails the test.
+	mockSharedDB(t)
+
+	require.NoError(t, db.Provide(app))
+	assert.Equal(t, "app-db", db.Config.Database.Name)
+	assert.NotEmpty(t, db.Config.Database.Username)
+}
+
+func TestSharedDBProvideDependentOffline(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{
+		ObjectMeta: metav1.ObjectMeta{Name: "env"},
+		Status:     crd.ClowdEnvironmentStatus{TargetNamespace: "env-ns"},
+	}
+	owner := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "app-ns"},
+		Spec:       crd.ClowdAppSpec{EnvName: "env", Database: crd.DatabaseSpec{Name: "app-db"}},
+	}
+	app := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "app-ns"},
+		Spec: crd.ClowdAppSpec{
+			EnvName:      "env",
+			Dependencies: []string{"owner"},
+			Database:     crd.DatabaseSpec{SharedDBAppName: "owner"},
+		},
+	}
+
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithIndex(&crd.ClowdApp{}, "spec.envName", func(o client.Object) []string {
+			return []string{o.(*crd.ClowdApp).Spec.EnvName}
+		}).
+		WithObjects(
+			owner,
+			&core.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "env-db-v12", Namespace: "env-ns"},
+				Data: map[string][]byte{
+					"hostname": []byte("env-db-v12.env.svc"),
+					"port":     []byte("5432"),
+					"username": []byte("envuser"),
+					"pgPass":   []byte("admin"),
+				},
+			},
+			&core.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "owner-db", Namespace: "app-ns"},
+				Data: map[string][]byte{
+					"hostname": []byte("env-db-v12.env.svc"),
+					"port":     []byte("5432"),
+					"username": []byte("ownerrole"),
+					"password": []byte("ownerpass"),
+					"pgPass":   []byte("admin"),
+					"name":     []byte("app-db"),
+				},
+			},
+		).Build()
+
+	log := logr.Discard()
+	cache := rc.NewObjectCache(context.TODO(), c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+
+	db := &sharedDbProvider{Provider: p.Provider{
+		Ctx:     context.Background(),
+		Client:  c,
+		Cache:   &cache,
+		Env:     env,
+		Config:  &config.AppConfig{},
+		Offline: true,
+	}}
+
+	mockSharedDB(t)
+
+	require.NoError(t, db.Provide(app))
+	assert.Equal(t, "app-db", db.Config.Database.Name)
+	assert.NotEmpty(t, db.Config.Database.Username)
+	assert.NotEqual(t, "ownerrole", db.Config.Database.Username, "the app must not share the owner's role")
+}
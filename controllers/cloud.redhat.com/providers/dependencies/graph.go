@@ -0,0 +1,173 @@
+package dependencies
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+// GraphReport holds the problems found in the dependency graph of every ClowdApp in an
+// environment. Only required dependencies are considered, a missing optional dependency is
+// expected and an optional edge can never hold up an app.
+type GraphReport struct {
+	// Cycles holds each set of apps that depend on each other in a loop, sorted by name.
+	Cycles [][]string
+
+	// Unknown maps an app name to the dependencies it names that match no ClowdApp or
+	// ClowdAppRef in the environment.
+	Unknown map[string][]string
+}
+
+// AnalyzeGraph builds the dependency graph for the given apps and app refs and reports any
+// cycles and dependencies on apps that do not exist.
+func AnalyzeGraph(apps []crd.ClowdApp, appRefs []crd.ClowdAppRef) GraphReport {
+	report := GraphReport{Unknown: map[string][]string{}}
+
+	known := map[string]bool{}
+	for _, appRef := range appRefs {
+		known[appRef.Name] = true
+	}
+
+	edges := map[string][]string{}
+	for _, app := range apps {
+		known[app.Name] = true
+		edges[app.Name] = append(edges[app.Name], app.Spec.Dependencies...)
+	}
+
+	for name, deps := range edges {
+		for _, dep := range deps {
+			if !known[dep] {
+				report.Unknown[name] = append(report.Unknown[name], dep)
+			}
+		}
+		sort.Strings(report.Unknown[name])
+	}
+
+	report.Cycles = findCycles(edges)
+
+	return report
+}
+
+// findCycles returns the strongly connected components of the graph that contain a loop, found
+// using Tarjan's algorithm.
+func findCycles(edges map[string][]string) [][]string {
+	nodes := []string{}
+	for name := range edges {
+		nodes = append(nodes, name)
+	}
+	sort.Strings(nodes)
+
+	index := 0
+	indices := map[string]int{}
+	lowLink := map[string]int{}
+	onStack := map[string]bool{}
+	stack := []string{}
+	cycles := [][]string{}
+
+	var connect func(node string)
+	connect = func(node string) {
+		indices[node] = index
+		lowLink[node] = index
+		index++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		selfLoop := false
+		for _, dep := range edges[node] {
+			if dep == node {
+				selfLoop = true
+			}
+			if _, ok := edges[dep]; !ok {
+				continue
+			}
+			if _, visited := indices[dep]; !visited {
+				connect(dep)
+				lowLink[node] = min(lowLink[node], lowLink[dep])
+			} else if onStack[dep] {
+				lowLink[node] = min(lowLink[node], indices[dep])
+			}
+		}
+
+		if lowLink[node] != indices[node] {
+			return
+		}
+
+		component := []string{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+
+		if len(component) > 1 || selfLoop {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, visited := indices[node]; !visited {
+			connect(node)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+
+	return cycles
+}
+
+// OK returns true if no problems were found in the graph.
+func (g GraphReport) OK() bool {
+	return len(g.Cycles) == 0 && len(g.Unknown) == 0
+}
+
+// CycleFor returns the cycle the named app is part of, or nil if it is not part of one.
+func (g GraphReport) CycleFor(appName string) []string {
+	for _, cycle := range g.Cycles {
+		for _, name := range cycle {
+			if name == appName {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// AppMessage describes the problems that affect the named app, it is empty if there are none.
+func (g GraphReport) AppMessage(appName string) string {
+	msgs := []string{}
+	if cycle := g.CycleFor(appName); cycle != nil {
+		msgs = append(msgs, fmt.Sprintf("dependency cycle between [%s]", strings.Join(cycle, ", ")))
+	}
+	if unknown := g.Unknown[appName]; len(unknown) > 0 {
+		msgs = append(msgs, fmt.Sprintf("unknown dependencies [%s]", strings.Join(unknown, ", ")))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// String describes every problem in the graph.
+func (g GraphReport) String() string {
+	msgs := []string{}
+	for _, cycle := range g.Cycles {
+		msgs = append(msgs, fmt.Sprintf("dependency cycle between [%s]", strings.Join(cycle, ", ")))
+	}
+
+	names := []string{}
+	for name := range g.Unknown {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s has unknown dependencies [%s]", name, strings.Join(g.Unknown[name], ", ")))
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,81 @@
+package dependencies
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+func graphApp(name string, deps []string, optionalDeps ...string) crd.ClowdApp {
+	return crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: crd.ClowdAppSpec{
+			Dependencies:         deps,
+			OptionalDependencies: optionalDeps,
+		},
+	}
+}
+
+func TestAnalyzeGraph_NoProblems(t *testing.T) {
+	apps := []crd.ClowdApp{
+		graphApp("inventory", []string{"rbac"}),
+		graphApp("rbac", nil),
+		graphApp("puptoo", []string{"inventory", "ingress"}, "missing"),
+	}
+	appRefs := []crd.ClowdAppRef{{
+		ObjectMeta: metav1.ObjectMeta{Name: "ingress"},
+	}}
+
+	report := AnalyzeGraph(apps, appRefs)
+
+	assert.True(t, report.OK())
+	assert.Empty(t, report.AppMessage("puptoo"))
+}
+
+func TestAnalyzeGraph_Cycle(t *testing.T) {
+	apps := []crd.ClowdApp{
+		graphApp("a", []string{"b"}),
+		graphApp("b", []string{"c"}),
+		graphApp("c", []string{"a"}),
+		graphApp("d", []string{"a"}),
+		graphApp("e", []string{"e"}),
+	}
+
+	report := AnalyzeGraph(apps, nil)
+
+	assert.False(t, report.OK())
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"e"}}, report.Cycles)
+	assert.Nil(t, report.CycleFor("d"), "depending on a cycle is not being part of one")
+	assert.Equal(t, "dependency cycle between [a, b, c]", report.AppMessage("b"))
+	assert.Equal(t, "dependency cycle between [a, b, c]; dependency cycle between [e]", report.String())
+}
+
+func TestAnalyzeGraph_OptionalDependenciesDoNotFormCycles(t *testing.T) {
+	apps := []crd.ClowdApp{
+		graphApp("a", []string{"b"}),
+		graphApp("b", nil, "a"),
+	}
+
+	report := AnalyzeGraph(apps, nil)
+
+	assert.True(t, report.OK())
+}
+
+func TestAnalyzeGraph_Unknown(t *testing.T) {
+	apps := []crd.ClowdApp{
+		graphApp("a", []string{"zeta", "b", "alpha"}),
+		graphApp("b", []string{"a"}),
+	}
+
+	report := AnalyzeGraph(apps, nil)
+
+	assert.Equal(t, map[string][]string{"a": {"alpha", "zeta"}}, report.Unknown)
+	assert.Equal(t, "dependency cycle between [a, b]; unknown dependencies [alpha, zeta]", report.AppMessage("a"))
+	assert.Equal(t, "dependency cycle between [a, b]; a has unknown dependencies [alpha, zeta]", report.String())
+}
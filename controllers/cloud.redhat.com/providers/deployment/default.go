@@ -4,6 +4,8 @@ package deployment
 import (
 	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
 	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1"
 
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
@@ -16,9 +18,23 @@ type deploymentProvider struct {
 // CoreDeployment is the deployment for the apps deployments.
 var CoreDeployment = rc.NewMultiResourceIdent(ProvName, "core_deployment", &apps.Deployment{})
 
+// CoreStatefulSet is the stateful set for app deployments of kind StatefulSet.
+var CoreStatefulSet = rc.NewMultiResourceIdent(ProvName, "core_statefulset", &apps.StatefulSet{})
+
+// CoreHeadlessService is the headless service that gives StatefulSet pods stable DNS names.
+var CoreHeadlessService = rc.NewMultiResourceIdent(ProvName, "core_headless_service", &core.Service{})
+
+// CorePodDisruptionBudget is the disruption budget for app deployments that request one.
+var CorePodDisruptionBudget = rc.NewMultiResourceIdent(ProvName, "core_pod_disruption_budget", &policy.PodDisruptionBudget{})
+
 // NewDeploymentProvider creates a new deployment provider instance
 func NewDeploymentProvider(p *providers.Provider) (providers.ClowderProvider, error) {
-	p.Cache.AddPossibleGVKFromIdent(CoreDeployment)
+	p.Cache.AddPossibleGVKFromIdent(
+		CoreDeployment,
+		CoreStatefulSet,
+		CoreHeadlessService,
+		CorePodDisruptionBudget,
+	)
 	return &deploymentProvider{Provider: *p}, nil
 }
 
@@ -30,7 +46,14 @@ func (dp *deploymentProvider) Provide(app *crd.ClowdApp) error {
 
 	for i := range app.Spec.Deployments {
 		deployment := &app.Spec.Deployments[i]
-		if err := dp.makeDeployment(deployment, app); err != nil {
+		if deployment.IsStatefulSet() {
+			if err := dp.makeStatefulSet(deployment, app); err != nil {
+				return err
+			}
+		} else if err := dp.makeDeployment(deployment, app); err != nil {
+			return err
+		}
+		if err := dp.makeDisruptionBudget(deployment, app); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,60 @@
+package deployment
+
+import (
+	policy "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+// getDisruptionBudget returns the budget that applies to the deployment, or nil if it should
+// not have a PodDisruptionBudget. The environment default only applies to deployments with
+// more than one replica, as a budget on a single replica would block node drains entirely.
+func getDisruptionBudget(env *crd.ClowdEnvironment, deployment *crd.Deployment) *crd.DisruptionBudget {
+	if deployment.DisruptionBudget != nil {
+		if deployment.DisruptionBudget.IsEmpty() {
+			return nil
+		}
+		return deployment.DisruptionBudget
+	}
+
+	envBudget := env.Spec.Providers.Deployment.DefaultDisruptionBudget
+	if envBudget == nil || envBudget.IsEmpty() {
+		return nil
+	}
+
+	if *deployment.GetReplicaCount() <= 1 {
+		return nil
+	}
+
+	return envBudget
+}
+
+func (dp *deploymentProvider) makeDisruptionBudget(deployment *crd.Deployment, app *crd.ClowdApp) error {
+	budget := getDisruptionBudget(dp.Env, deployment)
+	if budget == nil {
+		return nil
+	}
+
+	pdb := &policy.PodDisruptionBudget{}
+	nn := app.GetDeploymentNamespacedName(deployment)
+
+	if err := dp.Cache.Create(CorePodDisruptionBudget, nn, pdb); err != nil {
+		return err
+	}
+
+	initDisruptionBudget(app, pdb, nn, budget)
+
+	return dp.Cache.Update(CorePodDisruptionBudget, pdb)
+}
+
+func initDisruptionBudget(app *crd.ClowdApp, pdb *policy.PodDisruptionBudget, nn types.NamespacedName, budget *crd.DisruptionBudget) {
+	labels := app.GetLabels()
+	labels["pod"] = nn.Name
+	app.SetObjectMeta(pdb, crd.Name(nn.Name), crd.Labels(labels))
+
+	pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"pod": nn.Name}}
+	pdb.Spec.MinAvailable = budget.MinAvailable
+	pdb.Spec.MaxUnavailable = budget.MaxUnavailable
+}
@@ -0,0 +1,54 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+func TestGetDisruptionBudget(t *testing.T) {
+	envDefault := &crd.DisruptionBudget{MaxUnavailable: ptr.To(intstr.FromInt32(1))}
+	ownBudget := &crd.DisruptionBudget{MinAvailable: ptr.To(intstr.FromString("50%"))}
+
+	env := &crd.ClowdEnvironment{}
+	env.Spec.Providers.Deployment.DefaultDisruptionBudget = envDefault
+
+	tests := []struct {
+		name       string
+		deployment crd.Deployment
+		want       *crd.DisruptionBudget
+	}{
+		{
+			name:       "single replica skips the default",
+			deployment: crd.Deployment{},
+			want:       nil,
+		},
+		{
+			name:       "multiple replicas get the default",
+			deployment: crd.Deployment{Replicas: ptr.To(int32(3))},
+			want:       envDefault,
+		},
+		{
+			name:       "own budget wins",
+			deployment: crd.Deployment{DisruptionBudget: ownBudget},
+			want:       ownBudget,
+		},
+		{
+			name:       "empty budget opts out",
+			deployment: crd.Deployment{Replicas: ptr.To(int32(3)), DisruptionBudget: &crd.DisruptionBudget{}},
+			want:       nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getDisruptionBudget(env, &tt.deployment))
+		})
+	}
+
+	assert.Nil(t, getDisruptionBudget(&crd.ClowdEnvironment{}, &crd.Deployment{Replicas: ptr.To(int32(3))}))
+}
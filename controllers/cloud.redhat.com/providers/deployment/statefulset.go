@@ -0,0 +1,101 @@
+package deployment
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func (dp *deploymentProvider) makeStatefulSet(deployment *crd.Deployment, app *crd.ClowdApp) error {
+
+	s := &apps.StatefulSet{}
+	nn := app.GetDeploymentNamespacedName(deployment)
+
+	if err := dp.Cache.Create(CoreStatefulSet, nn, s); err != nil {
+		return err
+	}
+
+	// The pod template is built exactly as it would be for a Deployment, so the env vars,
+	// cdappconfig mount, probes and init containers all match.
+	d := &apps.Deployment{}
+	d.Spec.Replicas = s.Spec.Replicas
+	d.Spec.Template = s.Spec.Template
+	if err := initDeployment(app, dp.Env, d, nn, deployment); err != nil {
+		return err
+	}
+
+	initStatefulSet(app, s, d, nn, deployment)
+
+	if err := dp.makeHeadlessService(app, nn); err != nil {
+		return err
+	}
+
+	return dp.Cache.Update(CoreStatefulSet, s)
+}
+
+func initStatefulSet(app *crd.ClowdApp, s *apps.StatefulSet, d *apps.Deployment, nn types.NamespacedName, deployment *crd.Deployment) {
+	labels := app.GetLabels()
+	labels["pod"] = nn.Name
+	app.SetObjectMeta(s, crd.Name(nn.Name), crd.Labels(labels))
+
+	s.Kind = "StatefulSet"
+
+	utils.UpdateAnnotations(s, app.Annotations, deployment.Metadata.Annotations)
+
+	s.Spec.Replicas = d.Spec.Replicas
+	s.Spec.Selector = d.Spec.Selector
+	s.Spec.Template = d.Spec.Template
+	s.Spec.ServiceName = headlessServiceName(nn)
+
+	// Volume claim templates are immutable once the StatefulSet exists, so they are only set
+	// when it is first created.
+	if s.CreationTimestamp.IsZero() {
+		s.Spec.VolumeClaimTemplates = makeVolumeClaimTemplates(labels, deployment.VolumeClaimTemplates)
+	}
+}
+
+func makeVolumeClaimTemplates(labels map[string]string, templates []crd.VolumeClaimTemplate) []core.PersistentVolumeClaim {
+	claims := []core.PersistentVolumeClaim{}
+	for _, template := range templates {
+		claim := core.PersistentVolumeClaim{
+			Spec: template.Spec,
+		}
+		claim.Name = template.Name
+		claim.Labels = labels
+		claims = append(claims, claim)
+	}
+	return claims
+}
+
+func (dp *deploymentProvider) makeHeadlessService(app *crd.ClowdApp, nn types.NamespacedName) error {
+	s := &core.Service{}
+	snn := types.NamespacedName{
+		Name:      headlessServiceName(nn),
+		Namespace: nn.Namespace,
+	}
+
+	if err := dp.Cache.Create(CoreHeadlessService, snn, s); err != nil {
+		return err
+	}
+
+	labels := app.GetLabels()
+	labels["pod"] = nn.Name
+	app.SetObjectMeta(s, crd.Name(snn.Name), crd.Labels(labels))
+
+	s.Spec.ClusterIP = core.ClusterIPNone
+	s.Spec.Selector = map[string]string{"pod": nn.Name}
+	// Peers need to find each other while they start, before they report ready.
+	s.Spec.PublishNotReadyAddresses = true
+
+	return dp.Cache.Update(CoreHeadlessService, s)
+}
+
+func headlessServiceName(nn types.NamespacedName) string {
+	return fmt.Sprintf("%s-headless", nn.Name)
+}
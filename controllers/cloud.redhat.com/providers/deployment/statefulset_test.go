@@ -0,0 +1,47 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+func TestInitStatefulSet(t *testing.T) {
+	app := &crd.ClowdApp{ObjectMeta: defaultMetaObject()}
+	env := &crd.ClowdEnvironment{}
+	deployment := &crd.Deployment{
+		Name: "db",
+		Kind: crd.WorkloadStatefulSet,
+		PodSpec: crd.PodSpec{
+			Image: "quay.io/psav/clowder-hello",
+		},
+		VolumeClaimTemplates: []crd.VolumeClaimTemplate{{
+			Name: "data",
+			Spec: core.PersistentVolumeClaimSpec{
+				AccessModes: []core.PersistentVolumeAccessMode{core.ReadWriteOnce},
+			},
+		}},
+	}
+	nn := app.GetDeploymentNamespacedName(deployment)
+
+	d := &apps.Deployment{}
+	assert.NoError(t, initDeployment(app, env, d, nn, deployment))
+
+	s := &apps.StatefulSet{}
+	initStatefulSet(app, s, d, nn, deployment)
+
+	assert.Equal(t, "reqapp-db-headless", s.Spec.ServiceName)
+	assert.Equal(t, d.Spec.Template, s.Spec.Template)
+	assert.Equal(t, d.Spec.Selector, s.Spec.Selector)
+	assert.Len(t, s.Spec.VolumeClaimTemplates, 1)
+	assert.Equal(t, "data", s.Spec.VolumeClaimTemplates[0].Name)
+
+	existing := &apps.StatefulSet{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()}}
+	initStatefulSet(app, existing, d, nn, deployment)
+	assert.Empty(t, existing.Spec.VolumeClaimTemplates, "claim templates are left alone on existing StatefulSets")
+}
@@ -0,0 +1,112 @@
+package deployment
+
+import (
+	"context"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+// Workload is the object a ClowdApp deployment is rendered as, either an apps.Deployment or an
+// apps.StatefulSet. Providers that decorate the pod template work through a Workload so that the
+// same wiring is applied whichever kind the deployment asked for.
+type Workload struct {
+	client.Object
+	Template *core.PodTemplateSpec
+	Kind     crd.WorkloadKind
+	ident    rc.ResourceIdentMulti
+}
+
+// GetWorkload pulls the workload rendered for the given deployment out of the cache.
+func GetWorkload(cache *rc.ObjectCache, app *crd.ClowdApp, deployment *crd.Deployment) (*Workload, error) {
+	nn := app.GetDeploymentNamespacedName(deployment)
+
+	if deployment.IsStatefulSet() {
+		s := &apps.StatefulSet{}
+		if err := cache.Get(CoreStatefulSet, s, nn); err != nil {
+			return nil, err
+		}
+		return newStatefulSetWorkload(s), nil
+	}
+
+	d := &apps.Deployment{}
+	if err := cache.Get(CoreDeployment, d, nn); err != nil {
+		return nil, err
+	}
+	return newDeploymentWorkload(d), nil
+}
+
+// ListWorkloads returns every Deployment and StatefulSet workload in the cache.
+func ListWorkloads(cache *rc.ObjectCache) ([]*Workload, error) {
+	workloads := []*Workload{}
+
+	dList := apps.DeploymentList{}
+	if err := cache.List(CoreDeployment, &dList); err != nil {
+		return nil, err
+	}
+	for i := range dList.Items {
+		workloads = append(workloads, newDeploymentWorkload(&dList.Items[i]))
+	}
+
+	sList := apps.StatefulSetList{}
+	if err := cache.List(CoreStatefulSet, &sList); err != nil {
+		return nil, err
+	}
+	for i := range sList.Items {
+		workloads = append(workloads, newStatefulSetWorkload(&sList.Items[i]))
+	}
+
+	return workloads, nil
+}
+
+// UpdateWorkload writes the workload back into the cache.
+func UpdateWorkload(cache *rc.ObjectCache, w *Workload) error {
+	return cache.Update(w.ident, w.Object)
+}
+
+// HoldWorkload stops the cache from rolling out changes to the workload. A workload that already
+// exists is replaced in the cache with its live copy so that applying the cache leaves it alone, a
+// workload that does not exist yet is created with no replicas.
+func HoldWorkload(ctx context.Context, c client.Client, cache *rc.ObjectCache, w *Workload) error {
+	live := w.DeepCopyObject().(client.Object)
+	err := c.Get(ctx, client.ObjectKeyFromObject(w.Object), live)
+	if err != nil && !k8serr.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		return cache.Update(w.ident, live)
+	}
+
+	switch obj := w.Object.(type) {
+	case *apps.Deployment:
+		obj.Spec.Replicas = utils.Int32Ptr(0)
+	case *apps.StatefulSet:
+		obj.Spec.Replicas = utils.Int32Ptr(0)
+	}
+	return UpdateWorkload(cache, w)
+}
+
+func newDeploymentWorkload(d *apps.Deployment) *Workload {
+	return &Workload{
+		Object:   d,
+		Template: &d.Spec.Template,
+		Kind:     crd.WorkloadDeployment,
+		ident:    CoreDeployment,
+	}
+}
+
+func newStatefulSetWorkload(s *apps.StatefulSet) *Workload {
+	return &Workload{
+		Object:   s,
+		Template: &s.Spec.Template,
+		Kind:     crd.WorkloadStatefulSet,
+		ident:    CoreStatefulSet,
+	}
+}
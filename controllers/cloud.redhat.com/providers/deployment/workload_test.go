@@ -0,0 +1,66 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func TestHoldWorkload(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	app := &crd.ClowdApp{ObjectMeta: defaultMetaObject()}
+	running := &crd.Deployment{Name: "running"}
+	fresh := &crd.Deployment{Name: "fresh"}
+
+	live := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "reqapp-running", Namespace: "default"},
+		Spec:       apps.DeploymentSpec{Replicas: utils.Int32Ptr(3)},
+	}
+	live.Spec.Template.Spec.ServiceAccountName = "old"
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(live).Build()
+	log := logr.Discard()
+	cache := rc.NewObjectCache(context.TODO(), c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+
+	for _, deployment := range []*crd.Deployment{running, fresh} {
+		nn := app.GetDeploymentNamespacedName(deployment)
+		d := &apps.Deployment{}
+		require.NoError(t, cache.Create(CoreDeployment, nn, d))
+		d.Name, d.Namespace = nn.Name, nn.Namespace
+		d.Spec.Replicas = utils.Int32Ptr(2)
+		d.Spec.Template.Spec.ServiceAccountName = "new"
+		require.NoError(t, cache.Update(CoreDeployment, d))
+	}
+
+	workloads, err := ListWorkloads(&cache)
+	require.NoError(t, err)
+	require.Len(t, workloads, 2)
+	for _, w := range workloads {
+		require.NoError(t, HoldWorkload(context.TODO(), c, &cache, w))
+	}
+
+	w, err := GetWorkload(&cache, app, running)
+	require.NoError(t, err)
+	assert.Equal(t, "old", w.Template.Spec.ServiceAccountName, "a running workload keeps its live spec")
+	assert.Equal(t, int32(3), *w.Object.(*apps.Deployment).Spec.Replicas)
+
+	w, err = GetWorkload(&cache, app, fresh)
+	require.NoError(t, err)
+	assert.Equal(t, "new", w.Template.Spec.ServiceAccountName)
+	assert.Equal(t, int32(0), *w.Object.(*apps.Deployment).Spec.Replicas, "a new workload is created without replicas")
+}
@@ -0,0 +1,214 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	errlib "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+)
+
+// AdminTimeout bounds each request made through a KafkaAdmin.
+var AdminTimeout = 10 * time.Second
+
+// LiveTopic is the state of a topic as the brokers report it.
+type LiveTopic struct {
+	Partitions int32
+	Replicas   int32
+	// Config holds the topic's config values, including the broker defaults.
+	Config map[string]string
+}
+
+// KafkaAdmin is the part of the Kafka admin API used to check an app's topics and consumer groups
+// against the brokers directly.
+type KafkaAdmin interface {
+	// DescribeTopics returns the live state of the topics that exist among names.
+	DescribeTopics(ctx context.Context, names []string) (map[string]LiveTopic, error)
+	// ListGroups returns the names of the consumer groups known to the brokers.
+	ListGroups(ctx context.Context) ([]string, error)
+	// GroupLag returns the lag of each of the groups, per topic, summed over the partitions.
+	GroupLag(ctx context.Context, groups []string) (map[string]map[string]int64, error)
+	Close()
+}
+
+// NewKafkaAdmin connects a KafkaAdmin to the brokers of an app's config, with the app's own
+// credentials. It is replaced in tests.
+var NewKafkaAdmin = newFranzAdmin
+
+type franzAdmin struct {
+	client *kgo.Client
+	admin  *kadm.Client
+}
+
+func newFranzAdmin(brokers []config.BrokerConfig) (KafkaAdmin, error) {
+	if len(brokers) == 0 {
+		return nil, errors.NewClowderError("no kafka brokers in the app config")
+	}
+
+	opts := []kgo.Opt{kgo.RequestTimeoutOverhead(AdminTimeout)}
+
+	seeds := []string{}
+	for _, broker := range brokers {
+		port := 9092
+		if broker.Port != nil {
+			port = *broker.Port
+		}
+		seeds = append(seeds, fmt.Sprintf("%s:%d", broker.Hostname, port))
+	}
+	opts = append(opts, kgo.SeedBrokers(seeds...))
+
+	// The brokers of an app's config all share the same connection settings.
+	broker := brokers[0]
+
+	if broker.Authtype != nil && *broker.Authtype == config.BrokerConfigAuthtypeIam {
+		return nil, errors.NewClowderError("IAM authentication is not supported by the kafka admin client")
+	}
+
+	protocol := ""
+	if broker.SecurityProtocol != nil {
+		protocol = *broker.SecurityProtocol
+	} else if broker.Sasl != nil && broker.Sasl.SecurityProtocol != nil {
+		protocol = *broker.Sasl.SecurityProtocol
+	}
+
+	if protocol == "SSL" || protocol == "SASL_SSL" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if broker.Cacert != nil && *broker.Cacert != "" {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM([]byte(*broker.Cacert)) {
+				return nil, errors.NewClowderError("could not read the kafka CA certificate")
+			}
+			tlsConfig.RootCAs = pool
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	if broker.Sasl != nil && broker.Sasl.Username != nil && broker.Sasl.Password != nil {
+		mechanism, err := saslMechanism(broker.Sasl)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, errors.Wrap("could not create kafka admin client", err)
+	}
+	return &franzAdmin{client: client, admin: kadm.NewClient(client)}, nil
+}
+
+func saslMechanism(cfg *config.KafkaSASLConfig) (sasl.Mechanism, error) {
+	mechanism := "PLAIN"
+	if cfg.SaslMechanism != nil {
+		mechanism = strings.ToUpper(*cfg.SaslMechanism)
+	}
+
+	switch mechanism {
+	case "SCRAM-SHA-512":
+		return scram.Auth{User: *cfg.Username, Pass: *cfg.Password}.AsSha512Mechanism(), nil
+	case "SCRAM-SHA-256":
+		return scram.Auth{User: *cfg.Username, Pass: *cfg.Password}.AsSha256Mechanism(), nil
+	case "PLAIN":
+		return plain.Auth{User: *cfg.Username, Pass: *cfg.Password}.AsMechanism(), nil
+	default:
+		return nil, errors.NewClowderError(fmt.Sprintf("sasl mechanism %s is not supported by the kafka admin client", mechanism))
+	}
+}
+
+func (a *franzAdmin) DescribeTopics(ctx context.Context, names []string) (map[string]LiveTopic, error) {
+	ctx, cancel := context.WithTimeout(ctx, AdminTimeout)
+	defer cancel()
+
+	details, err := a.admin.ListTopics(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := map[string]LiveTopic{}
+	existing := []string{}
+	for name, detail := range details {
+		if detail.Err != nil {
+			if errlib.Is(detail.Err, kerr.UnknownTopicOrPartition) {
+				continue
+			}
+			return nil, detail.Err
+		}
+		topics[name] = LiveTopic{
+			Partitions: int32(len(detail.Partitions)),
+			Replicas:   int32(detail.Partitions.NumReplicas()),
+			Config:     map[string]string{},
+		}
+		existing = append(existing, name)
+	}
+
+	if len(existing) == 0 {
+		return topics, nil
+	}
+
+	configs, err := a.admin.DescribeTopicConfigs(ctx, existing...)
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range configs {
+		if resource.Err != nil {
+			return nil, resource.Err
+		}
+		for _, c := range resource.Configs {
+			if c.Value != nil {
+				topics[resource.Name].Config[c.Key] = *c.Value
+			}
+		}
+	}
+
+	return topics, nil
+}
+
+func (a *franzAdmin) ListGroups(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, AdminTimeout)
+	defer cancel()
+
+	groups, err := a.admin.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return groups.Groups(), nil
+}
+
+func (a *franzAdmin) GroupLag(ctx context.Context, groups []string) (map[string]map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, AdminTimeout)
+	defer cancel()
+
+	described, err := a.admin.Lag(ctx, groups...)
+	if err != nil {
+		return nil, err
+	}
+
+	lags := map[string]map[string]int64{}
+	for group, lag := range described {
+		if err := lag.Error(); err != nil {
+			return nil, err
+		}
+		lags[group] = map[string]int64{}
+		for topic, total := range lag.Lag.TotalByTopic() {
+			lags[group][topic] = total.Lag
+		}
+	}
+	return lags, nil
+}
+
+func (a *franzAdmin) Close() {
+	a.client.Close()
+}
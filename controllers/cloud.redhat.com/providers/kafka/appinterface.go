@@ -100,7 +100,7 @@ func (a *appInterface) Provide(app *crd.ClowdApp) error {
 		Brokers: []config.BrokerConfig{brokerConfig},
 	}
 
-	for _, topic := range app.Spec.KafkaTopics {
+	for _, topic := range app.GetKafkaTopics() {
 		topicName := types.NamespacedName{
 			Namespace: getKafkaNamespace(a.Env),
 			Name:      topic.TopicName,
@@ -115,12 +115,15 @@ func (a *appInterface) Provide(app *crd.ClowdApp) error {
 		a.Config.Kafka.Topics = append(
 			a.Config.Kafka.Topics,
 			config.TopicConfig{
-				Name:          topic.TopicName,
-				RequestedName: topic.TopicName,
+				Name:           topic.TopicName,
+				RequestedName:  topic.TopicName,
+				ConsumerGroups: topic.ConsumerGroups,
 			},
 		)
 	}
-	return nil
+	linkCompanionTopics(app, a.Config.Kafka.Topics)
+
+	return setExternalSchemaRegistryConfig(&a.Provider, a.Config.Kafka)
 }
 
 func validateKafkaTopic(ctx context.Context, cl client.Client, nn types.NamespacedName) error {
@@ -69,3 +69,58 @@ func TestAppInterface(t *testing.T) {
 	assert.Equal(t, topicName, topic.Name, "wrong topic name")
 	assert.Equal(t, topicName, topic.RequestedName, "wrong requested topic name")
 }
+
+func TestAppInterfaceDeadLetter(t *testing.T) {
+	pr := providers.Provider{
+		Env: &crd.ClowdEnvironment{
+			Spec: crd.ClowdEnvironmentSpec{
+				Providers: crd.ProvidersConfig{
+					Kafka: crd.KafkaConfig{
+						Mode:    "app-interface",
+						Cluster: crd.KafkaClusterConfig{Name: "platform-mq", Namespace: "platform-mq-prod"},
+					},
+				},
+			},
+		},
+		Config: &config.AppConfig{},
+	}
+
+	app := &crd.ClowdApp{
+		Spec: crd.ClowdAppSpec{
+			KafkaTopics: []crd.KafkaTopicSpec{{
+				TopicName:      "events",
+				ConsumerGroups: []string{"processor"},
+				DeadLetter:     &crd.KafkaDeadLetterSpec{RetryTopics: 2},
+			}, {
+				TopicName: "ingress",
+			}},
+		},
+	}
+
+	ai, err := NewAppInterface(&pr)
+	assert.NoError(t, err)
+	assert.NoError(t, ai.Provide(app))
+
+	assert.Equal(t, []config.TopicConfig{{
+		Name:            "events",
+		RequestedName:   "events",
+		ConsumerGroups:  []string{"processor"},
+		DeadLetterTopic: utils.StringPtr("events.dlq"),
+		RetryTopics:     []string{"events.retry.1", "events.retry.2"},
+	}, {
+		Name:           "events.retry.1",
+		RequestedName:  "events.retry.1",
+		ConsumerGroups: []string{"processor"},
+	}, {
+		Name:           "events.retry.2",
+		RequestedName:  "events.retry.2",
+		ConsumerGroups: []string{"processor"},
+	}, {
+		Name:           "events.dlq",
+		RequestedName:  "events.dlq",
+		ConsumerGroups: []string{"processor"},
+	}, {
+		Name:          "ingress",
+		RequestedName: "ingress",
+	}}, ai.GetConfig().Kafka.Topics)
+}
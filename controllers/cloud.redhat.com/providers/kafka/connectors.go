@@ -0,0 +1,346 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	core "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+// ConnectorAppLabel is set on every KafkaConnector to the name of the ClowdApp that declared it.
+const ConnectorAppLabel = "clowder/app"
+
+// ConnectorAppNamespaceLabel is set on every KafkaConnector to the namespace of the ClowdApp that
+// declared it.
+const ConnectorAppNamespaceLabel = "clowder/app-namespace"
+
+// ConnectorsProvName is the name/ident of the provider that renders the apps' KafkaConnectors.
+var ConnectorsProvName = "kafkaconnectors"
+
+// KafkaConnector is the resource ident for the KafkaConnectors declared by an app.
+var KafkaConnector = rc.NewMultiResourceIdent(ConnectorsProvName, "kafka_connector", &strimzi.KafkaConnector{})
+
+// KafkaConnectorSecret is the resource ident for the secret holding the templated config values
+// of an app's KafkaConnectors.
+var KafkaConnectorSecret = rc.NewSingleResourceIdent(ConnectorsProvName, "kafka_connector_secret", &core.Secret{})
+
+// KafkaConnectorRole is the resource ident for the role that lets the Kafka Connect cluster read
+// an app's connector secret.
+var KafkaConnectorRole = rc.NewSingleResourceIdent(ConnectorsProvName, "kafka_connector_role", &rbac.Role{})
+
+// KafkaConnectorRoleBinding is the resource ident for the binding of the connector secret role to
+// the Kafka Connect cluster's service account.
+var KafkaConnectorRoleBinding = rc.NewSingleResourceIdent(ConnectorsProvName, "kafka_connector_role_binding", &rbac.RoleBinding{})
+
+type kafkaConnectorsProvider struct {
+	providers.Provider
+}
+
+// GetKafkaConnectors returns the provider that renders the KafkaConnectors of an app. It is run
+// after the object store provider, so that connectors can refer to every part of the app's config.
+func GetKafkaConnectors(p *providers.Provider) (providers.ClowderProvider, error) {
+	if connectEnabled(p.Env) {
+		p.Cache.AddPossibleGVKFromIdent(
+			KafkaConnector,
+			KafkaConnectorSecret,
+			KafkaConnectorRole,
+			KafkaConnectorRoleBinding,
+		)
+	}
+	return &kafkaConnectorsProvider{Provider: *p}, nil
+}
+
+func init() {
+	providers.ProvidersRegistration.Register(GetKafkaConnectors, 8, ConnectorsProvName)
+}
+
+// connectEnabled returns whether the env's Kafka mode runs a Kafka Connect cluster.
+func connectEnabled(env *crd.ClowdEnvironment) bool {
+	switch env.Spec.Providers.Kafka.Mode {
+	case "operator", "ephem-msk":
+		return true
+	}
+	return false
+}
+
+func (k *kafkaConnectorsProvider) EnvProvide() error {
+	return nil
+}
+
+func (k *kafkaConnectorsProvider) Provide(app *crd.ClowdApp) error {
+	if !connectEnabled(k.Env) {
+		return nil
+	}
+	return createKafkaConnectors(&k.Provider, app)
+}
+
+// FinalizeApp removes the app's KafkaConnectors, which are owned by the environment.
+func (k *kafkaConnectorsProvider) FinalizeApp(app *crd.ClowdApp) error {
+	if !connectEnabled(k.Env) {
+		return nil
+	}
+	return deleteKafkaConnectors(&k.Provider, app, nil)
+}
+
+// GetKafkaConnectorName returns the name of the KafkaConnector rendered for one of an app's
+// connectors.
+func GetKafkaConnectorName(app *crd.ClowdApp, connector *crd.KafkaConnectorSpec) string {
+	return fmt.Sprintf("%s-%s", app.Name, connector.Name)
+}
+
+// GetKafkaConnectorSecretName returns the name of the secret, role and role binding that hold and
+// give access to the templated config values of an app's KafkaConnectors.
+func GetKafkaConnectorSecretName(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%s-kafka-connectors", app.Name)
+}
+
+// GetKafkaConnectorSelector returns the labels that select every KafkaConnector declared by an app.
+func GetKafkaConnectorSelector(app *crd.ClowdApp) client.MatchingLabels {
+	return client.MatchingLabels{
+		ConnectorAppLabel:          app.Name,
+		ConnectorAppNamespaceLabel: app.Namespace,
+	}
+}
+
+// renderConnectorConfig renders each value of a connector's config as a template against the
+// app's config. A "bucket" function looks a bucket up by the name requested in the ClowdApp.
+func renderConnectorConfig(connector *crd.KafkaConnectorSpec, appConfig *config.AppConfig) (map[string]string, error) {
+	funcs := template.FuncMap{
+		"bucket": func(name string) (*config.ObjectStoreBucket, error) {
+			if appConfig.ObjectStore != nil {
+				for i, bucket := range appConfig.ObjectStore.Buckets {
+					if bucket.RequestedName == name {
+						return &appConfig.ObjectStore.Buckets[i], nil
+					}
+				}
+			}
+			return nil, fmt.Errorf("no bucket named [%s] in the app config", name)
+		},
+	}
+
+	rendered := map[string]string{}
+	for key, value := range connector.Config {
+		tmpl, err := template.New(key).Funcs(funcs).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, errors.Wrap(fmt.Sprintf("kafka connector [%s] config [%s]", connector.Name, key), err)
+		}
+
+		buf := bytes.Buffer{}
+		if err := tmpl.Execute(&buf, appConfig); err != nil {
+			return nil, errors.Wrap(fmt.Sprintf("kafka connector [%s] config [%s]", connector.Name, key), err)
+		}
+		rendered[key] = buf.String()
+	}
+	return rendered, nil
+}
+
+// isConnectorTemplate returns whether a connector config value is a template. Templated values can
+// carry credentials from the app's config and are kept out of the KafkaConnector spec.
+func isConnectorTemplate(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// getConnectorSecretKey returns the key of a connector config value in the app's connector secret.
+// Connector names cannot contain dots, so keys of different connectors never clash.
+func getConnectorSecretKey(connector *crd.KafkaConnectorSpec, key string) string {
+	return fmt.Sprintf("%s.%s", connector.Name, key)
+}
+
+// createKafkaConnectors renders a KafkaConnector on the env's Kafka Connect cluster for each of
+// the app's connectors and removes any the app no longer declares. Connectors cannot be owned by
+// the app across namespaces, so, like the CyndiPipeline, they are owned by the ClowdEnvironment.
+// Templated config values are rendered into a secret that the connectors refer to through Strimzi's
+// KubernetesSecretConfigProvider, so that no credentials end up in the KafkaConnector spec.
+func createKafkaConnectors(p *providers.Provider, app *crd.ClowdApp) error {
+	if len(app.Spec.KafkaConnectors) == 0 {
+		return deleteKafkaConnectors(p, app, nil)
+	}
+
+	namespace := getConnectNamespace(p.Env)
+	secretNN := types.NamespacedName{
+		Name:      GetKafkaConnectorSecretName(app),
+		Namespace: namespace,
+	}
+
+	labels := map[string]string{"strimzi.io/cluster": getConnectClusterName(p.Env)}
+	for k, v := range GetKafkaConnectorSelector(app) {
+		labels[k] = v
+	}
+
+	secretData := map[string][]byte{}
+	declared := map[string]bool{}
+	for i := range app.Spec.KafkaConnectors {
+		connector := &app.Spec.KafkaConnectors[i]
+
+		nn := types.NamespacedName{
+			Name:      GetKafkaConnectorName(app, connector),
+			Namespace: namespace,
+		}
+		declared[nn.Name] = true
+
+		kc := &strimzi.KafkaConnector{}
+		if err := p.Cache.Create(KafkaConnector, nn, kc); err != nil {
+			return err
+		}
+
+		setConnectorObjectMeta(p, kc, nn, labels)
+
+		connectorConfig, err := renderConnectorConfig(connector, p.Config)
+		if err != nil {
+			return err
+		}
+
+		for key, value := range connectorConfig {
+			if !isConnectorTemplate(connector.Config[key]) {
+				continue
+			}
+			secretKey := getConnectorSecretKey(connector, key)
+			secretData[secretKey] = []byte(value)
+			connectorConfig[key] = fmt.Sprintf("${secrets:%s/%s:%s}", secretNN.Namespace, secretNN.Name, secretKey)
+		}
+
+		rawConfig, err := json.Marshal(connectorConfig)
+		if err != nil {
+			return err
+		}
+
+		tasksMax := connector.TasksMax
+		if tasksMax == 0 {
+			tasksMax = 1
+		}
+
+		kc.Spec = &strimzi.KafkaConnectorSpec{
+			Class:    utils.StringPtr(connector.Class),
+			Config:   &apiextensions.JSON{Raw: rawConfig},
+			TasksMax: &tasksMax,
+		}
+		if connector.Pause {
+			state := strimzi.KafkaConnectorSpecStatePaused
+			kc.Spec.State = &state
+		}
+
+		if err := p.Cache.Update(KafkaConnector, kc); err != nil {
+			return err
+		}
+	}
+
+	if err := createKafkaConnectorSecret(p, secretNN, labels, secretData); err != nil {
+		return err
+	}
+
+	return deleteKafkaConnectors(p, app, declared)
+}
+
+// createKafkaConnectorSecret writes the app's connector secret and gives the Kafka Connect
+// cluster's service account, which the config provider reads it as, access to it alone.
+func createKafkaConnectorSecret(p *providers.Provider, nn types.NamespacedName, labels map[string]string, data map[string][]byte) error {
+	secret := &core.Secret{}
+	if err := p.Cache.Create(KafkaConnectorSecret, nn, secret); err != nil {
+		return err
+	}
+	setConnectorObjectMeta(p, secret, nn, labels)
+	secret.Type = core.SecretTypeOpaque
+	secret.Data = data
+	if err := p.Cache.Update(KafkaConnectorSecret, secret); err != nil {
+		return err
+	}
+
+	role := &rbac.Role{}
+	if err := p.Cache.Create(KafkaConnectorRole, nn, role); err != nil {
+		return err
+	}
+	setConnectorObjectMeta(p, role, nn, labels)
+	role.Rules = []rbac.PolicyRule{{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		ResourceNames: []string{nn.Name},
+		Verbs:         []string{"get"},
+	}}
+	if err := p.Cache.Update(KafkaConnectorRole, role); err != nil {
+		return err
+	}
+
+	binding := &rbac.RoleBinding{}
+	if err := p.Cache.Create(KafkaConnectorRoleBinding, nn, binding); err != nil {
+		return err
+	}
+	setConnectorObjectMeta(p, binding, nn, labels)
+	binding.RoleRef = rbac.RoleRef{
+		APIGroup: "rbac.authorization.k8s.io",
+		Kind:     "Role",
+		Name:     nn.Name,
+	}
+	binding.Subjects = []rbac.Subject{{
+		Kind:      rbac.ServiceAccountKind,
+		Name:      fmt.Sprintf("%s-connect", getConnectClusterName(p.Env)),
+		Namespace: nn.Namespace,
+	}}
+	return p.Cache.Update(KafkaConnectorRoleBinding, binding)
+}
+
+// setConnectorObjectMeta names and labels an object rendered for an app's connectors. It would be
+// best for the ClowdApp to own these, but since cross-namespace OwnerReferences are not permitted,
+// they are owned by the ClowdEnvironment. They do not carry the env's label, so that the env's
+// reconciliation, which does not render them, leaves them be.
+func setConnectorObjectMeta(p *providers.Provider, obj metav1.Object, nn types.NamespacedName, labels map[string]string) {
+	objLabels := map[string]string{}
+	for k, v := range labels {
+		objLabels[k] = v
+	}
+	obj.SetName(nn.Name)
+	obj.SetNamespace(nn.Namespace)
+	obj.SetLabels(objLabels)
+	obj.SetOwnerReferences([]metav1.OwnerReference{p.Env.MakeOwnerReference()})
+}
+
+// deleteKafkaConnectors deletes the app's KafkaConnectors, other than those named in keep. With
+// nothing to keep, the app's connector secret and its role and binding are deleted as well.
+func deleteKafkaConnectors(p *providers.Provider, app *crd.ClowdApp, keep map[string]bool) error {
+	connectors := strimzi.KafkaConnectorList{}
+	if err := p.Client.List(p.Ctx, &connectors, client.InNamespace(getConnectNamespace(p.Env)), GetKafkaConnectorSelector(app)); err != nil {
+		return err
+	}
+
+	for i := range connectors.Items {
+		if keep[connectors.Items[i].Name] {
+			continue
+		}
+		if err := p.Client.Delete(p.Ctx, &connectors.Items[i]); err != nil && !k8serr.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if len(keep) > 0 {
+		return nil
+	}
+
+	meta := metav1.ObjectMeta{Name: GetKafkaConnectorSecretName(app), Namespace: getConnectNamespace(p.Env)}
+	for _, obj := range []client.Object{
+		&rbac.RoleBinding{ObjectMeta: meta},
+		&rbac.Role{ObjectMeta: meta},
+		&core.Secret{ObjectMeta: meta},
+	} {
+		if err := p.Client.Delete(p.Ctx, obj); err != nil && !k8serr.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	rbac "k8s.io/api/rbac/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+)
+
+func TestRenderConnectorConfig(t *testing.T) {
+	appConfig := &config.AppConfig{
+		Database: &config.DatabaseConfig{Hostname: "app-db.ns.svc", Port: 5432},
+		ObjectStore: &config.ObjectStoreConfig{
+			Buckets: []config.ObjectStoreBucket{{Name: "archive-env", RequestedName: "archive"}},
+		},
+	}
+
+	connector := &crd.KafkaConnectorSpec{
+		Name: "sink",
+		Config: map[string]string{
+			"database.hostname": "{{ .Database.Hostname }}",
+			"database.port":     "{{ .Database.Port }}",
+			"s3.bucket.name":    `{{ (bucket "archive").Name }}`,
+			"topics":            "events",
+		},
+	}
+
+	rendered, err := renderConnectorConfig(connector, appConfig)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"database.hostname": "app-db.ns.svc",
+		"database.port":     "5432",
+		"s3.bucket.name":    "archive-env",
+		"topics":            "events",
+	}, rendered)
+
+	connector.Config = map[string]string{"s3.bucket.name": `{{ (bucket "missing").Name }}`}
+	_, err = renderConnectorConfig(connector, appConfig)
+	assert.ErrorContains(t, err, "missing")
+
+	connector.Config = map[string]string{"inmemory": "{{ .InMemoryDb.Hostname }}"}
+	_, err = renderConnectorConfig(connector, appConfig)
+	assert.Error(t, err, "referring to config the app does not have is an error")
+}
+
+func TestCreateKafkaConnectors(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, strimzi.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Status.TargetNamespace = "env-ns"
+
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+	app.Namespace = "app-ns"
+	app.Spec.KafkaConnectors = []crd.KafkaConnectorSpec{{
+		Name:  "source",
+		Class: "io.debezium.connector.postgresql.PostgresConnector",
+		Config: map[string]string{
+			"database.hostname": "{{ .Database.Hostname }}",
+			"database.password": "{{ .Database.Password }}",
+			"topic.prefix":      "app",
+		},
+		Pause: true,
+	}}
+
+	stale := &strimzi.KafkaConnector{}
+	stale.Name = "app-removed"
+	stale.Namespace = "env-ns"
+	stale.Labels = GetKafkaConnectorSelector(app)
+
+	ctx := context.Background()
+	log := logr.Discard()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(stale).Build()
+	cache := rc.NewObjectCache(ctx, c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+
+	p := &providers.Provider{
+		Ctx:    ctx,
+		Client: c,
+		Env:    env,
+		Cache:  &cache,
+		Config: &config.AppConfig{Database: &config.DatabaseConfig{Hostname: "app-db.app-ns.svc", Password: "hunter2"}},
+	}
+
+	require.NoError(t, createKafkaConnectors(p, app))
+	require.NoError(t, cache.ApplyAll())
+
+	kc := &strimzi.KafkaConnector{}
+	require.NoError(t, c.Get(ctx, types.NamespacedName{Name: "app-source", Namespace: "env-ns"}, kc))
+	assert.Equal(t, "env", kc.Labels["strimzi.io/cluster"])
+	assert.Equal(t, "app", kc.Labels[ConnectorAppLabel])
+	assert.Equal(t, "app-ns", kc.Labels[ConnectorAppNamespaceLabel])
+	assert.Equal(t, strimzi.KafkaConnectorSpecStatePaused, *kc.Spec.State)
+	assert.Equal(t, int32(1), *kc.Spec.TasksMax)
+
+	cfg := map[string]string{}
+	require.NoError(t, json.Unmarshal(kc.Spec.Config.Raw, &cfg))
+	assert.Equal(t, map[string]string{
+		"database.hostname": "${secrets:env-ns/app-kafka-connectors:source.database.hostname}",
+		"database.password": "${secrets:env-ns/app-kafka-connectors:source.database.password}",
+		"topic.prefix":      "app",
+	}, cfg, "templated values are only referred to from the connector")
+
+	nn := types.NamespacedName{Name: "app-kafka-connectors", Namespace: "env-ns"}
+	secret := &core.Secret{}
+	require.NoError(t, c.Get(ctx, nn, secret))
+	assert.Equal(t, map[string][]byte{
+		"source.database.hostname": []byte("app-db.app-ns.svc"),
+		"source.database.password": []byte("hunter2"),
+	}, secret.Data)
+
+	role := &rbac.Role{}
+	require.NoError(t, c.Get(ctx, nn, role))
+	assert.Equal(t, []string{"app-kafka-connectors"}, role.Rules[0].ResourceNames)
+
+	binding := &rbac.RoleBinding{}
+	require.NoError(t, c.Get(ctx, nn, binding))
+	assert.Equal(t, "env-connect", binding.Subjects[0].Name, "the connect cluster's service account reads the secret")
+
+	connectors := strimzi.KafkaConnectorList{}
+	require.NoError(t, c.List(ctx, &connectors, client.InNamespace("env-ns")))
+	assert.Len(t, connectors.Items, 1, "connectors the app no longer declares are removed")
+
+	app.Spec.KafkaConnectors = nil
+	require.NoError(t, createKafkaConnectors(p, app))
+
+	require.NoError(t, c.List(ctx, &connectors, client.InNamespace("env-ns")))
+	assert.Empty(t, connectors.Items)
+	assert.True(t, k8serr.IsNotFound(c.Get(ctx, nn, &core.Secret{})), "the connector secret goes with the last connector")
+	assert.True(t, k8serr.IsNotFound(c.Get(ctx, nn, &rbac.RoleBinding{})))
+}
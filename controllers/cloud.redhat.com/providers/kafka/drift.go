@@ -0,0 +1,324 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+)
+
+// TopicDrift is a setting of a live topic that differs from what the apps in the environment
+// declare for it.
+type TopicDrift struct {
+	// Topic is the name of the live topic.
+	Topic string
+	// Setting is partitions, replicas, a config key, or ready for a topic the operator could not
+	// reconcile.
+	Setting  string
+	Declared string
+	Live     string
+}
+
+func (d TopicDrift) String() string {
+	return fmt.Sprintf("%s %s: declared %s, live %s", d.Topic, d.Setting, d.Declared, d.Live)
+}
+
+// AppTopicIndex is the field index of ClowdApps on the topics they declare. Its values are the
+// name of the app's environment and of the topic, so that the apps sharing a topic can be listed
+// without listing every app in the environment.
+const AppTopicIndex = "spec.kafkaTopics.topicName"
+
+// AppTopicIndexValues returns the AppTopicIndex values of a ClowdApp.
+func AppTopicIndexValues(o client.Object) []string {
+	app := o.(*crd.ClowdApp)
+	values := []string{}
+	for _, topic := range app.GetKafkaTopics() {
+		values = append(values, appTopicIndexValue(app.Spec.EnvName, topic.TopicName))
+	}
+	return values
+}
+
+func appTopicIndexValue(envName string, topicName string) string {
+	return fmt.Sprintf("%s/%s", envName, topicName)
+}
+
+// getAppsDeclaringTopic returns the apps in the environment that declare the topic.
+func getAppsDeclaringTopic(ctx context.Context, c client.Client, env *crd.ClowdEnvironment, topicName string) (*crd.ClowdAppList, error) {
+	appList := &crd.ClowdAppList{}
+	if err := c.List(ctx, appList, client.MatchingFields{AppTopicIndex: appTopicIndexValue(env.Name, topicName)}); err != nil {
+		return nil, err
+	}
+	return appList, nil
+}
+
+// getTopicNamespace returns the namespace the KafkaTopic resources are found in for the modes in
+// which they can be read, and false for the modes in which they cannot.
+func getTopicNamespace(env *crd.ClowdEnvironment) (string, bool) {
+	switch env.Spec.Providers.Kafka.Mode {
+	case "operator", "app-interface":
+		return getKafkaNamespace(env), true
+	case "ephem-msk":
+		if env.Spec.Providers.Kafka.TopicNamespace == "" {
+			return env.Status.TargetNamespace, true
+		}
+		return env.Spec.Providers.Kafka.TopicNamespace, true
+	default:
+		return "", false
+	}
+}
+
+// topicsReadFromBrokers returns whether the live topics of the env's kafka mode are read from
+// the brokers, as there are no KafkaTopic resources for them.
+func topicsReadFromBrokers(env *crd.ClowdEnvironment) bool {
+	switch env.Spec.Providers.Kafka.Mode {
+	case "managed", "redpanda":
+		return true
+	default:
+		return false
+	}
+}
+
+// topicsOwnedByClowder returns whether Clowder writes the spec of the env's KafkaTopics. In
+// app-interface mode the topics are owned by app-interface.
+func topicsOwnedByClowder(env *crd.ClowdEnvironment) bool {
+	return env.Spec.Providers.Kafka.Mode != "app-interface"
+}
+
+// GetTopicDrift checks each of the app's topics against the live topic. The topics are those of
+// the app's generated config, so that the names match the ones the kafka provider used. When
+// Clowder writes the KafkaTopics, their spec is the declared one, and the topic operator keeps the
+// broker in line with it, so only what the operator reports in the status is checked. When the
+// KafkaTopics are owned by app-interface, the settings the apps in the environment declare are
+// compared with their spec as well. In the modes without KafkaTopics the declared settings are
+// compared with those the brokers report, read with the app's own credentials. The second return
+// value is false when the live topics cannot be read in the environment's kafka mode.
+func GetTopicDrift(ctx context.Context, c client.Client, env *crd.ClowdEnvironment, app *crd.ClowdApp, kafkaConfig *config.KafkaConfig) ([]TopicDrift, bool, error) {
+	if len(app.Spec.KafkaTopics) == 0 || kafkaConfig == nil {
+		return nil, false, nil
+	}
+
+	names := map[string]string{}
+	for _, topic := range kafkaConfig.Topics {
+		names[topic.RequestedName] = topic.Name
+	}
+
+	if topicsReadFromBrokers(env) {
+		return getBrokerTopicDrift(ctx, c, env, app, kafkaConfig.Brokers, names)
+	}
+
+	namespace, ok := getTopicNamespace(env)
+	if !ok {
+		return nil, false, nil
+	}
+
+	drift := []TopicDrift{}
+	for _, topic := range app.GetKafkaTopics() {
+		name, ok := names[topic.TopicName]
+		if !ok {
+			continue
+		}
+
+		live := &strimzi.KafkaTopic{}
+		if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, live); err != nil {
+			if k8serr.IsNotFound(err) {
+				continue
+			}
+			return nil, false, err
+		}
+
+		drift = append(drift, compareTopicStatus(name, live)...)
+
+		if topicsOwnedByClowder(env) {
+			continue
+		}
+
+		topicDrift, err := compareDeclaredTopic(ctx, c, env, topic, name, live)
+		if err != nil {
+			return nil, false, err
+		}
+		drift = append(drift, topicDrift...)
+	}
+
+	return drift, true, nil
+}
+
+// getBrokerTopicDrift compares the settings the apps in the environment declare for the app's
+// topics with those the brokers report. Redpanda topics are created with a single replica, so
+// their replicas are not compared.
+func getBrokerTopicDrift(ctx context.Context, c client.Client, env *crd.ClowdEnvironment, app *crd.ClowdApp, brokers []config.BrokerConfig, names map[string]string) ([]TopicDrift, bool, error) {
+	admin, err := NewKafkaAdmin(brokers)
+	if err != nil {
+		return nil, false, err
+	}
+	defer admin.Close()
+
+	liveNames := []string{}
+	for _, topic := range app.GetKafkaTopics() {
+		if name, ok := names[topic.TopicName]; ok {
+			liveNames = append(liveNames, name)
+		}
+	}
+
+	liveTopics, err := admin.DescribeTopics(ctx, liveNames)
+	if err != nil {
+		return nil, false, err
+	}
+
+	drift := []TopicDrift{}
+	for _, topic := range app.GetKafkaTopics() {
+		name, ok := names[topic.TopicName]
+		if !ok {
+			continue
+		}
+		liveTopic, ok := liveTopics[name]
+		if !ok {
+			continue
+		}
+
+		liveConfig, err := json.Marshal(liveTopic.Config)
+		if err != nil {
+			return nil, false, err
+		}
+
+		live := &strimzi.KafkaTopic{Spec: &strimzi.KafkaTopicSpec{
+			Partitions: &liveTopic.Partitions,
+			Replicas:   &liveTopic.Replicas,
+			Config:     &apiextensions.JSON{Raw: liveConfig},
+		}}
+		if env.Spec.Providers.Kafka.Mode == "redpanda" {
+			live.Spec.Replicas = nil
+		}
+
+		topicDrift, err := compareDeclaredTopic(ctx, c, env, topic, name, live)
+		if err != nil {
+			return nil, false, err
+		}
+		drift = append(drift, topicDrift...)
+	}
+
+	return drift, true, nil
+}
+
+// compareDeclaredTopic returns the drift between the settings the apps in the environment declare
+// for a topic, merged as they are when Clowder writes the topic, and the spec of the live topic.
+func compareDeclaredTopic(ctx context.Context, c client.Client, env *crd.ClowdEnvironment, topic crd.KafkaTopicSpec, name string, live *strimzi.KafkaTopic) ([]TopicDrift, error) {
+	appList, err := getAppsDeclaringTopic(ctx, c, env, topic.TopicName)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := &strimzi.KafkaTopic{Spec: &strimzi.KafkaTopicSpec{}}
+	if err := processTopicValues(declared, env, appList, topic); err != nil {
+		return nil, err
+	}
+
+	return compareTopicSpec(name, declared.Spec, live)
+}
+
+// compareTopicStatus returns the drift the topic operator reports for a topic, that is a Ready
+// condition that is false because the operator could not apply the topic to the broker.
+func compareTopicStatus(name string, live *strimzi.KafkaTopic) []TopicDrift {
+	drift := []TopicDrift{}
+	if live.Status == nil {
+		return drift
+	}
+
+	for _, condition := range live.Status.Conditions {
+		if condition.Type == nil || *condition.Type != "Ready" || condition.Status == nil || *condition.Status != "False" {
+			continue
+		}
+		message := ""
+		if condition.Message != nil {
+			message = *condition.Message
+		}
+		drift = append(drift, TopicDrift{Topic: name, Setting: "ready", Declared: "True", Live: fmt.Sprintf("False (%s)", message)})
+	}
+	return drift
+}
+
+// compareTopicSpec returns the drift between the topic the apps declare and the spec of a topic
+// owned by someone else. Replicas are only reported when there are fewer than declared, as Clowder
+// itself lowers the declared replicas to the size of small clusters.
+func compareTopicSpec(name string, declared *strimzi.KafkaTopicSpec, live *strimzi.KafkaTopic) ([]TopicDrift, error) {
+	drift := []TopicDrift{}
+
+	if live.Spec == nil {
+		return drift, nil
+	}
+
+	if declared.Partitions != nil && live.Spec.Partitions != nil && *declared.Partitions != *live.Spec.Partitions {
+		drift = append(drift, TopicDrift{
+			Topic:    name,
+			Setting:  "partitions",
+			Declared: fmt.Sprint(*declared.Partitions),
+			Live:     fmt.Sprint(*live.Spec.Partitions),
+		})
+	}
+
+	if declared.Replicas != nil && live.Spec.Replicas != nil && *live.Spec.Replicas < *declared.Replicas {
+		drift = append(drift, TopicDrift{
+			Topic:    name,
+			Setting:  "replicas",
+			Declared: fmt.Sprint(*declared.Replicas),
+			Live:     fmt.Sprint(*live.Spec.Replicas),
+		})
+	}
+
+	declaredConfig, err := topicConfigValues(declared)
+	if err != nil {
+		return nil, err
+	}
+	liveConfig, err := topicConfigValues(live.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key := range declaredConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		liveValue, ok := liveConfig[key]
+		if !ok {
+			liveValue = "unset"
+		}
+		if liveValue != declaredConfig[key] {
+			drift = append(drift, TopicDrift{Topic: name, Setting: key, Declared: declaredConfig[key], Live: liveValue})
+		}
+	}
+
+	return drift, nil
+}
+
+// topicConfigValues returns the config of a topic as strings, whether the values in the resource
+// are strings or numbers.
+func topicConfigValues(spec *strimzi.KafkaTopicSpec) (map[string]string, error) {
+	values := map[string]string{}
+	if spec.Config == nil || len(spec.Config.Raw) == 0 {
+		return values, nil
+	}
+
+	raw := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(spec.Config.Raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	for key, value := range raw {
+		values[key] = fmt.Sprint(value)
+	}
+	return values, nil
+}
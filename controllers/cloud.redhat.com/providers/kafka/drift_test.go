@@ -0,0 +1,227 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func TestGetTopicDrift(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, strimzi.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Spec.Providers.Kafka.Mode = "app-interface"
+	env.Spec.Providers.Kafka.Cluster.Namespace = "platform-mq"
+
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+	app.Namespace = "app-ns"
+	app.Spec.EnvName = "env"
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{
+		{TopicName: "events", Partitions: 6, Replicas: 3, Config: map[string]string{"retention.ms": "86400000"}},
+		{TopicName: "ingress", Partitions: 3},
+		{TopicName: "missing"},
+	}
+
+	other := &crd.ClowdApp{}
+	other.Name = "other"
+	other.Namespace = "other-ns"
+	other.Spec.EnvName = "env"
+	other.Spec.KafkaTopics = []crd.KafkaTopicSpec{{TopicName: "ingress", Partitions: 12}}
+
+	events := &strimzi.KafkaTopic{}
+	events.Name = "events"
+	events.Namespace = "platform-mq"
+	events.Spec = &strimzi.KafkaTopicSpec{
+		Partitions: utils.Int32Ptr(3),
+		Replicas:   utils.Int32Ptr(3),
+		Config:     &apiextensions.JSON{Raw: []byte(`{"retention.ms": 604800000}`)},
+	}
+
+	ingress := &strimzi.KafkaTopic{}
+	ingress.Name = "ingress"
+	ingress.Namespace = "platform-mq"
+	ingress.Spec = &strimzi.KafkaTopicSpec{Partitions: utils.Int32Ptr(12), Replicas: utils.Int32Ptr(3)}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(app, other, events, ingress).
+		WithIndex(&crd.ClowdApp{}, AppTopicIndex, AppTopicIndexValues).
+		Build()
+
+	topics := &config.KafkaConfig{Topics: []config.TopicConfig{
+		{Name: "events", RequestedName: "events"},
+		{Name: "ingress", RequestedName: "ingress"},
+		{Name: "missing", RequestedName: "missing"},
+	}}
+
+	drift, checked, err := GetTopicDrift(context.Background(), c, env, app, topics)
+	require.NoError(t, err)
+	assert.True(t, checked)
+	assert.Equal(t, []TopicDrift{
+		{Topic: "events", Setting: "partitions", Declared: "6", Live: "3"},
+		{Topic: "events", Setting: "retention.ms", Declared: "86400000", Live: "604800000"},
+	}, drift, "partitions merged across apps and replicas above the declared ones are not drift")
+
+	env.Spec.Providers.Kafka.Mode = "operator"
+	drift, checked, err = GetTopicDrift(context.Background(), c, env, app, topics)
+	require.NoError(t, err)
+	assert.True(t, checked)
+	assert.Empty(t, drift, "the spec of topics Clowder writes is not compared with what it declares")
+
+	ingress.Status = &strimzi.KafkaTopicStatus{
+		Conditions: []strimzi.KafkaTopicStatusConditionsElem{{
+			Type:    utils.StringPtr("Ready"),
+			Status:  utils.StringPtr("False"),
+			Message: utils.StringPtr("Decreasing partitions not supported"),
+		}},
+	}
+	require.NoError(t, c.Update(context.Background(), ingress))
+	drift, _, err = GetTopicDrift(context.Background(), c, env, app, topics)
+	require.NoError(t, err)
+	assert.Equal(t, []TopicDrift{
+		{Topic: "ingress", Setting: "ready", Declared: "True", Live: "False (Decreasing partitions not supported)"},
+	}, drift, "what the topic operator reports is")
+
+	env.Spec.Providers.Kafka.Mode = "none"
+	drift, checked, err = GetTopicDrift(context.Background(), c, env, app, topics)
+	require.NoError(t, err)
+	assert.False(t, checked, "there are no topics to read without kafka")
+	assert.Empty(t, drift)
+}
+
+// fakeKafkaAdmin serves the live topics and consumer group lags of a test.
+type fakeKafkaAdmin struct {
+	topics map[string]LiveTopic
+	groups map[string]map[string]int64
+	closed bool
+}
+
+func (f *fakeKafkaAdmin) DescribeTopics(_ context.Context, names []string) (map[string]LiveTopic, error) {
+	topics := map[string]LiveTopic{}
+	for _, name := range names {
+		if topic, ok := f.topics[name]; ok {
+			topics[name] = topic
+		}
+	}
+	return topics, nil
+}
+
+func (f *fakeKafkaAdmin) ListGroups(_ context.Context) ([]string, error) {
+	groups := []string{}
+	for group := range f.groups {
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (f *fakeKafkaAdmin) GroupLag(_ context.Context, groups []string) (map[string]map[string]int64, error) {
+	lags := map[string]map[string]int64{}
+	for _, group := range groups {
+		lags[group] = f.groups[group]
+	}
+	return lags, nil
+}
+
+func (f *fakeKafkaAdmin) Close() {
+	f.closed = true
+}
+
+// mockKafkaAdmin replaces NewKafkaAdmin with one returning admin.
+func mockKafkaAdmin(t *testing.T, admin KafkaAdmin) {
+	t.Helper()
+
+	original := NewKafkaAdmin
+	NewKafkaAdmin = func([]config.BrokerConfig) (KafkaAdmin, error) {
+		return admin, nil
+	}
+	t.Cleanup(func() { NewKafkaAdmin = original })
+}
+
+func TestGetTopicDriftFromBrokers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Spec.Providers.Kafka.Mode = "managed"
+	env.Spec.Providers.Kafka.Cluster.Replicas = 3
+
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+	app.Namespace = "app-ns"
+	app.Spec.EnvName = "env"
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{
+		{TopicName: "events", Partitions: 6, Replicas: 3, Config: map[string]string{"retention.ms": "86400000"}},
+		{TopicName: "missing"},
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(app).
+		WithIndex(&crd.ClowdApp{}, AppTopicIndex, AppTopicIndexValues).
+		Build()
+
+	admin := &fakeKafkaAdmin{topics: map[string]LiveTopic{
+		"prefix-events": {Partitions: 3, Replicas: 1, Config: map[string]string{"retention.ms": "86400000", "cleanup.policy": "delete"}},
+	}}
+	mockKafkaAdmin(t, admin)
+
+	topics := &config.KafkaConfig{
+		Brokers: []config.BrokerConfig{{Hostname: "kafka.example.com"}},
+		Topics: []config.TopicConfig{
+			{Name: "prefix-events", RequestedName: "events"},
+			{Name: "prefix-missing", RequestedName: "missing"},
+		},
+	}
+
+	drift, checked, err := GetTopicDrift(context.Background(), c, env, app, topics)
+	require.NoError(t, err)
+	assert.True(t, checked)
+	assert.Equal(t, []TopicDrift{
+		{Topic: "prefix-events", Setting: "partitions", Declared: "6", Live: "3"},
+		{Topic: "prefix-events", Setting: "replicas", Declared: "3", Live: "1"},
+	}, drift)
+	assert.True(t, admin.closed)
+
+	env.Spec.Providers.Kafka.Mode = "redpanda"
+	drift, _, err = GetTopicDrift(context.Background(), c, env, app, topics)
+	require.NoError(t, err)
+	assert.Equal(t, []TopicDrift{
+		{Topic: "prefix-events", Setting: "partitions", Declared: "6", Live: "3"},
+	}, drift, "redpanda topics have a single replica")
+}
+
+func TestCompareTopicStatus(t *testing.T) {
+	live := &strimzi.KafkaTopic{
+		Spec: &strimzi.KafkaTopicSpec{},
+		Status: &strimzi.KafkaTopicStatus{
+			Conditions: []strimzi.KafkaTopicStatusConditionsElem{{
+				Type:    utils.StringPtr("Ready"),
+				Status:  utils.StringPtr("False"),
+				Message: utils.StringPtr("Decreasing partitions not supported"),
+			}},
+		},
+	}
+
+	drift := compareTopicStatus("events", live)
+	assert.Equal(t, []TopicDrift{{Topic: "events", Setting: "ready", Declared: "True", Live: "False (Decreasing partitions not supported)"}}, drift)
+	assert.Equal(t, "events ready: declared True, live False (Decreasing partitions not supported)", drift[0].String())
+}
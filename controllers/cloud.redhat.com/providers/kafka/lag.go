@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	promapi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+)
+
+// ConsumerLag is the lag, in messages, of one of an app's consumer groups on a topic.
+type ConsumerLag struct {
+	Group string
+	Topic string
+	Lag   int64
+}
+
+func (l ConsumerLag) String() string {
+	return fmt.Sprintf("%s on %s: %d", l.Group, l.Topic, l.Lag)
+}
+
+// LagQuerier runs an instant PromQL query against the Prometheus at address.
+type LagQuerier func(ctx context.Context, address, query string) (model.Vector, error)
+
+// QueryPrometheus is the LagQuerier that queries a Prometheus over its HTTP API.
+func QueryPrometheus(ctx context.Context, address, query string) (model.Vector, error) {
+	c, err := promapi.NewClient(promapi.Config{Address: address})
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err := promv1.NewAPI(c).Query(ctx, query, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	vector, ok := result.(model.Vector)
+	if !ok {
+		return nil, fmt.Errorf("expected a vector from prometheus but got %s", result.Type())
+	}
+	return vector, nil
+}
+
+// GetConsumerLagPrometheusURL returns the URL of the Prometheus the consumer lag of the apps in
+// the environment is read from, and false when it is read from the brokers.
+func GetConsumerLagPrometheusURL(env *crd.ClowdEnvironment) (string, bool) {
+	url := env.Spec.Providers.Kafka.ConsumerLag.PrometheusURL
+	return url, url != ""
+}
+
+// GetConsumerLag returns the lag of the consumer groups declared on the app's topics, on those
+// topics, summed over the partitions of each topic and sorted by group and topic, as a Kafka
+// Exporter reports it to the Prometheus at address. As the app's ACLs do, a declared group also
+// covers the groups whose names start with it. The topics are those of the app's generated config,
+// so that the names match the ones the app consumes from.
+func GetConsumerLag(ctx context.Context, query LagQuerier, address string, app *crd.ClowdApp, topics []config.TopicConfig) ([]ConsumerLag, error) {
+	groups, names := appConsumers(app, topics)
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	vector, err := query(ctx, address, consumerLagQuery(groups, names))
+	if err != nil {
+		return nil, err
+	}
+
+	lags := []ConsumerLag{}
+	for _, sample := range vector {
+		lags = append(lags, ConsumerLag{
+			Group: string(sample.Metric["consumergroup"]),
+			Topic: string(sample.Metric["topic"]),
+			Lag:   int64(sample.Value),
+		})
+	}
+
+	sortConsumerLags(lags)
+	return lags, nil
+}
+
+// GetConsumerLagFromKafka returns the same lag as GetConsumerLag, computed from the committed
+// offsets of the groups and the end offsets of the topics that the brokers report.
+func GetConsumerLagFromKafka(ctx context.Context, admin KafkaAdmin, app *crd.ClowdApp, topics []config.TopicConfig) ([]ConsumerLag, error) {
+	groups, names := appConsumers(app, topics)
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	live, err := admin.ListGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []string{}
+	for _, group := range live {
+		for _, prefix := range groups {
+			if strings.HasPrefix(group, prefix) {
+				matched = append(matched, group)
+				break
+			}
+		}
+	}
+
+	lags := []ConsumerLag{}
+	if len(matched) == 0 {
+		return lags, nil
+	}
+
+	groupLags, err := admin.GroupLag(ctx, matched)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	for group, topicLags := range groupLags {
+		for topic, lag := range topicLags {
+			if wanted[topic] {
+				lags = append(lags, ConsumerLag{Group: group, Topic: topic, Lag: lag})
+			}
+		}
+	}
+
+	sortConsumerLags(lags)
+	return lags, nil
+}
+
+func sortConsumerLags(lags []ConsumerLag) {
+	sort.Slice(lags, func(i, j int) bool {
+		if lags[i].Group != lags[j].Group {
+			return lags[i].Group < lags[j].Group
+		}
+		return lags[i].Topic < lags[j].Topic
+	})
+}
+
+// appConsumers returns the consumer groups declared on the app's topics and the names of those
+// topics in the app's config, both sorted. Topics that are not in the config yet are left out.
+func appConsumers(app *crd.ClowdApp, topics []config.TopicConfig) ([]string, []string) {
+	resolved := map[string]string{}
+	for _, topic := range topics {
+		resolved[topic.RequestedName] = topic.Name
+	}
+
+	seenGroups, seenNames := map[string]bool{}, map[string]bool{}
+	groups, names := []string{}, []string{}
+	for _, topic := range app.GetKafkaTopics() {
+		name, ok := resolved[topic.TopicName]
+		if !ok || len(topic.ConsumerGroups) == 0 {
+			continue
+		}
+		if !seenNames[name] {
+			seenNames[name] = true
+			names = append(names, name)
+		}
+		for _, group := range topic.ConsumerGroups {
+			if !seenGroups[group] {
+				seenGroups[group] = true
+				groups = append(groups, group)
+			}
+		}
+	}
+	sort.Strings(groups)
+	sort.Strings(names)
+	return groups, names
+}
+
+func consumerLagQuery(groups []string, topics []string) string {
+	patterns := []string{}
+	for _, group := range groups {
+		patterns = append(patterns, regexp.QuoteMeta(group)+".*")
+	}
+	names := []string{}
+	for _, topic := range topics {
+		names = append(names, regexp.QuoteMeta(topic))
+	}
+	return fmt.Sprintf(
+		"sum by (consumergroup, topic) (kafka_consumergroup_lag{consumergroup=~%s,topic=~%s})",
+		strconv.Quote(strings.Join(patterns, "|")),
+		strconv.Quote(strings.Join(names, "|")),
+	)
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+)
+
+func TestGetConsumerLag(t *testing.T) {
+	app := &crd.ClowdApp{}
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{
+		{TopicName: "ingress", Role: crd.KafkaTopicRoleProduce},
+		{TopicName: "events", ConsumerGroups: []string{"myapp.processor"}, DeadLetter: &crd.KafkaDeadLetterSpec{}},
+		{TopicName: "audit", ConsumerGroups: []string{"myapp-audit", "myapp.processor"}},
+	}
+
+	topics := []config.TopicConfig{
+		{Name: "ingress-env", RequestedName: "ingress"},
+		{Name: "events-env", RequestedName: "events"},
+		{Name: "audit-env", RequestedName: "audit"},
+	}
+
+	var address, query string
+	querier := func(_ context.Context, a, q string) (model.Vector, error) {
+		address, query = a, q
+		return model.Vector{
+			{Metric: model.Metric{"consumergroup": "myapp.processor", "topic": "events-env"}, Value: 1200},
+			{Metric: model.Metric{"consumergroup": "myapp-audit", "topic": "audit-env"}, Value: 3},
+			{Metric: model.Metric{"consumergroup": "myapp.processor", "topic": "audit-env"}, Value: 0},
+		}, nil
+	}
+
+	lags, err := GetConsumerLag(context.Background(), querier, "http://prometheus:9090", app, topics)
+	require.NoError(t, err)
+	assert.Equal(t, "http://prometheus:9090", address)
+	assert.Equal(t, `sum by (consumergroup, topic) (kafka_consumergroup_lag{consumergroup=~"myapp-audit.*|myapp\\.processor.*",topic=~"audit-env|events-env"})`, query)
+	assert.Equal(t, []ConsumerLag{
+		{Group: "myapp-audit", Topic: "audit-env", Lag: 3},
+		{Group: "myapp.processor", Topic: "audit-env", Lag: 0},
+		{Group: "myapp.processor", Topic: "events-env", Lag: 1200},
+	}, lags)
+	assert.Equal(t, "myapp.processor on events-env: 1200", lags[2].String())
+
+	_, err = GetConsumerLag(context.Background(), querier, "http://prometheus:9090", app, topics[:2])
+	require.NoError(t, err)
+	assert.Contains(t, query, `topic=~"events-env"`, "topics not in the app's config yet are left out")
+
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{{TopicName: "ingress"}}
+	lags, err = GetConsumerLag(context.Background(), func(context.Context, string, string) (model.Vector, error) {
+		t.Fatal("apps without consumer groups are not queried")
+		return nil, nil
+	}, "http://prometheus:9090", app, topics)
+	require.NoError(t, err)
+	assert.Empty(t, lags)
+}
+
+func TestGetConsumerLagFromKafka(t *testing.T) {
+	app := &crd.ClowdApp{}
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{
+		{TopicName: "events", ConsumerGroups: []string{"myapp.processor"}},
+		{TopicName: "audit", ConsumerGroups: []string{"myapp-audit"}},
+	}
+
+	topics := []config.TopicConfig{
+		{Name: "events-env", RequestedName: "events"},
+		{Name: "audit-env", RequestedName: "audit"},
+	}
+
+	admin := &fakeKafkaAdmin{groups: map[string]map[string]int64{
+		"myapp.processor":      {"events-env": 1200, "other-env": 5},
+		"myapp.processor-blue": {"events-env": 7},
+		"myapp-audit":          {"audit-env": 3},
+		"otherapp":             {"events-env": 99},
+	}}
+
+	lags, err := GetConsumerLagFromKafka(context.Background(), admin, app, topics)
+	require.NoError(t, err)
+	assert.Equal(t, []ConsumerLag{
+		{Group: "myapp-audit", Topic: "audit-env", Lag: 3},
+		{Group: "myapp.processor", Topic: "events-env", Lag: 1200},
+		{Group: "myapp.processor-blue", Topic: "events-env", Lag: 7},
+	}, lags, "groups starting with a declared group are covered, other groups and topics are not")
+
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{{TopicName: "events"}}
+	lags, err = GetConsumerLagFromKafka(context.Background(), admin, app, topics)
+	require.NoError(t, err)
+	assert.Empty(t, lags)
+}
+
+func TestGetConsumerLagPrometheusURL(t *testing.T) {
+	env := &crd.ClowdEnvironment{}
+	env.Spec.Providers.Kafka.ConsumerLag.Enabled = true
+
+	_, ok := GetConsumerLagPrometheusURL(env)
+	assert.False(t, ok, "the lag is read from the brokers unless a Prometheus is given")
+
+	env.Spec.Providers.Kafka.ConsumerLag.PrometheusURL = "https://thanos.example.com"
+	url, ok := GetConsumerLagPrometheusURL(env)
+	assert.True(t, ok)
+	assert.Equal(t, "https://thanos.example.com", url)
+}
@@ -50,7 +50,7 @@ func (k *managedKafkaProvider) Provide(app *crd.ClowdApp) error {
 
 	k.Config.Kafka = k.getKafkaConfig(brokers, app)
 
-	return nil
+	return setExternalSchemaRegistryConfig(&k.Provider, k.Config.Kafka)
 }
 
 func (k *managedKafkaProvider) appendTopic(topic crd.KafkaTopicSpec, kafkaConfig *config.KafkaConfig) {
@@ -64,8 +64,9 @@ func (k *managedKafkaProvider) appendTopic(topic crd.KafkaTopicSpec, kafkaConfig
 	kafkaConfig.Topics = append(
 		kafkaConfig.Topics,
 		config.TopicConfig{
-			Name:          topicName,
-			RequestedName: topic.TopicName,
+			Name:           topicName,
+			RequestedName:  topic.TopicName,
+			ConsumerGroups: topic.ConsumerGroups,
 		},
 	)
 }
@@ -135,9 +136,10 @@ func (k *managedKafkaProvider) getKafkaConfig(brokers []config.BrokerConfig, app
 	kafkaConfig.Brokers = brokers
 	kafkaConfig.Topics = []config.TopicConfig{}
 
-	for _, topic := range app.Spec.KafkaTopics {
+	for _, topic := range app.GetKafkaTopics() {
 		k.appendTopic(topic, kafkaConfig)
 	}
+	linkCompanionTopics(app, kafkaConfig.Topics)
 
 	return kafkaConfig
 
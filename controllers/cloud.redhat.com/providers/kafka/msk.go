@@ -14,6 +14,7 @@ import (
 	core "k8s.io/api/core/v1"
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
@@ -64,8 +65,10 @@ func (s *mskProvider) EnvProvide() error {
 		return err
 	}
 
-	if err := s.createConnectSecret(); err != nil {
-		return err
+	if !usesIAMAuth(s.Config.Kafka.Brokers) {
+		if err := s.createConnectSecret(); err != nil {
+			return err
+		}
 	}
 
 	kafkaCASecName := crd.NamespacedName{
@@ -160,7 +163,7 @@ func (s *mskProvider) createConnectSecret() error {
 }
 
 func (s *mskProvider) Provide(app *crd.ClowdApp) error {
-	if len(app.Spec.KafkaTopics) == 0 {
+	if len(app.Spec.KafkaTopics) == 0 && len(app.Spec.KafkaConnectors) == 0 {
 		return nil
 	}
 
@@ -172,6 +175,14 @@ func (s *mskProvider) Provide(app *crd.ClowdApp) error {
 		return err
 	}
 
+	if usesIAMAuth(s.Config.Kafka.Brokers) && (app.Spec.Cyndi.Enabled || len(app.Spec.KafkaConnectors) > 0) {
+		return errors.NewClowderError("cyndi and kafkaConnectors need kafka connect, which is not available with IAM authenticated MSK brokers")
+	}
+
+	if err := setExternalSchemaRegistryConfig(&s.Provider, s.Config.Kafka); err != nil {
+		return err
+	}
+
 	if err := processTopics(s, app); err != nil {
 		return err
 	}
@@ -220,6 +231,8 @@ func (s mskProvider) connectConfig(config *apiextensions.JSON) error {
 		"offset.storage.topic":              fmt.Sprintf("%v-connect-cluster-offsets", s.Env.Name),
 		"status.storage.replication.factor": strconv.Itoa(replicas),
 		"status.storage.topic":              fmt.Sprintf("%v-connect-cluster-status", s.Env.Name),
+		"config.providers":                  "secrets",
+		"config.providers.secrets.class":    "io.strimzi.kafka.KubernetesSecretConfigProvider",
 	}
 
 	byteData, err := json.Marshal(connectConfig)
@@ -275,6 +288,18 @@ func (s *mskProvider) configureBrokers() error {
 		return clowdErr
 	}
 
+	if usesIAMAuth(s.Config.Kafka.Brokers) {
+		// Strimzi only authenticates Kafka Connect with the built in types and drops the sasl.*
+		// and security.* options from its config. The custom type of newer releases is not in
+		// the Strimzi API Clowder is built against, which rejects a KafkaConnect using it.
+		s.Log.Info("Not provisioning kafka connect cluster, it is not supported with IAM authentication")
+		if recorder, ok := s.Ctx.Value(errors.ClowdKey("recorder")).(*record.EventRecorder); ok && recorder != nil && *recorder != nil {
+			(*recorder).Eventf(s.Env, core.EventTypeWarning, "KafkaConnectUnavailable",
+				"Kafka Connect is not provisioned for IAM authenticated MSK brokers, apps using cyndi or kafkaConnectors will fail to reconcile")
+		}
+		return nil
+	}
+
 	if err := configureKafkaConnectCluster(s); err != nil {
 		return errors.Wrap("failed to provision kafka connect cluster", err)
 	}
@@ -291,9 +316,16 @@ func (s *mskProvider) getKafkaConnectTrustedCertSecretName() (string, error) {
 }
 
 func (s *mskProvider) getConnectClusterUserName() string {
+	if usesIAMAuth(s.Config.Kafka.Brokers) {
+		return ""
+	}
 	return *s.Config.Kafka.Brokers[0].Sasl.Username
 }
 
+func (s *mskProvider) getConnectClusterPasswordSecret() (string, string) {
+	return s.getConnectClusterUserName(), "password"
+}
+
 func (s *mskProvider) KafkaTopicName(topic crd.KafkaTopicSpec, _ ...string) (string, error) {
 	return fmt.Sprintf("%s-%s", s.Env.Name, topic.TopicName), nil
 }
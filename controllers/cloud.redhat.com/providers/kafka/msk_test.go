@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+)
+
+func TestGetBrokerConfigIAM(t *testing.T) {
+	secret := &core.Secret{Data: map[string][]byte{
+		"hostnames": []byte("b-1.msk,b-2.msk"),
+		"port":      []byte("9098"),
+		"authtype":  []byte("iam"),
+		"region":    []byte("us-east-1"),
+		"roleArn":   []byte("arn:aws:iam::123456789012:role/app"),
+	}}
+
+	brokers, err := getBrokerConfig(secret)
+	require.NoError(t, err)
+	require.Len(t, brokers, 2)
+	assert.True(t, usesIAMAuth(brokers))
+	for _, broker := range brokers {
+		assert.Equal(t, config.BrokerConfigAuthtypeIam, *broker.Authtype)
+		assert.Nil(t, broker.Sasl, "IAM brokers carry no password")
+		assert.Equal(t, "SASL_SSL", *broker.SecurityProtocol)
+		assert.Equal(t, "us-east-1", broker.Iam.Region)
+		assert.Equal(t, "arn:aws:iam::123456789012:role/app", *broker.Iam.RoleArn)
+		assert.Equal(t, "OAUTHBEARER", *broker.Iam.SaslMechanism)
+	}
+
+	delete(secret.Data, "region")
+	_, err = getBrokerConfig(secret)
+	assert.Error(t, err, "the region is required")
+
+	delete(secret.Data, "authtype")
+	brokers, err = getBrokerConfig(secret)
+	require.NoError(t, err)
+	assert.False(t, usesIAMAuth(brokers))
+	assert.Equal(t, config.BrokerConfigAuthtypeSasl, *brokers[0].Authtype)
+	assert.Equal(t, "PLAIN", *brokers[0].Sasl.SaslMechanism)
+}
+
+func TestMSKIAMHasNoConnect(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, strimzi.AddToScheme(scheme))
+
+	secret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "msk", Namespace: "secrets"},
+		Data: map[string][]byte{
+			"hostnames": []byte("b-1.msk"),
+			"port":      []byte("9098"),
+			"authtype":  []byte("iam"),
+			"region":    []byte("us-east-1"),
+		},
+	}
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Status.TargetNamespace = "env-ns"
+	env.Spec.Providers.Kafka.Mode = "ephem-msk"
+	env.Spec.Providers.Kafka.ManagedSecretRef = crd.NamespacedName{Name: "msk", Namespace: "secrets"}
+
+	var recorder record.EventRecorder = record.NewFakeRecorder(1)
+	ctx := context.WithValue(context.Background(), errors.ClowdKey("recorder"), &recorder)
+	log := logr.Discard()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+	cache := rc.NewObjectCache(ctx, c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+	hc := hashcache.NewHashCache()
+
+	s := mskProvider{Provider: providers.Provider{
+		Ctx:       ctx,
+		Client:    c,
+		Env:       env,
+		Cache:     &cache,
+		HashCache: &hc,
+		Config:    &config.AppConfig{},
+	}}
+	require.NoError(t, s.configureBrokers())
+	assert.Error(t, cache.Get(KafkaConnect, &strimzi.KafkaConnect{}), "no kafka connect cluster is provisioned")
+	assert.Contains(t, <-recorder.(*record.FakeRecorder).Events, "KafkaConnectUnavailable", "the env is told why")
+
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+	app.Namespace = "app-ns"
+	app.Spec.KafkaConnectors = []crd.KafkaConnectorSpec{{Name: "sink", Class: "io.confluent.connect.s3.S3SinkConnector"}}
+	assert.ErrorContains(t, s.Provide(app), "not available with IAM")
+}
@@ -30,6 +30,7 @@ type providerInterface interface {
 	KafkaName() string
 	KafkaNamespace() string
 	getConnectClusterUserName() string
+	getConnectClusterPasswordSecret() (string, string)
 	getBootstrapServersString() string
 	connectConfig(*apiextensions.JSON) error
 	getKafkaConnectTrustedCertSecretName() (string, error)
@@ -76,6 +77,8 @@ func GetKafka(c *providers.Provider) (providers.ClowderProvider, error) {
 		return NewManagedKafka(c)
 	case "ephem-msk":
 		return NewMSK(c)
+	case "redpanda":
+		return NewRedpanda(c)
 	case "none", "":
 		return NewNoneKafka(c)
 	default:
@@ -133,7 +136,7 @@ func processTopics(s providerInterface, app *crd.ClowdApp) error {
 		return errors.Wrap("Topic creation failed: Error listing apps", err)
 	}
 
-	for _, topic := range app.Spec.KafkaTopics {
+	for _, topic := range app.GetKafkaTopics() {
 		k := &strimzi.KafkaTopic{}
 
 		topicName, err := s.KafkaTopicName(topic, app.Namespace)
@@ -175,15 +178,41 @@ func processTopics(s providerInterface, app *crd.ClowdApp) error {
 
 		topicConfig = append(
 			topicConfig,
-			config.TopicConfig{Name: topicName, RequestedName: topic.TopicName},
+			config.TopicConfig{Name: topicName, RequestedName: topic.TopicName, ConsumerGroups: topic.ConsumerGroups},
 		)
 	}
 
+	linkCompanionTopics(app, topicConfig)
 	s.GetConfig().Kafka.Topics = topicConfig
 
 	return nil
 }
 
+// linkCompanionTopics points the config of each topic with a deadLetter option at the actual names
+// of its retry and dead-letter topics, so that clients need not rely on naming conventions.
+func linkCompanionTopics(app *crd.ClowdApp, topics []config.TopicConfig) {
+	names := map[string]string{}
+	for _, topic := range topics {
+		names[topic.RequestedName] = topic.Name
+	}
+
+	for _, spec := range app.Spec.KafkaTopics {
+		if spec.DeadLetter == nil {
+			continue
+		}
+		for i := range topics {
+			if topics[i].RequestedName != spec.TopicName {
+				continue
+			}
+			for tier := int32(1); tier <= spec.DeadLetter.RetryTopics; tier++ {
+				topics[i].RetryTopics = append(topics[i].RetryTopics, names[spec.RetryTopicName(tier)])
+			}
+			deadLetterTopic := names[spec.DeadLetterTopicName()]
+			topics[i].DeadLetterTopic = &deadLetterTopic
+		}
+	}
+}
+
 func processTopicValues(
 	k *strimzi.KafkaTopic,
 	env *crd.ClowdEnvironment,
@@ -197,7 +226,7 @@ func processTopicValues(
 
 	for _, iapp := range appList.Items {
 		if iapp.Spec.KafkaTopics != nil {
-			for _, itopic := range iapp.Spec.KafkaTopics {
+			for _, itopic := range iapp.GetKafkaTopics() {
 				if itopic.TopicName != topic.TopicName {
 					// Only consider a topic that matches the name
 					continue
@@ -355,8 +384,6 @@ func configureKafkaConnectCluster(s providerInterface) error {
 		return fmt.Errorf("could not unmarshal config: %w", err)
 	}
 
-	username := s.getConnectClusterUserName()
-
 	k.Spec = &strimzi.KafkaConnectSpec{
 		Replicas:         &replicas,
 		BootstrapServers: s.getBootstrapServersString(),
@@ -390,10 +417,12 @@ func configureKafkaConnectCluster(s providerInterface) error {
 				SecretName:  secName,
 			}},
 		}
+		username := s.getConnectClusterUserName()
+		secretName, passwordKey := s.getConnectClusterPasswordSecret()
 		k.Spec.Authentication = &strimzi.KafkaConnectSpecAuthentication{
 			PasswordSecret: &strimzi.KafkaConnectSpecAuthenticationPasswordSecret{
-				Password:   "password",
-				SecretName: username,
+				Password:   passwordKey,
+				SecretName: secretName,
 			},
 			Type:     "scram-sha-512",
 			Username: &username,
@@ -467,21 +496,32 @@ func getBrokerConfig(secret *core.Secret) ([]config.BrokerConfig, error) {
 		hostnames = append(hostnames, hostname)
 	}
 
+	iamConfig, err := destructureIAMSecret(secret)
+	if err != nil {
+		return brokers, err
+	}
+
 	saslType := config.BrokerConfigAuthtypeSasl
+	iamType := config.BrokerConfigAuthtypeIam
 
 	for _, hostname := range hostnames {
 		broker := config.BrokerConfig{}
 		broker.Hostname = hostname
 		broker.Port = &port
-		broker.Authtype = &saslType
 		if cacert != "" {
 			broker.Cacert = &cacert
 		}
-		broker.Sasl = &config.KafkaSASLConfig{
-			Password:         &password,
-			Username:         &username,
-			SecurityProtocol: utils.StringPtr("SASL_SSL"),
-			SaslMechanism:    utils.StringPtr(saslMechanism),
+		if iamConfig != nil {
+			broker.Authtype = &iamType
+			broker.Iam = iamConfig
+		} else {
+			broker.Authtype = &saslType
+			broker.Sasl = &config.KafkaSASLConfig{
+				Password:         &password,
+				Username:         &username,
+				SecurityProtocol: utils.StringPtr("SASL_SSL"),
+				SaslMechanism:    utils.StringPtr(saslMechanism),
+			}
 		}
 		broker.SecurityProtocol = utils.StringPtr("SASL_SSL")
 		brokers = append(brokers, broker)
@@ -490,6 +530,34 @@ func getBrokerConfig(secret *core.Secret) ([]config.BrokerConfig, error) {
 	return brokers, nil
 }
 
+// destructureIAMSecret returns the IAM config of a secret whose 'authtype' key is 'iam', and nil
+// for the secrets of brokers that use SASL usernames and passwords.
+func destructureIAMSecret(secret *core.Secret) (*config.KafkaIAMConfig, error) {
+	if string(secret.Data["authtype"]) != string(config.BrokerConfigAuthtypeIam) {
+		return nil, nil
+	}
+
+	region := string(secret.Data["region"])
+	if region == "" {
+		return nil, errors.NewClowderError("no region defined in the secret for IAM authenticated Kafka")
+	}
+
+	iamConfig := &config.KafkaIAMConfig{
+		Region:        region,
+		SaslMechanism: utils.StringPtr("OAUTHBEARER"),
+	}
+	if val, ok := secret.Data["roleArn"]; ok && len(val) > 0 {
+		iamConfig.RoleArn = utils.StringPtr(string(val))
+	}
+	return iamConfig, nil
+}
+
+// usesIAMAuth returns true when the brokers authenticate clients with AWS IAM rather than SASL
+// usernames and passwords.
+func usesIAMAuth(brokers []config.BrokerConfig) bool {
+	return len(brokers) > 0 && brokers[0].Authtype != nil && *brokers[0].Authtype == config.BrokerConfigAuthtypeIam
+}
+
 func destructureSecret(secret *core.Secret) (int, string, string, string, []string, string, string, error) {
 	port, err := strconv.Atoi(string(secret.Data["port"]))
 	if err != nil {
@@ -0,0 +1,377 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/clowderconfig"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	obj "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/object"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+	provutils "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/utils"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+// DefaultImageRedpanda defines the default Redpanda image
+var DefaultImageRedpanda = "docker.redpanda.com/redpandadata/redpanda:v24.2.7"
+
+const redpandaKafkaPort = 9092
+const redpandaSchemaRegistryPort = 8081
+const redpandaAdminPort = 9644
+
+// RedpandaDeployment is the resource ident for the Redpanda deployment.
+var RedpandaDeployment = rc.NewSingleResourceIdent(ProvName, "redpanda_deployment", &apps.Deployment{})
+
+// RedpandaService is the resource ident for the Redpanda service.
+var RedpandaService = rc.NewSingleResourceIdent(ProvName, "redpanda_service", &core.Service{})
+
+// RedpandaPVC is the resource ident for the Redpanda PVC.
+var RedpandaPVC = rc.NewSingleResourceIdent(ProvName, "redpanda_pvc", &core.PersistentVolumeClaim{})
+
+// RedpandaTopicsJob is the resource ident for the job creating an app's topics in Redpanda.
+var RedpandaTopicsJob = rc.NewSingleResourceIdent(ProvName, "redpanda_topics_job", &batch.Job{})
+
+type redpandaProvider struct {
+	providers.Provider
+}
+
+// NewRedpanda returns a new redpanda provider object.
+func NewRedpanda(p *providers.Provider) (providers.ClowderProvider, error) {
+	p.Cache.AddPossibleGVKFromIdent(
+		RedpandaDeployment,
+		RedpandaService,
+		RedpandaPVC,
+		RedpandaTopicsJob,
+	)
+	return &redpandaProvider{Provider: *p}, nil
+}
+
+// GetRedpandaImage returns the Redpanda image used for the broker and the topic jobs
+func GetRedpandaImage() string {
+	if clowderconfig.LoadedConfig.Images.KafkaRedpanda != "" {
+		return clowderconfig.LoadedConfig.Images.KafkaRedpanda
+	}
+	return DefaultImageRedpanda
+}
+
+func getRedpandaHostname(env *crd.ClowdEnvironment) string {
+	nn := providers.GetNamespacedName(env, "redpanda")
+	return fmt.Sprintf("%s.%s.svc", nn.Name, nn.Namespace)
+}
+
+func (r *redpandaProvider) EnvProvide() error {
+	objList := []rc.ResourceIdent{
+		RedpandaDeployment,
+		RedpandaService,
+	}
+
+	usePVC := r.Env.Spec.Providers.Kafka.PVC
+	if usePVC {
+		objList = append(objList, RedpandaPVC)
+	}
+
+	return providers.CachedMakeComponent(r, objList, r.Env, "redpanda", makeLocalRedpanda, usePVC)
+}
+
+func (r *redpandaProvider) Provide(app *crd.ClowdApp) error {
+	port := redpandaKafkaPort
+	r.Config.Kafka = &config.KafkaConfig{
+		Brokers: []config.BrokerConfig{{
+			Hostname: getRedpandaHostname(r.Env),
+			Port:     &port,
+		}},
+		Topics: []config.TopicConfig{},
+	}
+
+	if r.Env.Spec.Providers.Kafka.SchemaRegistry.Enabled {
+		// Redpanda serves a Confluent compatible schema registry itself
+		r.Config.Kafka.SchemaRegistry = &config.SchemaRegistryConfig{
+			Url: fmt.Sprintf("http://%s:%d", getRedpandaHostname(r.Env), redpandaSchemaRegistryPort),
+		}
+	}
+
+	if len(app.Spec.KafkaTopics) == 0 {
+		return nil
+	}
+
+	appList, err := r.Env.GetAppsInEnv(r.Ctx, r.Client)
+	if err != nil {
+		return errors.Wrap("Topic creation failed: Error listing apps", err)
+	}
+
+	topics := []redpandaTopic{}
+	for _, topic := range app.GetKafkaTopics() {
+		// the topic values are merged across the apps in the environment exactly as they are for
+		// KafkaTopic resources in (*_operator_*) mode
+		k := &strimzi.KafkaTopic{Spec: &strimzi.KafkaTopicSpec{}}
+		if err := processTopicValues(k, r.Env, appList, topic); err != nil {
+			return err
+		}
+
+		rt := redpandaTopic{Name: topic.TopicName, Partitions: 3}
+		if k.Spec.Partitions != nil {
+			rt.Partitions = *k.Spec.Partitions
+		}
+		if k.Spec.Config != nil {
+			if err := json.Unmarshal(k.Spec.Config.Raw, &rt.Config); err != nil {
+				return err
+			}
+		}
+		topics = append(topics, rt)
+
+		r.Config.Kafka.Topics = append(
+			r.Config.Kafka.Topics,
+			config.TopicConfig{Name: topic.TopicName, RequestedName: topic.TopicName, ConsumerGroups: topic.ConsumerGroups},
+		)
+	}
+
+	linkCompanionTopics(app, r.Config.Kafka.Topics)
+
+	return r.makeTopicsJob(app, topics)
+}
+
+type redpandaTopic struct {
+	Name       string
+	Partitions int32
+	Config     map[string]string
+}
+
+// redpandaTopicsScript returns a script that creates each topic with rpk if it does not exist yet
+// and then applies its config, so that running it again is harmless.
+func redpandaTopicsScript(topics []redpandaTopic) string {
+	lines := []string{"set -e"}
+	for _, topic := range topics {
+		lines = append(lines, fmt.Sprintf(
+			"rpk topic describe %[1]s >/dev/null 2>&1 || rpk topic create %[1]s -p %[2]d -r 1",
+			shellQuote(topic.Name), topic.Partitions,
+		))
+
+		keys := []string{}
+		for key := range topic.Config {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		if len(keys) > 0 {
+			args := []string{}
+			for _, key := range keys {
+				args = append(args, "--set", shellQuote(fmt.Sprintf("%s=%s", key, topic.Config[key])))
+			}
+			lines = append(lines, fmt.Sprintf("rpk topic alter-config %s %s", shellQuote(topic.Name), strings.Join(args, " ")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// makeTopicsJob creates the app's topics through the Kafka admin API with a Job running rpk. The
+// Job is named after a hash of its script so that it is run again whenever the topics change.
+func (r *redpandaProvider) makeTopicsJob(app *crd.ClowdApp, topics []redpandaTopic) error {
+	script := redpandaTopicsScript(topics)
+
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(script)))[:8]
+	nn := types.NamespacedName{
+		Name:      fmt.Sprintf("%s-redpanda-topics-%s", app.Name, hash),
+		Namespace: app.Namespace,
+	}
+
+	job := &batch.Job{}
+	if err := r.Cache.Create(RedpandaTopicsJob, nn, job); err != nil {
+		return err
+	}
+
+	// The pod template of a Job is immutable, once it has been created for this script it is left
+	// exactly as it is.
+	if job.CreationTimestamp.IsZero() {
+		labels := app.GetLabels()
+		labels["job"] = nn.Name
+		labeler := utils.MakeLabeler(nn, labels, app)
+		labeler(job)
+
+		utils.UpdateAnnotations(job, provutils.KubeLinterAnnotations)
+
+		job.Spec.BackoffLimit = utils.Int32Ptr(6)
+		job.Spec.Template.SetLabels(labels)
+		job.Spec.Template.Spec.RestartPolicy = core.RestartPolicyOnFailure
+		job.Spec.Template.Spec.Containers = []core.Container{{
+			Name:    "topics",
+			Image:   GetRedpandaImage(),
+			Command: []string{"/bin/bash", "-c", script},
+			Env: []core.EnvVar{{
+				Name:  "RPK_BROKERS",
+				Value: fmt.Sprintf("%s:%d", getRedpandaHostname(r.Env), redpandaKafkaPort),
+			}},
+			TerminationMessagePath:   "/dev/termination-log",
+			TerminationMessagePolicy: core.TerminationMessageReadFile,
+			ImagePullPolicy:          core.PullIfNotPresent,
+			Resources: core.ResourceRequirements{
+				Limits: core.ResourceList{
+					"memory": resource.MustParse("256Mi"),
+					"cpu":    resource.MustParse("200m"),
+				},
+				Requests: core.ResourceList{
+					"memory": resource.MustParse("64Mi"),
+					"cpu":    resource.MustParse("50m"),
+				},
+			},
+		}}
+	}
+
+	return r.Cache.Update(RedpandaTopicsJob, job)
+}
+
+func makeLocalRedpanda(_ *crd.ClowdEnvironment, o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) error {
+	nn := providers.GetNamespacedName(o, "redpanda")
+
+	dd := objMap[RedpandaDeployment].(*apps.Deployment)
+	svc := objMap[RedpandaService].(*core.Service)
+
+	labels := o.GetLabels()
+	labels["env-app"] = nn.Name
+	labels["service"] = "kafka"
+
+	labeler := utils.MakeLabeler(nn, labels, o)
+	labeler(dd)
+
+	dd.Spec.Replicas = utils.Int32Ptr(1)
+	dd.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dd.Spec.Strategy = apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType}
+	dd.Spec.Template.SetLabels(labels)
+
+	var volSource core.VolumeSource
+	if usePVC {
+		volSource = core.VolumeSource{
+			PersistentVolumeClaim: &core.PersistentVolumeClaimVolumeSource{
+				ClaimName: nn.Name,
+			},
+		}
+	} else {
+		volSource = core.VolumeSource{
+			EmptyDir: &core.EmptyDirVolumeSource{},
+		}
+	}
+
+	dd.Spec.Template.Spec.Volumes = []core.Volume{{
+		Name:         nn.Name,
+		VolumeSource: volSource,
+	}}
+
+	hostname := fmt.Sprintf("%s.%s.svc", nn.Name, nn.Namespace)
+
+	probe := func(delay int32) *core.Probe {
+		return &core.Probe{
+			ProbeHandler: core.ProbeHandler{
+				HTTPGet: &core.HTTPGetAction{
+					Path: "/v1/status/ready",
+					Port: intstr.FromInt(redpandaAdminPort),
+				},
+			},
+			InitialDelaySeconds: delay,
+			TimeoutSeconds:      2,
+			PeriodSeconds:       10,
+			SuccessThreshold:    1,
+			FailureThreshold:    3,
+		}
+	}
+
+	dd.Spec.Template.Spec.Containers = []core.Container{{
+		Name:  nn.Name,
+		Image: GetRedpandaImage(),
+		Args: []string{
+			"redpanda",
+			"start",
+			"--mode=dev-container",
+			"--smp=1",
+			"--memory=1G",
+			"--default-log-level=warn",
+			fmt.Sprintf("--kafka-addr=0.0.0.0:%d", redpandaKafkaPort),
+			fmt.Sprintf("--advertise-kafka-addr=%s:%d", hostname, redpandaKafkaPort),
+			fmt.Sprintf("--schema-registry-addr=0.0.0.0:%d", redpandaSchemaRegistryPort),
+		},
+		Ports: []core.ContainerPort{{
+			Name:          "kafka",
+			ContainerPort: redpandaKafkaPort,
+			Protocol:      core.ProtocolTCP,
+		}, {
+			Name:          "schema-registry",
+			ContainerPort: redpandaSchemaRegistryPort,
+			Protocol:      core.ProtocolTCP,
+		}, {
+			Name:          "admin",
+			ContainerPort: redpandaAdminPort,
+			Protocol:      core.ProtocolTCP,
+		}},
+		VolumeMounts: []core.VolumeMount{{
+			Name:      nn.Name,
+			MountPath: "/var/lib/redpanda/data",
+		}},
+		LivenessProbe:            probe(30),
+		ReadinessProbe:           probe(10),
+		TerminationMessagePath:   "/dev/termination-log",
+		TerminationMessagePolicy: core.TerminationMessageReadFile,
+		ImagePullPolicy:          core.PullIfNotPresent,
+		Resources: core.ResourceRequirements{
+			Limits: core.ResourceList{
+				"memory": resource.MustParse("1500Mi"),
+				"cpu":    resource.MustParse("1"),
+			},
+			Requests: core.ResourceList{
+				"memory": resource.MustParse("1200Mi"),
+				"cpu":    resource.MustParse("200m"),
+			},
+		},
+	}}
+
+	servicePorts := []core.ServicePort{{
+		Name:       "kafka",
+		Port:       redpandaKafkaPort,
+		Protocol:   core.ProtocolTCP,
+		TargetPort: intstr.FromInt(redpandaKafkaPort),
+	}, {
+		Name:       "schema-registry",
+		Port:       redpandaSchemaRegistryPort,
+		Protocol:   core.ProtocolTCP,
+		TargetPort: intstr.FromInt(redpandaSchemaRegistryPort),
+	}, {
+		Name:       "admin",
+		Port:       redpandaAdminPort,
+		Protocol:   core.ProtocolTCP,
+		TargetPort: intstr.FromInt(redpandaAdminPort),
+	}}
+
+	utils.MakeService(svc, nn, labels, servicePorts, o, nodePort)
+
+	if usePVC {
+		pvc := objMap[RedpandaPVC].(*core.PersistentVolumeClaim)
+		env, ok := o.(*crd.ClowdEnvironment)
+		if !ok {
+			return fmt.Errorf("could not get env from object")
+		}
+		size := env.Spec.Providers.Kafka.Cluster.StorageSize
+		if size == "" {
+			size = "1Gi"
+		}
+		utils.MakePVC(pvc, nn, labels, size, o)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+)
+
+func TestRedpandaTopicsScript(t *testing.T) {
+	script := redpandaTopicsScript([]redpandaTopic{
+		{Name: "ingress", Partitions: 3},
+		{Name: "events", Partitions: 5, Config: map[string]string{"retention.ms": "1000", "cleanup.policy": "compact,delete"}},
+	})
+
+	assert.Equal(t, `set -e
+rpk topic describe 'ingress' >/dev/null 2>&1 || rpk topic create 'ingress' -p 3 -r 1
+rpk topic describe 'events' >/dev/null 2>&1 || rpk topic create 'events' -p 5 -r 1
+rpk topic alter-config 'events' --set 'cleanup.policy=compact,delete' --set 'retention.ms=1000'`, script)
+}
+
+func TestRedpandaProvide(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Spec.TargetNamespace = "env-ns"
+	env.Status.TargetNamespace = "env-ns"
+	env.Spec.Providers.Kafka.Mode = "redpanda"
+	env.Spec.Providers.Kafka.SchemaRegistry.Enabled = true
+
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+	app.Namespace = "app-ns"
+	app.Spec.EnvName = "env"
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{{
+		TopicName:      "events",
+		Partitions:     5,
+		Config:         map[string]string{"retention.ms": "1000"},
+		ConsumerGroups: []string{"processor"},
+	}}
+
+	ctx := context.Background()
+	log := logr.Discard()
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(app).
+		WithIndex(&crd.ClowdApp{}, "spec.envName", func(o client.Object) []string {
+			return []string{o.(*crd.ClowdApp).Spec.EnvName}
+		}).
+		Build()
+	cache := rc.NewObjectCache(ctx, c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+
+	p := &providers.Provider{
+		Ctx:    ctx,
+		Client: c,
+		Env:    env,
+		Cache:  &cache,
+		Config: &config.AppConfig{},
+	}
+
+	rp, err := NewRedpanda(p)
+	require.NoError(t, err)
+	require.NoError(t, rp.EnvProvide())
+	require.NoError(t, rp.Provide(app))
+
+	kafkaConfig := rp.GetConfig().Kafka
+	require.Len(t, kafkaConfig.Brokers, 1)
+	assert.Equal(t, "env-redpanda.env-ns.svc", kafkaConfig.Brokers[0].Hostname)
+	assert.Equal(t, 9092, *kafkaConfig.Brokers[0].Port)
+	assert.Nil(t, kafkaConfig.Brokers[0].Authtype, "the broker is plaintext without auth")
+	assert.Equal(t, "http://env-redpanda.env-ns.svc:8081", kafkaConfig.SchemaRegistry.Url)
+	assert.Equal(t, []config.TopicConfig{{Name: "events", RequestedName: "events", ConsumerGroups: []string{"processor"}}}, kafkaConfig.Topics)
+
+	job := &batch.Job{}
+	require.NoError(t, cache.Get(RedpandaTopicsJob, job))
+	assert.Equal(t, "app-ns", job.Namespace)
+	assert.Regexp(t, "^app-redpanda-topics-[0-9a-f]{8}$", job.Name)
+	assert.Contains(t, job.Spec.Template.Spec.Containers[0].Command[2], "rpk topic create 'events' -p 5 -r 1")
+	assert.Contains(t, job.Spec.Template.Spec.Containers[0].Command[2], "--set 'retention.ms=1000'")
+	assert.Equal(t, "env-redpanda.env-ns.svc:9092", job.Spec.Template.Spec.Containers[0].Env[0].Value)
+}
@@ -0,0 +1,339 @@
+package kafka
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	provutils "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/utils"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+// RotateCredentialsAnnotation requests a rotation of the Kafka credentials whenever its value
+// changes. It is read from a ClowdApp for the app's user and from a ClowdEnvironment for the
+// Kafka Connect user.
+const RotateCredentialsAnnotation = "clowder/rotate-kafka-credentials"
+
+// The rotation state is kept in annotations on the credentials secret.
+const (
+	activeUserAnnotation       = "clowder/active-user"
+	rotatedAtAnnotation        = "clowder/rotated-at"
+	rotationTriggerAnnotation  = "clowder/rotation-trigger"
+	previousRetiredAnnotation  = "clowder/previous-retired"
+	passwordChecksumAnnotation = "clowder/password-checksum"
+)
+
+// DefaultCredentialOverlap is how long the previous credential stays valid after a rotation when
+// the environment does not set an overlap.
+var DefaultCredentialOverlap = time.Hour
+
+// KafkaUserAlt is the resource ident for the second KafkaUser of a rotated app.
+var KafkaUserAlt = rc.NewSingleResourceIdent(ProvName, "kafka_user_alt", &strimzi.KafkaUser{}, rc.ResourceOptions{WriteNow: true})
+
+// KafkaUserCredentials is the resource ident for the passwords of a rotated app's KafkaUsers.
+var KafkaUserCredentials = rc.NewSingleResourceIdent(ProvName, "kafka_user_credentials", &core.Secret{}, rc.ResourceOptions{WriteNow: true})
+
+// KafkaConnectUserAlt is the resource ident for the second KafkaUser of a rotated Kafka Connect cluster.
+var KafkaConnectUserAlt = rc.NewSingleResourceIdent(ProvName, "kafka_connect_user_alt", &strimzi.KafkaUser{}, rc.ResourceOptions{WriteNow: true})
+
+// KafkaConnectUserCredentials is the resource ident for the passwords of a rotated Kafka Connect
+// cluster's KafkaUsers.
+var KafkaConnectUserCredentials = rc.NewSingleResourceIdent(ProvName, "kafka_connect_user_credentials", &core.Secret{}, rc.ResourceOptions{WriteNow: true})
+
+// now is replaced in tests.
+var now = time.Now
+
+// credentialRotationEnabled returns whether the env rotates the SCRAM credentials of its
+// KafkaUsers. Legacy Strimzi environments have no SCRAM users, so they are never rotated.
+func credentialRotationEnabled(env *crd.ClowdEnvironment) bool {
+	return env.Spec.Providers.Kafka.CredentialRotation.Enabled && !env.Spec.Providers.Kafka.EnableLegacyStrimzi
+}
+
+// Kafka only holds one SCRAM credential per user, so a rotated principal alternates between two
+// KafkaUsers, the user itself and its ".alt" user, whose passwords Clowder generates and keeps in
+// a ".credentials" secret. The dot keeps the names from clashing with those of other apps.
+func rotatedUserNames(name string) [2]string {
+	return [2]string{name, name + ".alt"}
+}
+
+func credentialsSecretName(name string) string {
+	return name + ".credentials"
+}
+
+func newScramPassword() ([]byte, error) {
+	password, err := utils.RandPassword(32, provutils.RCharSet)
+	return []byte(password), err
+}
+
+// rotateScramCredentials updates the passwords and rotation state kept in the credentials secret
+// of the principal name and returns the user clients should use. On first use the current
+// password of the principal's Strimzi managed user, if any, is carried over so that enabling
+// rotation does not change it.
+//
+// A rotation is due when the interval has passed since the last one or when the trigger differs
+// from the one last seen. It gives the other user a new password and moves clients to it, leaving
+// the previous credential valid. Once the overlap has passed, the previous user's password is
+// changed again so that the old credential stops working. A rotation that is due during the
+// overlap is postponed until then.
+func rotateScramCredentials(secret *core.Secret, name string, current []byte, trigger string, policy crd.KafkaCredentialRotationConfig) (string, error) {
+	users := rotatedUserNames(name)
+	t := now().UTC()
+
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	inactive := func(active string) string {
+		if active == users[0] {
+			return users[1]
+		}
+		return users[0]
+	}
+
+	active := annotations[activeUserAnnotation]
+	if active != users[0] && active != users[1] {
+		if len(current) == 0 {
+			password, err := newScramPassword()
+			if err != nil {
+				return "", err
+			}
+			current = password
+		}
+		password, err := newScramPassword()
+		if err != nil {
+			return "", err
+		}
+
+		active = users[0]
+		secret.Data[users[0]] = current
+		secret.Data[users[1]] = password
+		annotations[activeUserAnnotation] = active
+		annotations[rotatedAtAnnotation] = t.Format(time.RFC3339)
+		annotations[rotationTriggerAnnotation] = trigger
+		annotations[previousRetiredAnnotation] = "true"
+		secret.SetAnnotations(annotations)
+		return active, nil
+	}
+
+	rotatedAt, err := time.Parse(time.RFC3339, annotations[rotatedAtAnnotation])
+	if err != nil {
+		rotatedAt = t
+		annotations[rotatedAtAnnotation] = t.Format(time.RFC3339)
+	}
+
+	overlap := policy.Overlap.Duration
+	if overlap <= 0 {
+		overlap = DefaultCredentialOverlap
+	}
+	if annotations[previousRetiredAnnotation] != "true" && !t.Before(rotatedAt.Add(overlap)) {
+		password, err := newScramPassword()
+		if err != nil {
+			return "", err
+		}
+
+		secret.Data[inactive(active)] = password
+		annotations[previousRetiredAnnotation] = "true"
+	}
+
+	// Another rotation would move clients back to the previous user while some of them may still
+	// use it, so a rotation that is due waits for the previous credential to be retired. The
+	// trigger is only recorded once the rotation happens.
+	interval := policy.Interval.Duration
+	due := (interval > 0 && !t.Before(rotatedAt.Add(interval))) || trigger != annotations[rotationTriggerAnnotation]
+	if due && annotations[previousRetiredAnnotation] == "true" {
+		password, err := newScramPassword()
+		if err != nil {
+			return "", err
+		}
+
+		active = inactive(active)
+		secret.Data[active] = password
+		annotations[activeUserAnnotation] = active
+		annotations[rotatedAtAnnotation] = t.Format(time.RFC3339)
+		annotations[rotationTriggerAnnotation] = trigger
+		annotations[previousRetiredAnnotation] = "false"
+	}
+
+	secret.SetAnnotations(annotations)
+	return active, nil
+}
+
+// rotatedKafkaUsers writes the credentials secret and both KafkaUsers of the rotated principal
+// name, using spec for each of them, and returns the user clients should use. trigger is the value
+// of the rotation annotation on the object that owns the principal.
+func (s *strimziProvider) rotatedKafkaUsers(nn types.NamespacedName, spec func() *strimzi.KafkaUserSpec, trigger string, secretIdent rc.ResourceIdent, userIdents [2]rc.ResourceIdent) (string, error) {
+	secretNN := types.NamespacedName{Name: credentialsSecretName(nn.Name), Namespace: nn.Namespace}
+
+	secret := &core.Secret{}
+	if err := s.Cache.Create(secretIdent, secretNN, secret); err != nil {
+		return "", err
+	}
+
+	current := []byte{}
+	if _, ok := secret.GetAnnotations()[activeUserAnnotation]; !ok {
+		strimziSecret := &core.Secret{}
+		found, err := utils.UpdateOrErr(s.Client.Get(s.Ctx, nn, strimziSecret))
+		if err != nil {
+			return "", err
+		}
+		if found {
+			current = strimziSecret.Data["password"]
+		}
+	}
+
+	active, err := rotateScramCredentials(secret, nn.Name, current, trigger, s.Env.Spec.Providers.Kafka.CredentialRotation)
+	if err != nil {
+		return "", err
+	}
+
+	labeler := utils.GetCustomLabeler(nil, secretNN, s.Env)
+	labeler(secret)
+
+	if err := s.Cache.Update(secretIdent, secret); err != nil {
+		return "", err
+	}
+
+	for i, user := range rotatedUserNames(nn.Name) {
+		userNN := types.NamespacedName{Name: user, Namespace: nn.Namespace}
+
+		ku := &strimzi.KafkaUser{}
+		if err := s.Cache.Create(userIdents[i], userNN, ku); err != nil {
+			return "", err
+		}
+
+		labeler := utils.GetCustomLabeler(
+			map[string]string{"strimzi.io/cluster": getKafkaName(s.Env)}, userNN, s.Env,
+		)
+		labeler(ku)
+
+		// The User Operator does not watch the secret, changing the checksum gets it to apply a
+		// new password straight away
+		annotations := ku.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[passwordChecksumAnnotation] = fmt.Sprintf("%x", sha256.Sum256(secret.Data[user]))
+		ku.SetAnnotations(annotations)
+
+		key := user
+		ku.Spec = spec()
+		ku.Spec.Authentication.Password = &strimzi.KafkaUserSpecAuthenticationPassword{
+			ValueFrom: strimzi.KafkaUserSpecAuthenticationPasswordValueFrom{
+				SecretKeyRef: &strimzi.KafkaUserSpecAuthenticationPasswordValueFromSecretKeyRef{
+					Name: &secretNN.Name,
+					Key:  &key,
+				},
+			},
+		}
+
+		if err := s.Cache.Update(userIdents[i], ku); err != nil {
+			return "", err
+		}
+	}
+
+	return active, nil
+}
+
+// getRotatedUser returns the user clients of the rotated principal name should use and the secret
+// holding its password under the user's name.
+func (s *strimziProvider) getRotatedUser(nn types.NamespacedName) (string, *core.Secret, error) {
+	secretNN := types.NamespacedName{Name: credentialsSecretName(nn.Name), Namespace: nn.Namespace}
+
+	secret := &core.Secret{}
+	if err := s.Client.Get(s.Ctx, secretNN, secret); err != nil {
+		return "", nil, err
+	}
+
+	active, ok := secret.GetAnnotations()[activeUserAnnotation]
+	if !ok {
+		return "", nil, errors.NewClowderError("no active user in kafka credentials secret")
+	}
+	return active, secret, nil
+}
+
+// nextRotationStep returns how long until the next step of the rotation kept in a credentials
+// secret is due, that is the retirement of the previous credential at the end of the overlap or,
+// once it is retired, the next rotation on the interval. It returns false when no step is pending.
+func nextRotationStep(secret *core.Secret, policy crd.KafkaCredentialRotationConfig) (time.Duration, bool) {
+	annotations := secret.GetAnnotations()
+	rotatedAt, err := time.Parse(time.RFC3339, annotations[rotatedAtAnnotation])
+	if err != nil {
+		return 0, false
+	}
+
+	// no rotation happens before the previous credential is retired, so the end of the overlap
+	// comes first while it is pending
+	var next time.Time
+	if annotations[previousRetiredAnnotation] != "true" {
+		overlap := policy.Overlap.Duration
+		if overlap <= 0 {
+			overlap = DefaultCredentialOverlap
+		}
+		next = rotatedAt.Add(overlap)
+	} else if policy.Interval.Duration > 0 {
+		next = rotatedAt.Add(policy.Interval.Duration)
+	} else {
+		return 0, false
+	}
+
+	// a step that is already due is run on a prompt requeue
+	wait := next.Sub(now().UTC())
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait, true
+}
+
+// getRotationRequeue returns how long until the next rotation step of the principal name is due,
+// and false when its credentials are not rotated or nothing is pending.
+func getRotationRequeue(ctx context.Context, c client.Client, env *crd.ClowdEnvironment, nn types.NamespacedName) (time.Duration, bool, error) {
+	if env.Spec.Providers.Kafka.Mode != "operator" || !credentialRotationEnabled(env) {
+		return 0, false, nil
+	}
+
+	secret := &core.Secret{}
+	secretNN := types.NamespacedName{Name: credentialsSecretName(nn.Name), Namespace: nn.Namespace}
+	if err := c.Get(ctx, secretNN, secret); err != nil {
+		if k8serr.IsNotFound(err) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	wait, ok := nextRotationStep(secret, env.Spec.Providers.Kafka.CredentialRotation)
+	return wait, ok, nil
+}
+
+// GetAppRotationRequeue returns how long until the next rotation step of an app's Kafka
+// credentials is due, so that the app is reconciled again then, and false when nothing is pending.
+func GetAppRotationRequeue(ctx context.Context, c client.Client, env *crd.ClowdEnvironment, app *crd.ClowdApp) (time.Duration, bool, error) {
+	return getRotationRequeue(ctx, c, env, types.NamespacedName{
+		Name:      getKafkaUsername(env, app),
+		Namespace: getKafkaNamespace(env),
+	})
+}
+
+// GetConnectRotationRequeue returns how long until the next rotation step of the Kafka Connect
+// cluster's credentials is due, so that the env is reconciled again then, and false when nothing
+// is pending.
+func GetConnectRotationRequeue(ctx context.Context, c client.Client, env *crd.ClowdEnvironment) (time.Duration, bool, error) {
+	return getRotationRequeue(ctx, c, env, types.NamespacedName{
+		Name:      getConnectUsername(env),
+		Namespace: getConnectNamespace(env),
+	})
+}
@@ -0,0 +1,203 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+)
+
+func TestRotateScramCredentials(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	policy := crd.KafkaCredentialRotationConfig{
+		Enabled:  true,
+		Interval: metav1.Duration{Duration: 24 * time.Hour},
+		Overlap:  metav1.Duration{Duration: time.Hour},
+	}
+	secret := &core.Secret{}
+
+	active, err := rotateScramCredentials(secret, "env-app", []byte("strimzi-password"), "", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app", active)
+	assert.Equal(t, "strimzi-password", string(secret.Data["env-app"]), "the current password is kept when rotation is enabled")
+	assert.Len(t, secret.Data["env-app.alt"], 32)
+
+	clock = clock.Add(time.Hour)
+	active, err = rotateScramCredentials(secret, "env-app", nil, "", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app", active, "nothing is rotated before the interval")
+	assert.Equal(t, "strimzi-password", string(secret.Data["env-app"]))
+
+	clock = clock.Add(24 * time.Hour)
+	alt := string(secret.Data["env-app.alt"])
+	active, err = rotateScramCredentials(secret, "env-app", nil, "", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app.alt", active, "clients move to the other user after the interval")
+	assert.NotEqual(t, alt, string(secret.Data["env-app.alt"]), "the new user gets a new password")
+	assert.Equal(t, "strimzi-password", string(secret.Data["env-app"]), "the old credential stays valid during the overlap")
+
+	clock = clock.Add(time.Hour)
+	active, err = rotateScramCredentials(secret, "env-app", nil, "", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app.alt", active)
+	assert.NotEqual(t, "strimzi-password", string(secret.Data["env-app"]), "the old credential is retired after the overlap")
+
+	retired := string(secret.Data["env-app"])
+	active, err = rotateScramCredentials(secret, "env-app", nil, "2024-01-02", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app", active, "changing the trigger rotates straight away")
+	assert.NotEqual(t, retired, string(secret.Data["env-app"]))
+
+	active, err = rotateScramCredentials(secret, "env-app", nil, "2024-01-02", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app", active, "the same trigger only rotates once")
+}
+
+func TestCreateKafkaConnectUserRotated(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, strimzi.AddToScheme(scheme))
+	require.NoError(t, crd.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Spec.Providers.Kafka.Cluster.Namespace = "kafka"
+	env.Spec.Providers.Kafka.CredentialRotation.Enabled = true
+
+	strimziSecret := &core.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "env-connect", Namespace: "kafka"},
+		Data:       map[string][]byte{"password": []byte("strimzi-password")},
+	}
+
+	ctx := context.Background()
+	log := logr.Discard()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(strimziSecret).Build()
+	cache := rc.NewObjectCache(ctx, c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
+
+	s := &strimziProvider{Provider: providers.Provider{Ctx: ctx, Client: c, Env: env, Cache: &cache}}
+	require.NoError(t, s.createKafkaConnectUser())
+
+	assert.Equal(t, "env-connect", s.getConnectClusterUserName())
+	secretName, key := s.getConnectClusterPasswordSecret()
+	assert.Equal(t, "env-connect.credentials", secretName)
+	assert.Equal(t, "env-connect", key)
+
+	secret := &core.Secret{}
+	require.NoError(t, cache.Get(KafkaConnectUserCredentials, secret))
+	assert.Equal(t, "strimzi-password", string(secret.Data["env-connect"]))
+
+	for _, ident := range []rc.ResourceIdent{KafkaConnectUser, KafkaConnectUserAlt} {
+		ku := &strimzi.KafkaUser{}
+		require.NoError(t, cache.Get(ident, ku))
+		assert.Equal(t, "kafka", ku.Namespace)
+		assert.Equal(t, "env-connect.credentials", *ku.Spec.Authentication.Password.ValueFrom.SecretKeyRef.Name)
+		assert.Equal(t, ku.Name, *ku.Spec.Authentication.Password.ValueFrom.SecretKeyRef.Key)
+		assert.Len(t, ku.Spec.Authorization.Acls, 2)
+	}
+}
+
+func TestRotateScramCredentialsWaitsForRetirement(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	policy := crd.KafkaCredentialRotationConfig{
+		Enabled: true,
+		Overlap: metav1.Duration{Duration: time.Hour},
+	}
+	secret := &core.Secret{}
+
+	_, err := rotateScramCredentials(secret, "env-app", []byte("strimzi-password"), "", policy)
+	require.NoError(t, err)
+
+	active, err := rotateScramCredentials(secret, "env-app", nil, "1", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app.alt", active)
+
+	clock = clock.Add(30 * time.Minute)
+	active, err = rotateScramCredentials(secret, "env-app", nil, "2", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app.alt", active, "a second rotation waits for the previous credential to be retired")
+	assert.Equal(t, "strimzi-password", string(secret.Data["env-app"]), "clients still on the previous user keep working")
+	assert.Equal(t, "1", secret.Annotations[rotationTriggerAnnotation], "the new trigger is kept pending")
+
+	wait, ok := nextRotationStep(secret, policy)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Minute, wait, "the postponed rotation is run at the end of the overlap")
+
+	clock = clock.Add(30 * time.Minute)
+	active, err = rotateScramCredentials(secret, "env-app", nil, "2", policy)
+	require.NoError(t, err)
+	assert.Equal(t, "env-app", active, "the postponed rotation happens once the previous credential is retired")
+	assert.NotEqual(t, "strimzi-password", string(secret.Data["env-app"]))
+	assert.Equal(t, "2", secret.Annotations[rotationTriggerAnnotation])
+	assert.Equal(t, "false", secret.Annotations[previousRetiredAnnotation])
+}
+
+func TestGetAppRotationRequeue(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Spec.Providers.Kafka.Mode = "operator"
+	env.Spec.Providers.Kafka.Cluster.Namespace = "kafka"
+	env.Spec.Providers.Kafka.CredentialRotation = crd.KafkaCredentialRotationConfig{
+		Enabled:  true,
+		Interval: metav1.Duration{Duration: 24 * time.Hour},
+		Overlap:  metav1.Duration{Duration: time.Hour},
+	}
+
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+
+	secret := &core.Secret{ObjectMeta: metav1.ObjectMeta{Name: "env-app.credentials", Namespace: "kafka"}}
+	_, err := rotateScramCredentials(secret, "env-app", nil, "", env.Spec.Providers.Kafka.CredentialRotation)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+	clock = clock.Add(time.Hour)
+	wait, ok, err := GetAppRotationRequeue(ctx, c, env, app)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 23*time.Hour, wait, "the next rotation is due after the interval")
+
+	secret.Annotations[previousRetiredAnnotation] = "false"
+	require.NoError(t, c.Update(ctx, secret))
+	wait, _, err = GetAppRotationRequeue(ctx, c, env, app)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wait, "a retirement already due is run promptly")
+
+	clock = clock.Add(-30 * time.Minute)
+	wait, _, err = GetAppRotationRequeue(ctx, c, env, app)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, wait, "the previous credential is retired at the end of the overlap")
+
+	env.Spec.Providers.Kafka.EnableLegacyStrimzi = true
+	_, ok, err = GetAppRotationRequeue(ctx, c, env, app)
+	require.NoError(t, err)
+	assert.False(t, ok, "legacy strimzi environments are not rotated")
+}
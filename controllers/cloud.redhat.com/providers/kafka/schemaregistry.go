@@ -0,0 +1,188 @@
+package kafka
+
+import (
+	"fmt"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/clowderconfig"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+// DefaultImageSchemaRegistry defines the default Apicurio Registry image
+var DefaultImageSchemaRegistry = "quay.io/apicurio/apicurio-registry-mem:2.6.5.Final"
+
+const schemaRegistryPort = 8080
+
+// schemaRegistryAPIPath is the Confluent compatible API served by Apicurio, which is what most
+// Kafka serializers expect.
+const schemaRegistryAPIPath = "/apis/ccompat/v7"
+
+// SchemaRegistryDeployment is the resource ident for the local schema registry deployment.
+var SchemaRegistryDeployment = rc.NewSingleResourceIdent(ProvName, "schema_registry_deployment", &apps.Deployment{})
+
+// SchemaRegistryService is the resource ident for the local schema registry service.
+var SchemaRegistryService = rc.NewSingleResourceIdent(ProvName, "schema_registry_service", &core.Service{})
+
+// GetSchemaRegistryImage returns the schema registry image for the environment
+func GetSchemaRegistryImage(p *providers.Provider) string {
+	if p.Env.Spec.Providers.Kafka.SchemaRegistry.Image != "" {
+		return p.Env.Spec.Providers.Kafka.SchemaRegistry.Image
+	}
+	if clowderconfig.LoadedConfig.Images.KafkaSchemaRegistry != "" {
+		return clowderconfig.LoadedConfig.Images.KafkaSchemaRegistry
+	}
+	return DefaultImageSchemaRegistry
+}
+
+func getSchemaRegistryNamespacedName(p *providers.Provider) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      fmt.Sprintf("%s-schema-registry", getKafkaName(p.Env)),
+		Namespace: getKafkaNamespace(p.Env),
+	}
+}
+
+// createSchemaRegistry deploys an Apicurio Registry next to the Kafka cluster. Schemas are held in
+// memory and are lost when the pod restarts.
+func createSchemaRegistry(p *providers.Provider) error {
+	nn := getSchemaRegistryNamespacedName(p)
+
+	dd := &apps.Deployment{}
+	if err := p.Cache.Create(SchemaRegistryDeployment, nn, dd); err != nil {
+		return err
+	}
+
+	labels := p.Env.GetLabels()
+	labels["env-app"] = nn.Name
+	labels["service"] = "schema-registry"
+	labeler := utils.MakeLabeler(nn, labels, p.Env)
+	labeler(dd)
+
+	dd.Spec.Replicas = utils.Int32Ptr(1)
+	dd.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	dd.Spec.Template.SetLabels(labels)
+
+	probe := func(path string, delay int32) *core.Probe {
+		return &core.Probe{
+			ProbeHandler: core.ProbeHandler{
+				HTTPGet: &core.HTTPGetAction{
+					Path: path,
+					Port: intstr.FromInt(schemaRegistryPort),
+				},
+			},
+			InitialDelaySeconds: delay,
+			TimeoutSeconds:      5,
+			PeriodSeconds:       10,
+			SuccessThreshold:    1,
+			FailureThreshold:    3,
+		}
+	}
+
+	dd.Spec.Template.Spec.Containers = []core.Container{{
+		Name:  nn.Name,
+		Image: GetSchemaRegistryImage(p),
+		Ports: []core.ContainerPort{{
+			Name:          "registry",
+			ContainerPort: schemaRegistryPort,
+			Protocol:      core.ProtocolTCP,
+		}},
+		LivenessProbe:            probe("/health/live", 30),
+		ReadinessProbe:           probe("/health/ready", 10),
+		TerminationMessagePath:   "/dev/termination-log",
+		TerminationMessagePolicy: core.TerminationMessageReadFile,
+		ImagePullPolicy:          core.PullIfNotPresent,
+		Resources: core.ResourceRequirements{
+			Limits: core.ResourceList{
+				"memory": resource.MustParse("512Mi"),
+				"cpu":    resource.MustParse("500m"),
+			},
+			Requests: core.ResourceList{
+				"memory": resource.MustParse("256Mi"),
+				"cpu":    resource.MustParse("100m"),
+			},
+		},
+	}}
+
+	if err := p.Cache.Update(SchemaRegistryDeployment, dd); err != nil {
+		return err
+	}
+
+	svc := &core.Service{}
+	if err := p.Cache.Create(SchemaRegistryService, nn, svc); err != nil {
+		return err
+	}
+
+	servicePorts := []core.ServicePort{{
+		Name:       "registry",
+		Port:       schemaRegistryPort,
+		Protocol:   core.ProtocolTCP,
+		TargetPort: intstr.FromInt(schemaRegistryPort),
+	}}
+
+	utils.MakeService(svc, nn, labels, servicePorts, p.Env, false)
+
+	return p.Cache.Update(SchemaRegistryService, svc)
+}
+
+// setLocalSchemaRegistryConfig points the app at the registry deployed by createSchemaRegistry.
+func setLocalSchemaRegistryConfig(p *providers.Provider, kafkaConfig *config.KafkaConfig) {
+	if !p.Env.Spec.Providers.Kafka.SchemaRegistry.Enabled {
+		return
+	}
+
+	nn := getSchemaRegistryNamespacedName(p)
+	kafkaConfig.SchemaRegistry = &config.SchemaRegistryConfig{
+		Url: fmt.Sprintf("http://%s.%s.svc:%d%s", nn.Name, nn.Namespace, schemaRegistryPort, schemaRegistryAPIPath),
+	}
+}
+
+// setExternalSchemaRegistryConfig passes through the URL, and the credentials from the secret, of
+// a registry that Clowder does not manage.
+func setExternalSchemaRegistryConfig(p *providers.Provider, kafkaConfig *config.KafkaConfig) error {
+	registry := p.Env.Spec.Providers.Kafka.SchemaRegistry
+	if !registry.Enabled {
+		return nil
+	}
+
+	if registry.URL == "" {
+		return errors.NewClowderError("no url defined for the kafka schema registry")
+	}
+
+	registryConfig := &config.SchemaRegistryConfig{Url: registry.URL}
+
+	if registry.SecretRef != nil {
+		secret := &core.Secret{}
+		nn := types.NamespacedName{
+			Name:      registry.SecretRef.Name,
+			Namespace: registry.SecretRef.Namespace,
+		}
+
+		if err := p.Client.Get(p.Ctx, nn, secret); err != nil {
+			return errors.Wrap("couldn't get kafka schema registry secret", err)
+		}
+
+		if _, err := p.HashCache.CreateOrUpdateObject(secret, true); err != nil {
+			return err
+		}
+
+		if err := p.HashCache.AddClowdObjectToObject(p.Env, secret); err != nil {
+			return err
+		}
+
+		registryConfig.Username = utils.StringPtr(string(secret.Data["username"]))
+		registryConfig.Password = utils.StringPtr(string(secret.Data["password"]))
+	}
+
+	kafkaConfig.SchemaRegistry = registryConfig
+	return nil
+}
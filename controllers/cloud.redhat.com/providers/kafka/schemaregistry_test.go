@@ -0,0 +1,55 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+)
+
+func TestSchemaRegistryConfig(t *testing.T) {
+	secret := &core.Secret{}
+	secret.Name = "registry-creds"
+	secret.Namespace = "kafka"
+	secret.Data = map[string][]byte{"username": []byte("user"), "password": []byte("pass")}
+
+	hc := hashcache.NewHashCache()
+	env := &crd.ClowdEnvironment{}
+	env.Name = "env"
+	env.Status.TargetNamespace = "env-ns"
+
+	p := &providers.Provider{
+		Ctx:       context.Background(),
+		Client:    fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build(),
+		Env:       env,
+		HashCache: &hc,
+	}
+
+	kafkaConfig := &config.KafkaConfig{}
+	require.NoError(t, setExternalSchemaRegistryConfig(p, kafkaConfig))
+	assert.Nil(t, kafkaConfig.SchemaRegistry, "no registry unless it is enabled")
+
+	env.Spec.Providers.Kafka.SchemaRegistry.Enabled = true
+	assert.Error(t, setExternalSchemaRegistryConfig(p, kafkaConfig), "external registries need a url")
+
+	env.Spec.Providers.Kafka.SchemaRegistry.URL = "https://registry.example.com/apis/ccompat/v7"
+	env.Spec.Providers.Kafka.SchemaRegistry.SecretRef = &crd.NamespacedName{Name: "registry-creds", Namespace: "kafka"}
+	require.NoError(t, setExternalSchemaRegistryConfig(p, kafkaConfig))
+	require.NotNil(t, kafkaConfig.SchemaRegistry)
+	assert.Equal(t, "https://registry.example.com/apis/ccompat/v7", kafkaConfig.SchemaRegistry.Url)
+	assert.Equal(t, "user", *kafkaConfig.SchemaRegistry.Username)
+	assert.Equal(t, "pass", *kafkaConfig.SchemaRegistry.Password)
+
+	local := &config.KafkaConfig{}
+	setLocalSchemaRegistryConfig(p, local)
+	assert.Equal(t, "http://env-schema-registry.env-ns.svc:8080/apis/ccompat/v7", local.SchemaRegistry.Url)
+}
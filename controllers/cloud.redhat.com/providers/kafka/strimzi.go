@@ -45,6 +45,8 @@ var KafkaNetworkPolicy = rc.NewSingleResourceIdent(ProvName, "kafka_network_poli
 type strimziProvider struct {
 	providers.Provider
 	rootKafkaProvider
+	// The user of the Kafka Connect cluster when its credentials are rotated
+	connectUser string
 }
 
 // NewStrimzi returns a new strimzi provider object.
@@ -59,8 +61,14 @@ func NewStrimzi(p *providers.Provider) (providers.ClowderProvider, error) {
 		KafkaConnect,
 		KafkaUser,
 		KafkaConnectUser,
+		KafkaUserAlt,
+		KafkaUserCredentials,
+		KafkaConnectUserAlt,
+		KafkaConnectUserCredentials,
 		KafkaMetricsConfigMap,
 		KafkaNetworkPolicy,
+		SchemaRegistryDeployment,
+		SchemaRegistryService,
 	)
 	return &strimziProvider{Provider: *p}, nil
 }
@@ -70,6 +78,12 @@ func (s *strimziProvider) EnvProvide() error {
 		return err
 	}
 
+	if s.Env.Spec.Providers.Kafka.SchemaRegistry.Enabled {
+		if err := createSchemaRegistry(&s.Provider); err != nil {
+			return errors.Wrap("failed to provision kafka schema registry", err)
+		}
+	}
+
 	return s.configureBrokers()
 }
 
@@ -115,6 +129,8 @@ func (s *strimziProvider) Provide(app *crd.ClowdApp) error {
 		}
 	}
 
+	setLocalSchemaRegistryConfig(&s.Provider, s.Config.Kafka)
+
 	if app.Spec.Cyndi.Enabled {
 		err := createCyndiPipeline(s, app, getConnectNamespace(s.Env), getConnectClusterName(s.Env))
 		if err != nil {
@@ -428,6 +444,17 @@ func (s *strimziProvider) configureKafkaCluster() error {
 
 	k.Spec.Kafka.MetricsConfig = &metricsConfig
 
+	// the Kafka Exporter exposes the consumer group lag for the consumer lag collector to read
+	// from Prometheus
+	if _, ok := GetConsumerLagPrometheusURL(s.Env); ok && s.Env.Spec.Providers.Kafka.ConsumerLag.Enabled {
+		k.Spec.KafkaExporter = &strimzi.KafkaSpecKafkaExporter{
+			GroupRegex: utils.StringPtr(".*"),
+			TopicRegex: utils.StringPtr(".*"),
+		}
+	} else {
+		k.Spec.KafkaExporter = nil
+	}
+
 	listener := strimzi.KafkaSpecKafkaListenersElem{
 		Type: "internal",
 	}
@@ -531,8 +558,22 @@ func (s strimziProvider) connectConfig(config *apiextensions.JSON) error {
 	return config.UnmarshalJSON(rawConfig)
 }
 
+func getConnectUsername(env *crd.ClowdEnvironment) string {
+	return fmt.Sprintf("%s-connect", env.Name)
+}
+
 func (s *strimziProvider) getConnectClusterUserName() string {
-	return fmt.Sprintf("%s-connect", s.Env.Name)
+	if s.connectUser != "" {
+		return s.connectUser
+	}
+	return getConnectUsername(s.Env)
+}
+
+func (s *strimziProvider) getConnectClusterPasswordSecret() (string, string) {
+	if s.connectUser != "" {
+		return credentialsSecretName(getConnectUsername(s.Env)), s.connectUser
+	}
+	return s.getConnectClusterUserName(), "password"
 }
 
 func (s *strimziProvider) createKafkaMetricsConfigMap() (types.NamespacedName, error) {
@@ -584,7 +625,22 @@ func (s *strimziProvider) createKafkaConnectUser() error {
 
 	clusterNN := types.NamespacedName{
 		Namespace: getConnectNamespace(s.Env),
-		Name:      s.getConnectClusterUserName(),
+		Name:      getConnectUsername(s.Env),
+	}
+
+	if credentialRotationEnabled(s.Env) {
+		user, err := s.rotatedKafkaUsers(
+			clusterNN,
+			connectKafkaUserSpec,
+			s.Env.GetAnnotations()[RotateCredentialsAnnotation],
+			KafkaConnectUserCredentials,
+			[2]rc.ResourceIdent{KafkaConnectUser, KafkaConnectUserAlt},
+		)
+		if err != nil {
+			return err
+		}
+		s.connectUser = user
+		return nil
 	}
 
 	ku := &strimzi.KafkaUser{}
@@ -601,45 +657,53 @@ func (s *strimziProvider) createKafkaConnectUser() error {
 	if s.Env.Spec.Providers.Kafka.EnableLegacyStrimzi {
 		ku.Spec = &strimzi.KafkaUserSpec{}
 	} else {
-		ku.Spec = &strimzi.KafkaUserSpec{
-			Authentication: &strimzi.KafkaUserSpecAuthentication{
-				Type: strimzi.KafkaUserSpecAuthenticationTypeScramSha512,
-			},
-			Authorization: &strimzi.KafkaUserSpecAuthorization{
-				Acls: []strimzi.KafkaUserSpecAuthorizationAclsElem{},
-				Type: strimzi.KafkaUserSpecAuthorizationTypeSimple,
-			},
-		}
+		ku.Spec = connectKafkaUserSpec()
+	}
 
-		address := "*"
-		topic := "*"
-		patternType := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypeLiteral
+	return s.Cache.Update(KafkaConnectUser, ku)
+}
 
-		all := strimzi.KafkaUserSpecAuthorizationAclsElemOperationAll
+// connectKafkaUserSpec returns the spec of the Kafka Connect cluster's KafkaUser, which has full
+// access to every topic and consumer group.
+func connectKafkaUserSpec() *strimzi.KafkaUserSpec {
+	spec := &strimzi.KafkaUserSpec{
+		Authentication: &strimzi.KafkaUserSpecAuthentication{
+			Type: strimzi.KafkaUserSpecAuthenticationTypeScramSha512,
+		},
+		Authorization: &strimzi.KafkaUserSpecAuthorization{
+			Acls: []strimzi.KafkaUserSpecAuthorizationAclsElem{},
+			Type: strimzi.KafkaUserSpecAuthorizationTypeSimple,
+		},
+	}
 
-		ku.Spec.Authorization.Acls = append(ku.Spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
-			Host:      &address,
-			Operation: &all,
-			Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
-				Name:        &topic,
-				PatternType: &patternType,
-				Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeTopic,
-			},
-		})
+	address := "*"
+	topic := "*"
+	patternType := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypeLiteral
 
-		group := "*"
-		ku.Spec.Authorization.Acls = append(ku.Spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
-			Host:      &address,
-			Operation: &all,
-			Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
-				Name:        &group,
-				PatternType: &patternType,
-				Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
-			},
-		})
-	}
+	all := strimzi.KafkaUserSpecAuthorizationAclsElemOperationAll
 
-	return s.Cache.Update(KafkaConnectUser, ku)
+	spec.Authorization.Acls = append(spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
+		Host:      &address,
+		Operation: &all,
+		Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
+			Name:        &topic,
+			PatternType: &patternType,
+			Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeTopic,
+		},
+	})
+
+	group := "*"
+	spec.Authorization.Acls = append(spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
+		Host:      &address,
+		Operation: &all,
+		Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
+			Name:        &group,
+			PatternType: &patternType,
+			Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
+		},
+	})
+
+	return spec
 }
 
 func (s *strimziProvider) configureListeners() error {
@@ -680,6 +744,8 @@ func (s *strimziProvider) configureListeners() error {
 		}
 	}
 
+	setLocalSchemaRegistryConfig(&s.Provider, s.Config.Kafka)
+
 	if len(s.Config.Kafka.Brokers) < 1 {
 		return fmt.Errorf(
 			"kafka cluster '%s' in ns '%s' has no listeners", clusterNN.Name, clusterNN.Namespace,
@@ -804,6 +870,20 @@ func (s *strimziProvider) setBrokerCredentials(app *crd.ClowdApp, configs *confi
 				Namespace: getKafkaNamespace(s.Env),
 			}
 
+			// Rotated apps use whichever of their users is active, with the password Clowder
+			// generated for it
+			var kafkaSecret *core.Secret
+			passwordKey := "password"
+			if credentialRotationEnabled(s.Env) {
+				user, secret, err := s.getRotatedUser(nn)
+				if err != nil {
+					return err
+				}
+				nn.Name = user
+				kafkaSecret = secret
+				passwordKey = user
+			}
+
 			err := s.Client.Get(s.Ctx, nn, ku)
 			if err != nil {
 				return err
@@ -814,20 +894,22 @@ func (s *strimziProvider) setBrokerCredentials(app *crd.ClowdApp, configs *confi
 			}
 			broker.Sasl.Username = ku.Status.Username
 
-			if ku.Status.Secret == nil {
-				return errors.NewClowderError("no secret in kafkauser status")
-			}
+			if kafkaSecret == nil {
+				if ku.Status.Secret == nil {
+					return errors.NewClowderError("no secret in kafkauser status")
+				}
 
-			secnn := types.NamespacedName{
-				Name:      *ku.Status.Secret,
-				Namespace: getKafkaNamespace(s.Env),
-			}
+				secnn := types.NamespacedName{
+					Name:      *ku.Status.Secret,
+					Namespace: getKafkaNamespace(s.Env),
+				}
 
-			kafkaSecret := &core.Secret{}
+				kafkaSecret = &core.Secret{}
 
-			err = s.Client.Get(s.Ctx, secnn, kafkaSecret)
-			if err != nil {
-				return err
+				err = s.Client.Get(s.Ctx, secnn, kafkaSecret)
+				if err != nil {
+					return err
+				}
 			}
 
 			_, err = s.HashCache.CreateOrUpdateObject(kafkaSecret, true)
@@ -839,10 +921,10 @@ func (s *strimziProvider) setBrokerCredentials(app *crd.ClowdApp, configs *confi
 				return err
 			}
 
-			if kafkaSecret.Data["password"] == nil {
+			if kafkaSecret.Data[passwordKey] == nil {
 				return errors.NewClowderError("no password in kafkauser secret")
 			}
-			password := string(kafkaSecret.Data["password"])
+			password := string(kafkaSecret.Data[passwordKey])
 			broker.Sasl.Password = &password
 			broker.Sasl.SecurityProtocol = utils.StringPtr("SASL_SSL")
 			broker.Sasl.SaslMechanism = utils.StringPtr("SCRAM-SHA-512")
@@ -854,12 +936,41 @@ func (s *strimziProvider) setBrokerCredentials(app *crd.ClowdApp, configs *confi
 
 func (s *strimziProvider) createKafkaUser(app *crd.ClowdApp) error {
 
-	ku := &strimzi.KafkaUser{}
+	acls, err := s.appKafkaACLs(app)
+	if err != nil {
+		return err
+	}
+
+	spec := func() *strimzi.KafkaUserSpec {
+		return &strimzi.KafkaUserSpec{
+			Authentication: &strimzi.KafkaUserSpecAuthentication{
+				Type: strimzi.KafkaUserSpecAuthenticationTypeScramSha512,
+			},
+			Authorization: &strimzi.KafkaUserSpecAuthorization{
+				Acls: acls,
+				Type: strimzi.KafkaUserSpecAuthorizationTypeSimple,
+			},
+			Quotas: appKafkaQuotas(s.Env, app),
+		}
+	}
+
 	nn := types.NamespacedName{
 		Name:      getKafkaUsername(s.Env, app),
 		Namespace: getKafkaNamespace(s.Env),
 	}
 
+	if credentialRotationEnabled(s.Env) {
+		_, err := s.rotatedKafkaUsers(
+			nn,
+			spec,
+			app.GetAnnotations()[RotateCredentialsAnnotation],
+			KafkaUserCredentials,
+			[2]rc.ResourceIdent{KafkaUser, KafkaUserAlt},
+		)
+		return err
+	}
+
+	ku := &strimzi.KafkaUser{}
 	if err := s.Cache.Create(KafkaUser, nn, ku); err != nil {
 		return err
 	}
@@ -869,49 +980,144 @@ func (s *strimziProvider) createKafkaUser(app *crd.ClowdApp) error {
 
 	labeler(ku)
 
-	ku.Spec = &strimzi.KafkaUserSpec{
-		Authentication: &strimzi.KafkaUserSpecAuthentication{
-			Type: strimzi.KafkaUserSpecAuthenticationTypeScramSha512,
-		},
-		Authorization: &strimzi.KafkaUserSpecAuthorization{
-			Acls: []strimzi.KafkaUserSpecAuthorizationAclsElem{},
-			Type: strimzi.KafkaUserSpecAuthorizationTypeSimple,
-		},
+	ku.Spec = spec()
+
+	return s.Cache.Update(KafkaUser, ku)
+}
+
+// appKafkaQuotas returns the quotas for an app's KafkaUser. Each quota the app does not set falls
+// back to the environment's default, and is capped at the environment's maximum so that apps
+// created before a maximum was lowered are held to it too. It returns nil when no quota applies.
+func appKafkaQuotas(env *crd.ClowdEnvironment, app *crd.ClowdApp) *strimzi.KafkaUserSpecQuotas {
+	requested := crd.KafkaQuotaSpec{}
+	if app.Spec.KafkaQuotas != nil {
+		requested = *app.Spec.KafkaQuotas
 	}
+	defaults := env.Spec.Providers.Kafka.Quotas.Default
+	limits := env.Spec.Providers.Kafka.Quotas.Max
 
+	quota := func(value, fallback, limit *int32) *int32 {
+		if value == nil {
+			value = fallback
+		}
+		if value != nil && limit != nil && *value > *limit {
+			value = limit
+		}
+		if value == nil {
+			return nil
+		}
+		v := *value
+		return &v
+	}
+
+	quotas := &strimzi.KafkaUserSpecQuotas{
+		ProducerByteRate:  quota(requested.ProducerByteRate, defaults.ProducerByteRate, limits.ProducerByteRate),
+		ConsumerByteRate:  quota(requested.ConsumerByteRate, defaults.ConsumerByteRate, limits.ConsumerByteRate),
+		RequestPercentage: quota(requested.RequestPercentage, defaults.RequestPercentage, limits.RequestPercentage),
+	}
+	if quotas.ProducerByteRate == nil && quotas.ConsumerByteRate == nil && quotas.RequestPercentage == nil {
+		return nil
+	}
+	return quotas
+}
+
+// appKafkaACLs returns the ACLs for an app's KafkaUser. Apps that declare a role or consumer groups
+// on any of their topics are only granted the operations those need, others keep full access to
+// their topics and to every consumer group.
+func (s *strimziProvider) appKafkaACLs(app *crd.ClowdApp) ([]strimzi.KafkaUserSpecAuthorizationAclsElem, error) {
+	acls := []strimzi.KafkaUserSpecAuthorizationAclsElem{}
 	address := "*"
-	patternType := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypeLiteral
+	literal := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypeLiteral
+	prefix := strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypePrefix
 	all := strimzi.KafkaUserSpecAuthorizationAclsElemOperationAll
 
-	for _, topic := range app.Spec.KafkaTopics {
+	scoped := usesTopicRoles(app)
+	groups := []string{}
+
+	for _, topic := range app.GetKafkaTopics() {
 		topicName, err := s.KafkaTopicName(topic, app.Namespace)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		ku.Spec.Authorization.Acls = append(ku.Spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
-			Host:      &address,
-			Operation: &all,
+		acl := strimzi.KafkaUserSpecAuthorizationAclsElem{
+			Host: &address,
 			Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
 				Name:        &topicName,
-				PatternType: &patternType,
+				PatternType: &literal,
 				Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeTopic,
 			},
+		}
+
+		if !scoped {
+			acl.Operation = &all
+			acls = append(acls, acl)
+			continue
+		}
+
+		acl.Operations = []strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElem{
+			strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemDescribe,
+		}
+		if topic.CanConsume() {
+			acl.Operations = append(acl.Operations, strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemRead)
+		}
+		if topic.CanProduce() {
+			acl.Operations = append(acl.Operations, strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemWrite)
+		}
+		acls = append(acls, acl)
+
+		for _, group := range topic.ConsumerGroups {
+			// The webhook rejects empty names, they are skipped here too as a prefix ACL for an
+			// empty name would grant every consumer group in the cluster
+			if strings.TrimSpace(group) == "" {
+				continue
+			}
+			if !utils.Contains(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	if !scoped {
+		group := "*"
+		acls = append(acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
+			Host:      &address,
+			Operation: &all,
+			Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
+				Name:        &group,
+				PatternType: &literal,
+				Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
+			},
 		})
+		return acls, nil
 	}
 
-	group := "*"
-	ku.Spec.Authorization.Acls = append(ku.Spec.Authorization.Acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
-		Host:      &address,
-		Operation: &all,
-		Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
-			Name:        &group,
-			PatternType: &patternType,
-			Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
-		},
-	})
+	for i := range groups {
+		acls = append(acls, strimzi.KafkaUserSpecAuthorizationAclsElem{
+			Host: &address,
+			Operations: []strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElem{
+				strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemDescribe,
+				strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemRead,
+			},
+			Resource: strimzi.KafkaUserSpecAuthorizationAclsElemResource{
+				Name:        &groups[i],
+				PatternType: &prefix,
+				Type:        strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup,
+			},
+		})
+	}
 
-	return s.Cache.Update(KafkaUser, ku)
+	return acls, nil
+}
+
+// usesTopicRoles returns true if the app declares a role or consumer groups on any of its topics.
+func usesTopicRoles(app *crd.ClowdApp) bool {
+	for _, topic := range app.GetKafkaTopics() {
+		if topic.Role != "" || len(topic.ConsumerGroups) != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *strimziProvider) KafkaTopicName(topic crd.KafkaTopicSpec, namespace ...string) (string, error) {
@@ -0,0 +1,84 @@
+package kafka
+
+import (
+	"testing"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func TestAppKafkaACLs(t *testing.T) {
+	s := &strimziProvider{Provider: providers.Provider{Env: &crd.ClowdEnvironment{}}}
+
+	app := &crd.ClowdApp{}
+	app.Namespace = "test"
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{{TopicName: "legacy"}}
+
+	acls, err := s.appKafkaACLs(app)
+	require.NoError(t, err)
+	require.Len(t, acls, 2, "apps without roles keep the previous ACLs")
+	assert.Equal(t, strimzi.KafkaUserSpecAuthorizationAclsElemOperationAll, *acls[0].Operation)
+	assert.Equal(t, "*", *acls[1].Resource.Name)
+
+	app.Spec.KafkaTopics = []crd.KafkaTopicSpec{
+		{TopicName: "ingress", Role: crd.KafkaTopicRoleProduce},
+		{TopicName: "events", Role: crd.KafkaTopicRoleConsume, ConsumerGroups: []string{"processor"}},
+		{TopicName: "retries", ConsumerGroups: []string{"processor"}},
+	}
+
+	acls, err = s.appKafkaACLs(app)
+	require.NoError(t, err)
+	require.Len(t, acls, 4, "a group shared between topics is granted once")
+
+	describe := strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemDescribe
+	read := strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemRead
+	write := strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElemWrite
+
+	for _, acl := range acls {
+		assert.Nil(t, acl.Operation, "no ACL grants All")
+	}
+
+	assert.Equal(t, "ingress", *acls[0].Resource.Name)
+	assert.ElementsMatch(t, []strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElem{describe, write}, acls[0].Operations)
+	assert.Equal(t, "events", *acls[1].Resource.Name)
+	assert.ElementsMatch(t, []strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElem{describe, read}, acls[1].Operations)
+	assert.Equal(t, "retries", *acls[2].Resource.Name)
+	assert.ElementsMatch(t, []strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElem{describe, read, write}, acls[2].Operations)
+
+	assert.Equal(t, strimzi.KafkaUserSpecAuthorizationAclsElemResourceTypeGroup, acls[3].Resource.Type)
+	assert.Equal(t, "processor", *acls[3].Resource.Name)
+	assert.Equal(t, strimzi.KafkaUserSpecAuthorizationAclsElemResourcePatternTypePrefix, *acls[3].Resource.PatternType)
+	assert.ElementsMatch(t, []strimzi.KafkaUserSpecAuthorizationAclsElemOperationsElem{describe, read}, acls[3].Operations)
+}
+
+func TestAppKafkaQuotas(t *testing.T) {
+	env := &crd.ClowdEnvironment{}
+	app := &crd.ClowdApp{}
+
+	assert.Nil(t, appKafkaQuotas(env, app), "no quotas without app or env quotas")
+
+	env.Spec.Providers.Kafka.Quotas.Default = crd.KafkaQuotaSpec{
+		ProducerByteRate:  utils.Int32Ptr(1048576),
+		RequestPercentage: utils.Int32Ptr(50),
+	}
+	env.Spec.Providers.Kafka.Quotas.Max = crd.KafkaQuotaSpec{
+		ConsumerByteRate:  utils.Int32Ptr(4194304),
+		RequestPercentage: utils.Int32Ptr(25),
+	}
+	app.Spec.KafkaQuotas = &crd.KafkaQuotaSpec{
+		ProducerByteRate: utils.Int32Ptr(2097152),
+		ConsumerByteRate: utils.Int32Ptr(8388608),
+	}
+
+	assert.Equal(t, &strimzi.KafkaUserSpecQuotas{
+		ProducerByteRate:  utils.Int32Ptr(2097152),
+		ConsumerByteRate:  utils.Int32Ptr(4194304),
+		RequestPercentage: utils.Int32Ptr(25),
+	}, appKafkaQuotas(env, app), "app quotas override the defaults and all are capped at the maximums")
+}
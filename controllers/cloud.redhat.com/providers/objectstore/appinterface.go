@@ -2,11 +2,18 @@
 package objectstore
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
+	"sync"
 
 	"github.com/RedHatInsights/rhc-osdk-utils/utils"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
@@ -19,6 +26,26 @@ type appInterfaceObjectstoreProvider struct {
 	providers.Provider
 }
 
+// bucketInspector reads the settings a provisioned bucket has, using the bucket's own credentials.
+type bucketInspector func(ctx context.Context, bucket config.ObjectStoreBucket) (crd.ObjectStoreBucketSpec, error)
+
+// inspectBucket is the bucketInspector used to check the buckets, it is replaced in tests.
+var inspectBucket bucketInspector = inspectS3Bucket
+
+// bucketCheck is the outcome of the last check of an app's buckets, along with a hash of the
+// options and bucket secrets it was made against.
+type bucketCheck struct {
+	hash       string
+	mismatches []string
+}
+
+// bucketChecks holds the last check of each app's buckets, so that S3 is only read again once
+// the app's options or its bucket secrets change.
+var bucketChecks = struct {
+	sync.Mutex
+	checks map[types.NamespacedName]bucketCheck
+}{checks: map[types.NamespacedName]bucketCheck{}}
+
 // NewAppInterfaceObjectstore returns a new app-interface object store provider object.
 func NewAppInterfaceObjectstore(p *providers.Provider) (providers.ClowderProvider, error) {
 	return &appInterfaceObjectstoreProvider{Provider: *p}, nil
@@ -57,11 +84,20 @@ func (a *appInterfaceObjectstoreProvider) Provide(app *crd.ClowdApp) error {
 	return nil
 }
 
-func resolveBucketDeps(requestedBuckets []string, c *config.ObjectStoreConfig) error {
+// FinalizeApp forgets the last check of the app's buckets.
+func (a *appInterfaceObjectstoreProvider) FinalizeApp(app *crd.ClowdApp) error {
+	bucketChecks.Lock()
+	defer bucketChecks.Unlock()
+	delete(bucketChecks.checks, types.NamespacedName{Name: app.Name, Namespace: app.Namespace})
+	return nil
+}
+
+func resolveBucketDeps(requestedBuckets []crd.ObjectStoreBucketSpec, c *config.ObjectStoreConfig) error {
 	buckets := []config.ObjectStoreBucket{}
 	missing := []string{}
 
-	for _, requestedBucket := range requestedBuckets {
+	for _, requested := range requestedBuckets {
+		requestedBucket := requested.Name
 		found := false
 		for _, bucket := range c.Buckets {
 			if strings.HasPrefix(bucket.Name, requestedBucket) {
@@ -94,6 +130,153 @@ func resolveBucketDeps(requestedBuckets []string, c *config.ObjectStoreConfig) e
 	return nil
 }
 
+// CheckBucketSettings compares the settings of the provisioned buckets the app declares options
+// for with those options, and returns a description of each mismatch. Only app-interface buckets
+// are checked, as Clowder applies the options itself in the other modes; checked is false when
+// there is nothing to check. S3 is only read when the options or bucket secrets have changed since
+// the app was last checked. Bucket quotas are not checked as S3 has none.
+func CheckBucketSettings(ctx context.Context, env *crd.ClowdEnvironment, app *crd.ClowdApp, c *config.ObjectStoreConfig) (mismatches []string, checked bool, err error) {
+	if env.Spec.Providers.ObjectStore.Mode != "app-interface" || c == nil {
+		return nil, false, nil
+	}
+
+	requested := []crd.ObjectStoreBucketSpec{}
+	buckets := []config.ObjectStoreBucket{}
+	for _, spec := range app.Spec.ObjectStore {
+		if len(spec.Expiration) == 0 && !spec.Versioning {
+			continue
+		}
+		requested = append(requested, spec)
+		for _, bucket := range c.Buckets {
+			if bucket.RequestedName == spec.Name {
+				buckets = append(buckets, bucket)
+			}
+		}
+	}
+
+	if len(requested) == 0 {
+		return nil, false, nil
+	}
+
+	hash, err := hashBucketCheck(requested, buckets)
+	if err != nil {
+		return nil, false, err
+	}
+
+	nn := types.NamespacedName{Name: app.Name, Namespace: app.Namespace}
+
+	bucketChecks.Lock()
+	last, ok := bucketChecks.checks[nn]
+	bucketChecks.Unlock()
+	if ok && last.hash == hash {
+		return last.mismatches, true, nil
+	}
+
+	mismatches = []string{}
+	for _, spec := range requested {
+		for _, bucket := range buckets {
+			if bucket.RequestedName != spec.Name {
+				continue
+			}
+
+			settings, err := inspectBucket(ctx, bucket)
+			if err != nil {
+				return nil, false, errors.Wrap(fmt.Sprintf("Could not read the settings of bucket %s", bucket.Name), err)
+			}
+
+			for _, mismatch := range compareBucketSettings(spec, settings) {
+				mismatches = append(mismatches, fmt.Sprintf("%s: %s", spec.Name, mismatch))
+			}
+		}
+	}
+
+	bucketChecks.Lock()
+	bucketChecks.checks[nn] = bucketCheck{hash: hash, mismatches: mismatches}
+	bucketChecks.Unlock()
+
+	return mismatches, true, nil
+}
+
+// hashBucketCheck returns a hash of what the buckets are checked against: the options the app
+// declares and the bucket secrets' endpoints, names and keys.
+func hashBucketCheck(requested []crd.ObjectStoreBucketSpec, buckets []config.ObjectStoreBucket) (string, error) {
+	data, err := json.Marshal(struct {
+		Requested []crd.ObjectStoreBucketSpec
+		Buckets   []config.ObjectStoreBucket
+	}{requested, buckets})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// compareBucketSettings returns how the settings a bucket has differ from the ones requested.
+func compareBucketSettings(requested crd.ObjectStoreBucketSpec, actual crd.ObjectStoreBucketSpec) []string {
+	mismatches := []string{}
+
+	if requested.Versioning && !actual.Versioning {
+		mismatches = append(mismatches, "versioning is not enabled")
+	}
+
+	hasRule := func(rules []crd.ObjectStoreExpirationRule, rule crd.ObjectStoreExpirationRule) bool {
+		for _, r := range rules {
+			if r == rule {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, rule := range requested.Expiration {
+		if !hasRule(actual.Expiration, rule) {
+			mismatches = append(mismatches, fmt.Sprintf("objects under %q do not expire after %d days", rule.Prefix, rule.Days))
+		}
+	}
+	for _, rule := range actual.Expiration {
+		if !hasRule(requested.Expiration, rule) {
+			mismatches = append(mismatches, fmt.Sprintf("objects under %q expire after %d days but no such rule is declared", rule.Prefix, rule.Days))
+		}
+	}
+
+	return mismatches
+}
+
+// inspectS3Bucket reads the settings of a bucket from its S3 endpoint.
+func inspectS3Bucket(ctx context.Context, bucket config.ObjectStoreBucket) (crd.ObjectStoreBucketSpec, error) {
+	endpoint := "s3.amazonaws.com"
+	if bucket.Endpoint != nil && *bucket.Endpoint != "" {
+		endpoint = *bucket.Endpoint
+	}
+	secure := bucket.Tls == nil || *bucket.Tls
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		endpoint = u.Host
+		secure = u.Scheme != "http"
+	}
+
+	accessKey, secretKey := "", ""
+	if bucket.AccessKey != nil {
+		accessKey = *bucket.AccessKey
+	}
+	if bucket.SecretKey != nil {
+		secretKey = *bucket.SecretKey
+	}
+
+	region := ""
+	if bucket.Region != nil {
+		region = *bucket.Region
+	}
+
+	cl, err := newS3Client(endpoint, secure, region, accessKey, secretKey, nil)
+	if err != nil {
+		return crd.ObjectStoreBucketSpec{}, err
+	}
+
+	handler := &minioHandler{Client: cl}
+	return handler.GetSettings(ctx, bucket.Name)
+}
+
 func genObjStoreConfig(secrets []core.Secret) (*config.ObjectStoreConfig, error) {
 	buckets := []config.ObjectStoreBucket{}
 	objectStoreConfig := config.ObjectStoreConfig{Port: 443}
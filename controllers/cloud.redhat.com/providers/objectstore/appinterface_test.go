@@ -1,12 +1,17 @@
 package objectstore
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
 
 	"github.com/RedHatInsights/rhc-osdk-utils/utils"
 )
@@ -67,7 +72,7 @@ func TestAppInterfaceObjectStore(t *testing.T) {
 
 	assert.NoError(t, err, "error calling genObjStoreConfig")
 
-	err = resolveBucketDeps([]string{"test-bucket"}, c)
+	err = resolveBucketDeps([]crd.ObjectStoreBucketSpec{{Name: "test-bucket"}}, c)
 
 	assert.NoError(t, err, "error calling resolveBucketDeps")
 
@@ -102,7 +107,7 @@ func TestAppInterfaceBadBucket(t *testing.T) {
 			Tls:           utils.TruePtr(),
 		}},
 	}
-	err := resolveBucketDeps([]string{"test-bucket"}, &c)
+	err := resolveBucketDeps([]crd.ObjectStoreBucketSpec{{Name: "test-bucket"}}, &c)
 
 	assert.Error(t, err)
 }
@@ -139,9 +144,72 @@ func TestAppInterfaceGoodAndBadBucket(t *testing.T) {
 			},
 		},
 	}
-	err := resolveBucketDeps([]string{"test-bucket"}, &c)
+	err := resolveBucketDeps([]crd.ObjectStoreBucketSpec{{Name: "test-bucket"}}, &c)
 
 	assert.NoError(t, err)
 	assert.Len(t, c.Buckets, 1)
 	assert.Equal(t, c.Hostname, "test-endpoint")
 }
+
+func TestAppInterfaceBucketSettings(t *testing.T) {
+	inspected := []string{}
+	inspectBucket = func(_ context.Context, bucket config.ObjectStoreBucket) (crd.ObjectStoreBucketSpec, error) {
+		inspected = append(inspected, bucket.Name)
+		return crd.ObjectStoreBucketSpec{
+			Name:       bucket.Name,
+			Expiration: []crd.ObjectStoreExpirationRule{{Prefix: "tmp/", Days: 7}},
+		}, nil
+	}
+	defer func() { inspectBucket = inspectS3Bucket }()
+
+	env := &crd.ClowdEnvironment{}
+	env.Spec.Providers.ObjectStore.Mode = "app-interface"
+	app := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"},
+		Spec: crd.ClowdAppSpec{ObjectStore: []crd.ObjectStoreBucketSpec{
+			{Name: "logs", Expiration: []crd.ObjectStoreExpirationRule{{Prefix: "tmp/", Days: 7}}},
+			{Name: "data"},
+		}},
+	}
+	c := &config.ObjectStoreConfig{
+		Buckets: []config.ObjectStoreBucket{
+			{Name: "logs-abc", RequestedName: "logs", AccessKey: utils.StringPtr("key")},
+			{Name: "data-abc", RequestedName: "data"},
+		},
+	}
+
+	mismatches, checked, err := CheckBucketSettings(context.TODO(), env, app, c)
+	assert.NoError(t, err)
+	assert.True(t, checked)
+	assert.Empty(t, mismatches)
+	assert.Equal(t, []string{"logs-abc"}, inspected, "only buckets with options are inspected")
+
+	_, _, err = CheckBucketSettings(context.TODO(), env, app, c)
+	assert.NoError(t, err)
+	assert.Len(t, inspected, 1, "unchanged buckets are not inspected again")
+
+	c.Buckets[0].AccessKey = utils.StringPtr("rotated")
+	_, _, err = CheckBucketSettings(context.TODO(), env, app, c)
+	assert.NoError(t, err)
+	assert.Len(t, inspected, 2, "a changed bucket secret is inspected again")
+
+	app.Spec.ObjectStore[0].Versioning = true
+	app.Spec.ObjectStore[0].Expiration[0].Days = 30
+	mismatches, checked, err = CheckBucketSettings(context.TODO(), env, app, c)
+	assert.NoError(t, err)
+	assert.True(t, checked)
+	assert.Equal(t, []string{
+		"logs: versioning is not enabled",
+		`logs: objects under "tmp/" do not expire after 30 days`,
+		`logs: objects under "tmp/" expire after 7 days but no such rule is declared`,
+	}, mismatches)
+
+	a := &appInterfaceObjectstoreProvider{Provider: providers.Provider{Ctx: context.TODO()}}
+	assert.NoError(t, a.FinalizeApp(app))
+	assert.NotContains(t, bucketChecks.checks, types.NamespacedName{Name: "app", Namespace: "test"})
+
+	env.Spec.Providers.ObjectStore.Mode = "minio"
+	_, checked, err = CheckBucketSettings(context.TODO(), env, app, c)
+	assert.NoError(t, err)
+	assert.False(t, checked, "buckets Clowder configures itself are not checked")
+}
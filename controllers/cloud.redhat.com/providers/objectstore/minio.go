@@ -2,14 +2,25 @@ package objectstore
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/minio/madmin-go/v3"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/minio/minio-go/v7/pkg/tags"
 	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -40,6 +51,9 @@ var MinioSecret = rc.NewSingleResourceIdent(ProvName, "minio_db_secret", &core.S
 // MinioNetworkPolicy is the resource ident for the KafkaNetworkPolicy
 var MinioNetworkPolicy = rc.NewSingleResourceIdent(ProvName, "minio_network_policy", &networking.NetworkPolicy{})
 
+// MinioAppSecret is the resource ident for the secret holding an app's MinIO credentials.
+var MinioAppSecret = rc.NewSingleResourceIdent(ProvName, "minio_app_secret", &core.Secret{})
+
 // minio is an object store provider that deploys and configures MinIO
 type minioProvider struct {
 	providers.Provider
@@ -54,6 +68,7 @@ func NewMinIO(p *providers.Provider) (providers.ClowderProvider, error) {
 		MinioPVC,
 		MinioSecret,
 		MinioNetworkPolicy,
+		MinioAppSecret,
 	)
 
 	nn := providers.GetNamespacedName(p.Env, "minio")
@@ -69,7 +84,12 @@ func NewMinIO(p *providers.Provider) (providers.ClowderProvider, error) {
 		return nil, raisedErr
 	}
 
-	mp, err := createMinioProvider(p, *secMap, &minioHandler{})
+	var handler bucketHandler = &minioHandler{}
+	if p.Offline {
+		handler = &offlineHandler{}
+	}
+
+	mp, err := createMinioProvider(p, *secMap, handler)
 
 	if err != nil {
 		return nil, err
@@ -99,10 +119,11 @@ func (m *minioProvider) EnvProvide() error {
 	return createNetworkPolicy(&m.Provider)
 }
 
-// Provide creates new buckets
+// Provide creates new buckets, and a MinIO user for the app that can only access them
 func (m *minioProvider) Provide(app *crd.ClowdApp) error {
 	if len(app.Spec.ObjectStore) == 0 {
-		return nil
+		// the app may have dropped all of its buckets, its user has nothing left to access
+		return m.removeAppUser(app)
 	}
 
 	secret := &core.Secret{}
@@ -125,51 +146,145 @@ func (m *minioProvider) Provide(app *crd.ClowdApp) error {
 		return err
 	}
 
+	dataInit := func() map[string]string {
+		return map[string]string{
+			"accessKey": utils.RandString(16),
+			"secretKey": utils.RandString(32),
+		}
+	}
+
+	// MakeOrGetSecret will set data if it already exists
+	appSecMap, err := providers.MakeOrGetSecret(app, m.Cache, MinioAppSecret, getAppSecretName(app), dataInit)
+	if err != nil {
+		return errors.Wrap("Couldn't set/get app secret", err)
+	}
+	accessKey := (*appSecMap)["accessKey"]
+	secretKey := (*appSecMap)["secretKey"]
+
 	m.Config.ObjectStore = &config.ObjectStoreConfig{
 		Hostname:  string(secret.Data["hostname"]),
 		Port:      int(port),
-		AccessKey: utils.StringPtr(string(secret.Data["accessKey"])),
-		SecretKey: utils.StringPtr(string(secret.Data["secretKey"])),
+		AccessKey: utils.StringPtr(accessKey),
+		SecretKey: utils.StringPtr(secretKey),
 		Tls:       false,
 		Buckets:   []config.ObjectStoreBucket{},
 	}
 
 	for _, bucket := range app.Spec.ObjectStore {
-		found, err := m.BucketHandler.Exists(m.Ctx, bucket)
+		found, err := m.BucketHandler.Exists(m.Ctx, bucket.Name)
 
 		if err != nil {
-			return newBucketError(bucketCheckErrorMsg, bucket, err)
+			return newBucketError(bucketCheckErrorMsg, bucket.Name, err)
 		}
 
 		if !found {
-			err = m.BucketHandler.Make(m.Ctx, bucket)
+			err = m.BucketHandler.Make(m.Ctx, bucket.Name)
 
 			if err != nil {
-				return newBucketError(bucketCreateErrorMsg, bucket, err)
+				return newBucketError(bucketCreateErrorMsg, bucket.Name, err)
 			}
 		}
 
+		// Every bucket is configured, so that settings removed from the spec are removed from
+		// the bucket as well
+		if err := m.BucketHandler.Configure(m.Ctx, bucket); err != nil {
+			return newBucketError(bucketConfigureErrorMsg, bucket.Name, err)
+		}
+
+		if err := m.seedBucket(app, bucket, !found); err != nil {
+			return newBucketError(bucketSeedErrorMsg, bucket.Name, err)
+		}
+
+		eventTopics, err := m.setBucketNotifications(app, bucket)
+		if err != nil {
+			return newBucketError(bucketNotificationsErrorMsg, bucket.Name, err)
+		}
+
 		newBucket := config.ObjectStoreBucket{
-			Name:          bucket,
-			RequestedName: bucket,
+			Name:          bucket.Name,
+			RequestedName: bucket.Name,
 			Endpoint:      utils.StringPtr(string(secret.Data["hostname"])),
+			EventTopics:   eventTopics,
 		}
 
-		if string(secret.Data["accessKey"]) != "" {
+		if accessKey != "" {
 			newBucket.AccessKey = m.Config.ObjectStore.AccessKey
 		}
-		if string(secret.Data["secretKey"]) != "" {
+		if secretKey != "" {
 			newBucket.SecretKey = m.Config.ObjectStore.SecretKey
 		}
 
 		m.Config.ObjectStore.Buckets = append(m.Config.ObjectStore.Buckets, newBucket)
 	}
 
+	if err := m.BucketHandler.SetAppUser(m.Ctx, getAppPolicyName(app), accessKey, secretKey, app.GetObjectStoreBucketNames()); err != nil {
+		newErr := errors.Wrap("failed to set the app's minio user", err)
+		newErr.Requeue = true
+		return newErr
+	}
+
 	return nil
 }
 
+// FinalizeApp removes the Kafka notification targets, MinIO user and policy that were created for
+// the app.
+func (m *minioProvider) FinalizeApp(app *crd.ClowdApp) error {
+	if err := m.BucketHandler.RemoveKafkaTargets(m.Ctx, getKafkaTargetOwner(app), app.GetObjectStoreBucketNames()); err != nil {
+		return errors.Wrap("failed to remove the app's kafka notification targets", err)
+	}
+
+	return m.removeAppUser(app)
+}
+
+// removeAppUser removes the app's MinIO user and policy, if the app has ever had them.
+func (m *minioProvider) removeAppUser(app *crd.ClowdApp) error {
+	secret := &core.Secret{}
+	if err := m.Client.Get(m.Ctx, getAppSecretName(app), secret); err != nil {
+		if k8serr.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	return m.BucketHandler.RemoveAppUser(m.Ctx, getAppPolicyName(app), string(secret.Data["accessKey"]))
+}
+
+func getAppSecretName(app *crd.ClowdApp) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      fmt.Sprintf("%v-objectstore", app.Name),
+		Namespace: app.Namespace,
+	}
+}
+
+// getAppPolicyName returns the name of the app's MinIO policy, which is unique across the
+// namespaces sharing the environment's MinIO.
+func getAppPolicyName(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%v-%v", app.Namespace, app.Name)
+}
+
+// appBucketPolicy returns a policy that grants every action on the given buckets and their
+// objects, and nothing else.
+func appBucketPolicy(buckets []string) ([]byte, error) {
+	resources := []string{}
+	for _, bucket := range buckets {
+		resources = append(resources, fmt.Sprintf("arn:aws:s3:::%s", bucket), fmt.Sprintf("arn:aws:s3:::%s/*", bucket))
+	}
+
+	return json.Marshal(map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{{
+			"Effect":   "Allow",
+			"Action":   []string{"s3:*"},
+			"Resource": resources,
+		}},
+	})
+}
+
 const bucketCheckErrorMsg = "failed to check if bucket exists"
 const bucketCreateErrorMsg = "failed to create bucket"
+const bucketConfigureErrorMsg = "failed to configure bucket"
+const bucketSeedErrorMsg = "failed to seed bucket"
+const bucketNotificationsErrorMsg = "failed to set bucket notifications"
 
 func newBucketError(msg string, bucketName string, rootCause error) error {
 	newErr := errors.Wrap(fmt.Sprintf("bucket %q -- %s", bucketName, msg), rootCause)
@@ -181,12 +296,23 @@ func newBucketError(msg string, bucketName string, rootCause error) error {
 type bucketHandler interface {
 	Exists(ctx context.Context, bucketName string) (bool, error)
 	Make(ctx context.Context, bucketName string) error
+	Configure(ctx context.Context, bucket crd.ObjectStoreBucketSpec) error
+	GetSettings(ctx context.Context, bucketName string) (crd.ObjectStoreBucketSpec, error)
+	GetSeedRevision(ctx context.Context, bucketName string) (string, error)
+	SetSeedRevision(ctx context.Context, bucketName string, revision string) error
+	Upload(ctx context.Context, bucketName string, objectName string, reader io.Reader, size int64) error
+	SetKafkaTarget(ctx context.Context, target kafkaTarget) error
+	SetNotifications(ctx context.Context, bucketName string, notifications []bucketNotification) error
+	RemoveKafkaTargets(ctx context.Context, owner string, buckets []string) error
 	CreateClient(hostname string, port int, accessKey *string, secretKey *string) error
+	SetAppUser(ctx context.Context, name string, accessKey string, secretKey string, buckets []string) error
+	RemoveAppUser(ctx context.Context, name string, accessKey string) error
 }
 
 // minioHandler will implement the above interface using minio-go
 type minioHandler struct {
 	Client *minio.Client
+	Admin  *madmin.AdminClient
 }
 
 func (h *minioHandler) Exists(ctx context.Context, bucketName string) (bool, error) {
@@ -197,6 +323,413 @@ func (h *minioHandler) Make(ctx context.Context, bucketName string) error {
 	return h.Client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
 }
 
+// Configure applies the expiration rules, versioning and quota of the bucket, replacing any it
+// had before.
+func (h *minioHandler) Configure(ctx context.Context, bucket crd.ObjectStoreBucketSpec) error {
+	if err := h.Client.SetBucketLifecycle(ctx, bucket.Name, bucketLifecycle(bucket)); err != nil {
+		return errors.Wrap("failed to set expiration", err)
+	}
+
+	if bucket.Versioning {
+		if err := h.Client.EnableVersioning(ctx, bucket.Name); err != nil {
+			return errors.Wrap("failed to enable versioning", err)
+		}
+	} else {
+		versioning, err := h.Client.GetBucketVersioning(ctx, bucket.Name)
+		if err != nil {
+			return errors.Wrap("failed to get versioning", err)
+		}
+		if versioning.Enabled() {
+			if err := h.Client.SuspendVersioning(ctx, bucket.Name); err != nil {
+				return errors.Wrap("failed to suspend versioning", err)
+			}
+		}
+	}
+
+	// Quotas need the MinIO admin API, which other S3 compatible servers do not have
+	if h.Admin == nil {
+		return nil
+	}
+
+	// An empty quota removes any quota the bucket had
+	quota := &madmin.BucketQuota{}
+	if bucket.Quota != nil {
+		size := uint64(bucket.Quota.Value())
+		quota = &madmin.BucketQuota{Quota: size, Size: size, Type: madmin.HardQuota}
+	}
+	if err := h.Admin.SetBucketQuota(ctx, bucket.Name, quota); err != nil {
+		return errors.Wrap("failed to set quota", err)
+	}
+
+	return nil
+}
+
+// GetSettings returns the expiration rules and versioning the bucket has. Quotas are not read
+// back as S3 has none.
+func (h *minioHandler) GetSettings(ctx context.Context, bucketName string) (crd.ObjectStoreBucketSpec, error) {
+	settings := crd.ObjectStoreBucketSpec{Name: bucketName}
+
+	lc, err := h.Client.GetBucketLifecycle(ctx, bucketName)
+	if err != nil && minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" {
+		return settings, err
+	}
+	if lc != nil {
+		for _, rule := range lc.Rules {
+			if rule.Status != "Enabled" || rule.Expiration.Days == 0 {
+				continue
+			}
+			prefix := rule.Prefix
+			if rule.RuleFilter.Prefix != "" {
+				prefix = rule.RuleFilter.Prefix
+			}
+			settings.Expiration = append(settings.Expiration, crd.ObjectStoreExpirationRule{
+				Prefix: prefix,
+				Days:   int32(rule.Expiration.Days),
+			})
+		}
+	}
+
+	versioning, err := h.Client.GetBucketVersioning(ctx, bucketName)
+	if err != nil {
+		return settings, err
+	}
+	settings.Versioning = versioning.Enabled()
+
+	return settings, nil
+}
+
+// GetSeedRevision returns the revision of the seed last uploaded to the bucket, if any.
+func (h *minioHandler) GetSeedRevision(ctx context.Context, bucketName string) (string, error) {
+	bucketTags, err := h.Client.GetBucketTagging(ctx, bucketName)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == minio.NoSuchTagSet {
+			return "", nil
+		}
+		return "", err
+	}
+	return bucketTags.ToMap()[seedRevisionTag], nil
+}
+
+// SetSeedRevision records the revision of the seed uploaded to the bucket, keeping its other tags.
+func (h *minioHandler) SetSeedRevision(ctx context.Context, bucketName string, revision string) error {
+	tagMap := map[string]string{}
+	bucketTags, err := h.Client.GetBucketTagging(ctx, bucketName)
+	if err != nil && minio.ToErrorResponse(err).Code != minio.NoSuchTagSet {
+		return err
+	}
+	if err == nil {
+		tagMap = bucketTags.ToMap()
+	}
+	tagMap[seedRevisionTag] = revision
+
+	bucketTags, err = tags.MapToBucketTags(tagMap)
+	if err != nil {
+		return err
+	}
+	return h.Client.SetBucketTagging(ctx, bucketName, bucketTags)
+}
+
+func (h *minioHandler) Upload(ctx context.Context, bucketName string, objectName string, reader io.Reader, size int64) error {
+	_, err := h.Client.PutObject(ctx, bucketName, objectName, reader, size, minio.PutObjectOptions{})
+	return err
+}
+
+// SetKafkaTarget creates or updates the Kafka notification target. MinIO is shared by every app
+// in the environment, so releases that only apply targets on a restart are rejected rather than
+// restarted. Targets already set to the same config are left alone.
+func (h *minioHandler) SetKafkaTarget(ctx context.Context, target kafkaTarget) error {
+	kv, checksum := kafkaTargetConfig(target)
+
+	current, err := h.Admin.GetConfigKV(ctx, fmt.Sprintf("%s:%s", madmin.NotifyKafkaSubSys, target.ID))
+	if err == nil {
+		configs, err := madmin.ParseServerConfigOutput(string(current))
+		if err == nil {
+			for _, c := range configs {
+				if comment, _ := c.Lookup(madmin.CommentKey); c.Target == target.ID && comment == checksum {
+					return nil
+				}
+			}
+		}
+	}
+
+	if err := h.checkKafkaTargetRelease(ctx); err != nil {
+		return err
+	}
+
+	restart, err := h.Admin.SetConfigKV(ctx, kv)
+	if err != nil {
+		return err
+	}
+
+	if restart {
+		// A release the check could not tell apart. The target is taken back out, and not left to
+		// start with whatever next restarts MinIO
+		if _, err := h.Admin.DelConfigKV(ctx, fmt.Sprintf("%s:%s", madmin.NotifyKafkaSubSys, target.ID)); err != nil {
+			return err
+		}
+		return newKafkaTargetReleaseError("")
+	}
+	return nil
+}
+
+// minKafkaTargetRelease is the date of the first MinIO releases that apply Kafka notification
+// targets while running, the oldest that bucket notifications can be used with.
+var minKafkaTargetRelease = time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// checkKafkaTargetRelease returns an error if MinIO is older than minKafkaTargetRelease. Servers
+// whose release cannot be read are left for SetConfigKV to tell.
+func (h *minioHandler) checkKafkaTargetRelease(ctx context.Context) error {
+	info, err := h.Admin.ServerInfo(ctx)
+	if err != nil {
+		return nil
+	}
+
+	for _, server := range info.Servers {
+		released, err := time.Parse(time.RFC3339, server.Version)
+		if err == nil && released.Before(minKafkaTargetRelease) {
+			return newKafkaTargetReleaseError(server.Version)
+		}
+	}
+	return nil
+}
+
+func newKafkaTargetReleaseError(version string) error {
+	running := "an older MinIO"
+	if version != "" {
+		running = fmt.Sprintf("MinIO %s", version)
+	}
+	return errors.NewClowderError(fmt.Sprintf("the environment runs %s, which only applies kafka notification targets on a restart; bucket notifications need a release from %s or later, set with objectStore.images.minio", running, minKafkaTargetRelease.Format(time.DateOnly)))
+}
+
+// SetNotifications replaces the notifications Clowder set on the bucket with ones publishing to
+// the Kafka targets, leaving any others alone. Nothing is written when there are neither
+// notifications to set nor ones from Clowder to clear.
+func (h *minioHandler) SetNotifications(ctx context.Context, bucketName string, notifications []bucketNotification) error {
+	cfg, err := h.Client.GetBucketNotification(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+
+	removed := removeQueues(&cfg, func(id string) bool { return strings.HasPrefix(id, kafkaTargetIDPrefix) })
+	if !removed && len(notifications) == 0 {
+		return nil
+	}
+
+	for _, n := range notifications {
+		queue := notification.NewConfig(notification.NewArn("minio", "sqs", "", n.TargetID, "kafka"))
+		for _, event := range n.Events {
+			switch event {
+			case crd.ObjectStoreEventObjectCreated:
+				queue.AddEvents(notification.ObjectCreatedAll)
+			case crd.ObjectStoreEventObjectDeleted:
+				queue.AddEvents(notification.ObjectRemovedAll)
+			}
+		}
+		if n.Prefix != "" {
+			queue.AddFilterPrefix(n.Prefix)
+		}
+		cfg.AddQueue(queue)
+	}
+
+	return h.Client.SetBucketNotification(ctx, bucketName, cfg)
+}
+
+// RemoveKafkaTargets removes the Kafka targets of owner, after the notifications of the buckets
+// that publish to them.
+func (h *minioHandler) RemoveKafkaTargets(ctx context.Context, owner string, buckets []string) error {
+	current, err := h.Admin.GetConfigKV(ctx, madmin.NotifyKafkaSubSys)
+	if err != nil {
+		return err
+	}
+
+	configs, err := madmin.ParseServerConfigOutput(string(current))
+	if err != nil {
+		return err
+	}
+
+	ids := map[string]bool{}
+	for _, c := range configs {
+		if comment, _ := c.Lookup(madmin.CommentKey); c.Target != "" && strings.HasPrefix(comment, kafkaTargetComment(owner)) {
+			ids[c.Target] = true
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	for _, bucket := range buckets {
+		cfg, err := h.Client.GetBucketNotification(ctx, bucket)
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchBucket" {
+				continue
+			}
+			return err
+		}
+
+		if removeQueues(&cfg, func(id string) bool { return ids[id] }) {
+			if err := h.Client.SetBucketNotification(ctx, bucket, cfg); err != nil {
+				return err
+			}
+		}
+	}
+
+	for id := range ids {
+		if _, err := h.Admin.DelConfigKV(ctx, fmt.Sprintf("%s:%s", madmin.NotifyKafkaSubSys, id)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeQueues drops the queue notifications publishing to the targets whose IDs match from the
+// configuration, and reports whether there were any.
+func removeQueues(cfg *notification.Configuration, match func(id string) bool) bool {
+	queues := []notification.QueueConfig{}
+	for _, q := range cfg.QueueConfigs {
+		// The ARN of a queue is arn:minio:sqs:<region>:<target id>:kafka
+		parts := strings.Split(q.Queue, ":")
+		if len(parts) == 6 && match(parts[4]) {
+			continue
+		}
+		queues = append(queues, q)
+	}
+
+	removed := len(queues) != len(cfg.QueueConfigs)
+	cfg.QueueConfigs = queues
+	return removed
+}
+
+// bucketLifecycle returns the lifecycle configuration holding the expiration rules of the bucket.
+func bucketLifecycle(bucket crd.ObjectStoreBucketSpec) *lifecycle.Configuration {
+	lc := lifecycle.NewConfiguration()
+	for i, rule := range bucket.Expiration {
+		lc.Rules = append(lc.Rules, lifecycle.Rule{
+			ID:         fmt.Sprintf("clowder-expiration-%d", i),
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: rule.Prefix},
+			Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(rule.Days)},
+		})
+	}
+	return lc
+}
+
+// SetAppUser creates the user with the given keys and attaches to it a policy, named name,
+// limited to the buckets. The user and policy are read first so that the admin API is only
+// written to when something changed.
+func (h *minioHandler) SetAppUser(ctx context.Context, name string, accessKey string, secretKey string, buckets []string) error {
+	policy, err := appBucketPolicy(buckets)
+	if err != nil {
+		return err
+	}
+
+	current, err := h.getPolicy(ctx, name)
+	if err != nil {
+		return err
+	}
+	if current == nil || !samePolicy(current, policy) {
+		if err := h.Admin.AddCannedPolicy(ctx, name, policy); err != nil {
+			return err
+		}
+	}
+
+	user, err := h.Admin.GetUserInfo(ctx, accessKey)
+	if err != nil {
+		if madmin.ToErrorResponse(err).Code != "XMinioAdminNoSuchUser" {
+			return err
+		}
+		if err := h.Admin.AddUser(ctx, accessKey, secretKey); err != nil {
+			return err
+		}
+	}
+
+	if slices.Contains(strings.Split(user.PolicyName, ","), name) {
+		return nil
+	}
+
+	_, err = h.Admin.AttachPolicy(ctx, madmin.PolicyAssociationReq{Policies: []string{name}, User: accessKey})
+	if adminUnsupported(err) {
+		// the user only ever has its own policy, so replacing its policies is the same
+		return h.Admin.SetPolicy(ctx, name, accessKey, false)
+	}
+	return err
+}
+
+// getPolicy returns the document of the policy name, or nil when there is no such policy. MinIO
+// releases before the v2 policy API, such as the default image, hand back the bare document.
+func (h *minioHandler) getPolicy(ctx context.Context, name string) ([]byte, error) {
+	info, err := h.Admin.InfoCannedPolicyV2(ctx, name)
+	if err == nil && len(info.Policy) > 0 {
+		return info.Policy, nil
+	}
+	if err != nil && !adminUnsupported(err) {
+		if madmin.ToErrorResponse(err).Code == "XMinioAdminNoSuchPolicy" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	policy, err := h.Admin.InfoCannedPolicy(ctx, name)
+	if err != nil {
+		if madmin.ToErrorResponse(err).Code == "XMinioAdminNoSuchPolicy" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return policy, nil
+}
+
+// adminUnsupported reports whether the MinIO server does not have the admin API that was called.
+// Older releases answer unknown admin routes with a version mismatch rather than NotImplemented.
+func adminUnsupported(err error) bool {
+	switch madmin.ToErrorResponse(err).Code {
+	case "NotImplemented", "XMinioAdminVersionMismatch":
+		return true
+	}
+	return false
+}
+
+// samePolicy reports whether two bucket policies grant the same actions on the same resources,
+// MinIO does not hand a stored policy back in the order it was given.
+func samePolicy(a []byte, b []byte) bool {
+	type statement struct {
+		Effect   string
+		Action   []string
+		Resource []string
+	}
+	type document struct {
+		Statement []statement
+	}
+
+	var docA, docB document
+	if json.Unmarshal(a, &docA) != nil || json.Unmarshal(b, &docB) != nil {
+		return false
+	}
+
+	for _, doc := range []*document{&docA, &docB} {
+		for i := range doc.Statement {
+			slices.Sort(doc.Statement[i].Action)
+			slices.Sort(doc.Statement[i].Resource)
+		}
+	}
+
+	return reflect.DeepEqual(docA, docB)
+}
+
+// RemoveAppUser removes the user and its policy, either of which may already be gone.
+func (h *minioHandler) RemoveAppUser(ctx context.Context, name string, accessKey string) error {
+	if err := h.Admin.RemoveUser(ctx, accessKey); err != nil && madmin.ToErrorResponse(err).Code != "XMinioAdminNoSuchUser" {
+		return err
+	}
+
+	if err := h.Admin.RemoveCannedPolicy(ctx, name); err != nil && madmin.ToErrorResponse(err).Code != "XMinioAdminNoSuchPolicy" {
+		return err
+	}
+
+	return nil
+}
+
 func (h *minioHandler) CreateClient(
 	hostname string, port int, accessKey *string, secretKey *string,
 ) error {
@@ -211,8 +744,71 @@ func (h *minioHandler) CreateClient(
 		return errors.Wrap("Failed to create minio client", err)
 	}
 
+	admin, err := madmin.New(endpoint, *accessKey, *secretKey, false)
+
+	if err != nil {
+		return errors.Wrap("Failed to create minio admin client", err)
+	}
+
 	h.Client = cl
+	h.Admin = admin
+
+	return nil
+}
+
+// offlineHandler stands in for minio when rendering without a cluster, every bucket is treated as
+// already existing.
+type offlineHandler struct{}
+
+func (h *offlineHandler) Exists(_ context.Context, _ string) (bool, error) {
+	return true, nil
+}
+
+func (h *offlineHandler) Make(_ context.Context, _ string) error {
+	return nil
+}
 
+func (h *offlineHandler) Configure(_ context.Context, _ crd.ObjectStoreBucketSpec) error {
+	return nil
+}
+
+func (h *offlineHandler) GetSettings(_ context.Context, bucketName string) (crd.ObjectStoreBucketSpec, error) {
+	return crd.ObjectStoreBucketSpec{Name: bucketName}, nil
+}
+
+func (h *offlineHandler) GetSeedRevision(_ context.Context, _ string) (string, error) {
+	return "", nil
+}
+
+func (h *offlineHandler) SetSeedRevision(_ context.Context, _ string, _ string) error {
+	return nil
+}
+
+func (h *offlineHandler) Upload(_ context.Context, _ string, _ string, _ io.Reader, _ int64) error {
+	return nil
+}
+
+func (h *offlineHandler) SetKafkaTarget(_ context.Context, _ kafkaTarget) error {
+	return nil
+}
+
+func (h *offlineHandler) SetNotifications(_ context.Context, _ string, _ []bucketNotification) error {
+	return nil
+}
+
+func (h *offlineHandler) RemoveKafkaTargets(_ context.Context, _ string, _ []string) error {
+	return nil
+}
+
+func (h *offlineHandler) CreateClient(_ string, _ int, _ *string, _ *string) error {
+	return nil
+}
+
+func (h *offlineHandler) SetAppUser(_ context.Context, _ string, _ string, _ string, _ []string) error {
+	return nil
+}
+
+func (h *offlineHandler) RemoveAppUser(_ context.Context, _ string, _ string) error {
 	return nil
 }
 
@@ -287,6 +883,11 @@ func createNetworkPolicy(p *providers.Provider) error {
 	return p.Cache.Update(MinioNetworkPolicy, np)
 }
 
+// getMinioCAsName returns the name of the secret holding the CAs MinIO trusts.
+func getMinioCAsName(o obj.ClowdObject) types.NamespacedName {
+	return providers.GetNamespacedName(o, "minio-cas")
+}
+
 func makeLocalMinIO(_ *crd.ClowdEnvironment, o obj.ClowdObject, objMap providers.ObjectMap, usePVC bool, nodePort bool) error {
 	nn := providers.GetNamespacedName(o, "minio")
 
@@ -323,6 +924,16 @@ func makeLocalMinIO(_ *crd.ClowdEnvironment, o obj.ClowdObject, objMap providers
 			Name:         nn.Name,
 			VolumeSource: volSource,
 		},
+		// The CAs MinIO trusts besides the system's, added when apps need them
+		{
+			Name: getMinioCAsName(o).Name,
+			VolumeSource: core.VolumeSource{
+				Secret: &core.SecretVolumeSource{
+					SecretName: getMinioCAsName(o).Name,
+					Optional:   utils.TruePtr(),
+				},
+			},
+		},
 	}
 	dd.Spec.Template.Labels = labels
 
@@ -382,10 +993,16 @@ func makeLocalMinIO(_ *crd.ClowdEnvironment, o obj.ClowdObject, objMap providers
 		VolumeMounts: []core.VolumeMount{{
 			Name:      nn.Name,
 			MountPath: "/storage",
+		}, {
+			Name:      getMinioCAsName(o).Name,
+			MountPath: "/certs/CAs",
+			ReadOnly:  true,
 		}},
 		Args: []string{
 			"server",
 			"/storage",
+			"--certs-dir",
+			"/certs",
 		},
 		LivenessProbe:            &livenessProbe,
 		ReadinessProbe:           &readinessProbe,
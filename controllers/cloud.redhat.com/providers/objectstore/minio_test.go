@@ -2,21 +2,31 @@ package objectstore
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/go-logr/logr"
+	"github.com/minio/madmin-go/v3"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
 	"github.com/stretchr/testify/assert"
 	core "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
 )
 
 type mockBucket struct {
@@ -34,7 +44,20 @@ type mockBucketHandler struct {
 	wantCreateClientError bool
 	ExistsCalls           []string
 	MakeCalls             []string
+	ConfigureCalls        []crd.ObjectStoreBucketSpec
 	MockBuckets           []mockBucket
+	AppUsers              map[string]mockAppUser
+	SeedRevisions         map[string]string
+	SeedRevisionReads     int
+	Uploads               map[string]string
+	KafkaTargets          map[string]kafkaTarget
+	Notifications         map[string][]bucketNotification
+}
+
+type mockAppUser struct {
+	AccessKey string
+	SecretKey string
+	Buckets   []string
 }
 
 func (c *mockBucketHandler) Exists(_ context.Context, bucketName string) (bool, error) {
@@ -82,125 +105,168 @@ func (c *mockBucketHandler) CreateClient(
 	return nil
 }
 
-func getTestProvider(t *testing.T) providers.Provider {
-	t.Helper()
-	return providers.Provider{
-		Ctx: context.TODO(),
-		Env: &crd.ClowdEnvironment{
-			ObjectMeta: v1.ObjectMeta{
-				Name: "test",
-			},
-		},
-		Client:    &FakeClient{},
-		Config:    &config.AppConfig{},
-		HashCache: &hashcache.HashCache{},
-	}
-}
-
-func getTestMinioProvider(t *testing.T) *minioProvider {
-	t.Helper()
-	testMinioProvider := &minioProvider{
-		Provider: getTestProvider(t),
-	}
-	return testMinioProvider
-}
-
-func setupBucketTest(t *testing.T, mockBuckets []mockBucket) (
-	*mockBucketHandler, *crd.ClowdApp, *minioProvider,
-) {
-	t.Helper()
-	var bucketNames []string
-	for _, mb := range mockBuckets {
-		bucketNames = append(bucketNames, mb.Name)
-	}
-	testApp := &crd.ClowdApp{
-		Spec: crd.ClowdAppSpec{
-			ObjectStore: bucketNames,
-		},
-	}
-	testMinioProvider := getTestMinioProvider(t)
-	testBucketHandler := &mockBucketHandler{MockBuckets: mockBuckets}
-	testMinioProvider.BucketHandler = testBucketHandler
-	hc := hashcache.NewHashCache()
-	testMinioProvider.HashCache = &hc
-	return testBucketHandler, testApp, testMinioProvider
+func (c *mockBucketHandler) Configure(_ context.Context, bucket crd.ObjectStoreBucketSpec) error {
+	c.ConfigureCalls = append(c.ConfigureCalls, bucket)
+	return nil
 }
 
-type FakeClient struct {
+func (c *mockBucketHandler) GetSettings(_ context.Context, bucketName string) (crd.ObjectStoreBucketSpec, error) {
+	return crd.ObjectStoreBucketSpec{Name: bucketName}, nil
 }
 
-type FakeStatus struct {
+func (c *mockBucketHandler) GetSeedRevision(_ context.Context, bucketName string) (string, error) {
+	c.SeedRevisionReads++
+	return c.SeedRevisions[bucketName], nil
 }
 
-func (fc *FakeClient) Create(_ context.Context, _ client.Object, _ ...client.CreateOption) error {
+func (c *mockBucketHandler) SetSeedRevision(_ context.Context, bucketName string, revision string) error {
+	if c.SeedRevisions == nil {
+		c.SeedRevisions = map[string]string{}
+	}
+	c.SeedRevisions[bucketName] = revision
 	return nil
 }
 
-func (fc *FakeClient) Delete(_ context.Context, _ client.Object, _ ...client.DeleteOption) error {
+func (c *mockBucketHandler) Upload(_ context.Context, bucketName string, objectName string, reader io.Reader, _ int64) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if c.Uploads == nil {
+		c.Uploads = map[string]string{}
+	}
+	c.Uploads[bucketName+"/"+objectName] = string(data)
 	return nil
 }
 
-func (fc *FakeClient) Update(_ context.Context, _ client.Object, _ ...client.UpdateOption) error {
+func (c *mockBucketHandler) SetKafkaTarget(_ context.Context, target kafkaTarget) error {
+	if c.KafkaTargets == nil {
+		c.KafkaTargets = map[string]kafkaTarget{}
+	}
+	c.KafkaTargets[target.ID] = target
 	return nil
 }
 
-func (fc *FakeClient) Patch(_ context.Context, _ client.Object, _ client.Patch, _ ...client.PatchOption) error {
+func (c *mockBucketHandler) SetNotifications(_ context.Context, bucketName string, notifications []bucketNotification) error {
+	if c.Notifications == nil {
+		c.Notifications = map[string][]bucketNotification{}
+	}
+	if len(notifications) == 0 {
+		delete(c.Notifications, bucketName)
+		return nil
+	}
+	c.Notifications[bucketName] = notifications
 	return nil
 }
 
-func (fc *FakeClient) Apply(_ context.Context, _ runtime.ApplyConfiguration, _ ...client.ApplyOption) error {
+func (c *mockBucketHandler) RemoveKafkaTargets(_ context.Context, owner string, buckets []string) error {
+	for id, target := range c.KafkaTargets {
+		if target.Owner != owner {
+			continue
+		}
+		for _, bucket := range buckets {
+			kept := []bucketNotification{}
+			for _, n := range c.Notifications[bucket] {
+				if n.TargetID != id {
+					kept = append(kept, n)
+				}
+			}
+			c.Notifications[bucket] = kept
+		}
+		delete(c.KafkaTargets, id)
+	}
 	return nil
 }
 
-func (fc *FakeClient) DeleteAllOf(_ context.Context, _ client.Object, _ ...client.DeleteAllOfOption) error {
+func (c *mockBucketHandler) SetAppUser(_ context.Context, name string, accessKey string, secretKey string, buckets []string) error {
+	if c.AppUsers == nil {
+		c.AppUsers = map[string]mockAppUser{}
+	}
+	c.AppUsers[name] = mockAppUser{AccessKey: accessKey, SecretKey: secretKey, Buckets: buckets}
 	return nil
 }
 
-func (fc *FakeClient) GroupVersionKindFor(runtime.Object) (schema.GroupVersionKind, error) {
-	return schema.GroupVersionKind{}, nil
-}
-
-func (fc *FakeClient) IsObjectNamespaced(runtime.Object) (bool, error) {
-	return false, nil
-}
-
-func (fc *FakeClient) Get(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
-	p, _ := obj.(*core.Secret)
-	p.Data = make(map[string][]byte)
-	p.Data["port"] = []byte("2345")
+func (c *mockBucketHandler) RemoveAppUser(_ context.Context, name string, _ string) error {
+	delete(c.AppUsers, name)
 	return nil
 }
 
-func (fc *FakeClient) List(_ context.Context, _ client.ObjectList, _ ...client.ListOption) error {
-	return nil
-}
+func getTestProvider(t *testing.T) providers.Provider {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, crd.AddToScheme(scheme))
 
-func (fc *FakeClient) Scheme() *runtime.Scheme {
-	return nil
-}
+	env := &crd.ClowdEnvironment{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "test",
+		},
+	}
 
-func (fc *FakeClient) RESTMapper() meta.RESTMapper {
-	return nil
-}
+	minioSecret := &core.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name: "test-minio",
+		},
+		Data: map[string][]byte{"port": []byte("2345")},
+	}
 
-func (fc *FakeClient) SubResource(_ string) client.SubResourceClient {
-	return nil
-}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(minioSecret).Build()
+
+	log := logr.Discard()
+	cache := rc.NewObjectCache(context.TODO(), c, &log, rc.NewCacheConfig(scheme, nil, nil, rc.Options{}))
 
-func (fc *FakeClient) Status() client.StatusWriter {
-	return &FakeStatus{}
+	return providers.Provider{
+		Ctx:       context.TODO(),
+		Env:       env,
+		Client:    c,
+		Cache:     &cache,
+		Config:    &config.AppConfig{},
+		HashCache: &hashcache.HashCache{},
+	}
 }
 
-func (fc *FakeStatus) Patch(_ context.Context, _ client.Object, _ client.Patch, _ ...client.SubResourcePatchOption) error {
-	return nil
+func getTestMinioProvider(t *testing.T) *minioProvider {
+	t.Helper()
+	testMinioProvider := &minioProvider{
+		Provider: getTestProvider(t),
+	}
+	return testMinioProvider
 }
 
-func (fc *FakeStatus) Create(_ context.Context, _ client.Object, _ client.Object, _ ...client.SubResourceCreateOption) error {
-	return nil
+func setupBucketTest(t *testing.T, mockBuckets []mockBucket) (
+	*mockBucketHandler, *crd.ClowdApp, *minioProvider,
+) {
+	t.Helper()
+	var buckets []crd.ObjectStoreBucketSpec
+	for _, mb := range mockBuckets {
+		buckets = append(buckets, crd.ObjectStoreBucketSpec{Name: mb.Name})
+	}
+	testApp := &crd.ClowdApp{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "app",
+			Namespace: "app-ns",
+		},
+		Spec: crd.ClowdAppSpec{
+			ObjectStore: buckets,
+		},
+	}
+	testMinioProvider := getTestMinioProvider(t)
+	testBucketHandler := &mockBucketHandler{MockBuckets: mockBuckets}
+	testMinioProvider.BucketHandler = testBucketHandler
+	hc := hashcache.NewHashCache()
+	testMinioProvider.HashCache = &hc
+	return testBucketHandler, testApp, testMinioProvider
 }
 
-func (fc *FakeStatus) Update(_ context.Context, _ client.Object, _ ...client.SubResourceUpdateOption) error {
-	return nil
+// wantBucket returns the config expected for a bucket, with the app's own keys.
+func wantBucket(mp *minioProvider, name string) config.ObjectStoreBucket {
+	return config.ObjectStoreBucket{
+		Name:          name,
+		RequestedName: name,
+		Endpoint:      &mp.Config.ObjectStore.Hostname,
+		AccessKey:     mp.Config.ObjectStore.AccessKey,
+		SecretKey:     mp.Config.ObjectStore.SecretKey,
+	}
 }
 
 func TestMinio(t *testing.T) {
@@ -278,7 +344,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.MakeCalls, 0)
 		assert.Contains(handler.ExistsCalls, bucketName)
 
-		wantBucketConfig := config.ObjectStoreBucket{Name: bucketName, RequestedName: bucketName, Endpoint: &mp.Config.ObjectStore.Hostname}
+		wantBucketConfig := wantBucket(mp, bucketName)
 		assert.Contains(mp.Config.ObjectStore.Buckets, wantBucketConfig)
 		assert.Len(mp.Config.ObjectStore.Buckets, 1)
 	})
@@ -306,7 +372,7 @@ func TestMinio(t *testing.T) {
 		assert.Contains(handler.ExistsCalls, bucketName)
 		assert.Contains(handler.MakeCalls, bucketName)
 
-		wantBucketConfig := config.ObjectStoreBucket{Name: bucketName, RequestedName: bucketName, Endpoint: &mp.Config.ObjectStore.Hostname}
+		wantBucketConfig := wantBucket(mp, bucketName)
 		assert.Contains(mp.Config.ObjectStore.Buckets, wantBucketConfig)
 		assert.Len(mp.Config.ObjectStore.Buckets, 1)
 	})
@@ -336,7 +402,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.MakeCalls, 3)
 		assert.Len(mp.Config.ObjectStore.Buckets, 3)
 		for _, b := range []string{b1, b2, b3} {
-			wantBucketConfig := config.ObjectStoreBucket{Name: b, RequestedName: b, Endpoint: &mp.Config.ObjectStore.Hostname}
+			wantBucketConfig := wantBucket(mp, b)
 			assert.Contains(mp.Config.ObjectStore.Buckets, wantBucketConfig)
 			assert.Contains(handler.ExistsCalls, b)
 			assert.Contains(handler.MakeCalls, b)
@@ -368,7 +434,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(mp.Config.ObjectStore.Buckets, 3)
 		for _, b := range []string{b1, b2, b3} {
 			assert.Contains(handler.ExistsCalls, b)
-			wantBucketConfig := config.ObjectStoreBucket{Name: b, RequestedName: b, Endpoint: &mp.Config.ObjectStore.Hostname}
+			wantBucketConfig := wantBucket(mp, b)
 			assert.Contains(mp.Config.ObjectStore.Buckets, wantBucketConfig)
 		}
 		assert.Contains(handler.MakeCalls, b3)
@@ -401,7 +467,7 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.ExistsCalls, 2)
 		assert.Len(handler.MakeCalls, 1)
 		assert.Len(mp.Config.ObjectStore.Buckets, 1)
-		wantBucketConfig := config.ObjectStoreBucket{Name: b1, RequestedName: b1, Endpoint: &mp.Config.ObjectStore.Hostname}
+		wantBucketConfig := wantBucket(mp, b1)
 		assert.Contains(mp.Config.ObjectStore.Buckets, wantBucketConfig)
 	})
 
@@ -432,7 +498,170 @@ func TestMinio(t *testing.T) {
 		assert.Len(handler.ExistsCalls, 2)
 		assert.Len(handler.MakeCalls, 2)
 		assert.Len(mp.Config.ObjectStore.Buckets, 1)
-		wantBucketConfig := config.ObjectStoreBucket{Name: b1, RequestedName: b1, Endpoint: &mp.Config.ObjectStore.Hostname}
+		wantBucketConfig := wantBucket(mp, b1)
 		assert.Contains(mp.Config.ObjectStore.Buckets, wantBucketConfig)
 	})
 }
+
+func TestMinioAppUser(t *testing.T) {
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "b1"}, {Name: "b2", Exists: true}})
+
+	assert.NoError(t, mp.Provide(app))
+
+	secret := &core.Secret{}
+	assert.NoError(t, mp.Cache.Get(MinioAppSecret, secret))
+	assert.Equal(t, "app-objectstore", secret.Name)
+	assert.Equal(t, "app-ns", secret.Namespace)
+
+	user := handler.AppUsers["app-ns-app"]
+	assert.Equal(t, []string{"b1", "b2"}, user.Buckets)
+	assert.Equal(t, secret.StringData["accessKey"], user.AccessKey)
+	assert.Equal(t, secret.StringData["secretKey"], user.SecretKey)
+	assert.Equal(t, user.AccessKey, *mp.Config.ObjectStore.AccessKey, "the app gets its own keys rather than the root ones")
+	assert.Equal(t, user.SecretKey, *mp.Config.ObjectStore.SecretKey)
+
+	assert.NoError(t, mp.Client.Create(context.TODO(), secret))
+	assert.NoError(t, mp.FinalizeApp(app))
+	assert.Empty(t, handler.AppUsers, "the user is removed with the app")
+}
+
+func TestMinioAppUserWithoutBuckets(t *testing.T) {
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "b1"}})
+
+	assert.NoError(t, mp.Provide(app))
+	secret := &core.Secret{}
+	assert.NoError(t, mp.Cache.Get(MinioAppSecret, secret))
+	assert.NoError(t, mp.Client.Create(context.TODO(), secret))
+	assert.Contains(t, handler.AppUsers, "app-ns-app")
+
+	app.Spec.ObjectStore = nil
+	assert.NoError(t, mp.Provide(app))
+	assert.Empty(t, handler.AppUsers, "the user is removed once the app has no buckets")
+}
+
+// TestMinioAppUserOldServer runs SetAppUser against a MinIO that predates the v2 policy and the
+// policy attach APIs, as the default image does.
+func TestMinioAppUserOldServer(t *testing.T) {
+	policies := map[string][]byte{}
+	userPolicy := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/minio/admin/v3/info-canned-policy":
+			policy, ok := policies[r.URL.Query().Get("name")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"Code":"XMinioAdminNoSuchPolicy"}`))
+				return
+			}
+			_, _ = w.Write(policy)
+		case "/minio/admin/v3/add-canned-policy":
+			body, _ := io.ReadAll(r.Body)
+			policies[r.URL.Query().Get("name")] = body
+		case "/minio/admin/v3/user-info":
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"policyName":%q,"status":"enabled"}`, userPolicy)))
+		case "/minio/admin/v3/set-user-or-group-policy":
+			userPolicy = r.URL.Query().Get("policyName")
+		default:
+			w.WriteHeader(http.StatusUpgradeRequired)
+			_, _ = w.Write([]byte(`{"Code":"XMinioAdminVersionMismatch"}`))
+		}
+	}))
+	defer server.Close()
+
+	admin, err := madmin.New(strings.TrimPrefix(server.URL, "http://"), "root", "rootpassword", false)
+	assert.NoError(t, err)
+	h := &minioHandler{Admin: admin}
+
+	assert.NoError(t, h.SetAppUser(context.TODO(), "app-ns-app", "key", "secret", []string{"b1"}))
+	assert.Equal(t, "app-ns-app", userPolicy, "the policy is set on the user when it cannot be attached")
+	want, err := appBucketPolicy([]string{"b1"})
+	assert.NoError(t, err)
+	assert.True(t, samePolicy(want, policies["app-ns-app"]))
+
+	current, err := h.getPolicy(context.TODO(), "app-ns-app")
+	assert.NoError(t, err)
+	assert.True(t, samePolicy(want, current), "the bare policy document of older servers is read")
+}
+
+func TestMinioKafkaTargetRelease(t *testing.T) {
+	version := "2020-11-19T23:48:16Z"
+	configured := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/minio/admin/v3/info":
+			_, _ = w.Write([]byte(fmt.Sprintf(`{"servers":[{"version":%q}]}`, version)))
+		case "/minio/admin/v3/set-config-kv":
+			configured = true
+			w.Header().Set(madmin.ConfigAppliedHeader, madmin.ConfigAppliedTrue)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"Code":"XMinioConfigNotFound"}`))
+		}
+	}))
+	defer server.Close()
+
+	admin, err := madmin.New(strings.TrimPrefix(server.URL, "http://"), "root", "rootpassword", false)
+	assert.NoError(t, err)
+	h := &minioHandler{Admin: admin}
+	target := kafkaTarget{ID: "clowder0123", Owner: "app-ns/app", Brokers: []string{"kafka:9092"}, Topic: "events"}
+
+	err = h.SetKafkaTarget(context.TODO(), target)
+	assert.ErrorContains(t, err, "the environment runs MinIO 2020-11-19T23:48:16Z, which only applies kafka notification targets on a restart")
+	assert.False(t, configured, "older releases are not configured")
+
+	version = "2024-01-16T16:07:38Z"
+	assert.NoError(t, h.SetKafkaTarget(context.TODO(), target))
+	assert.True(t, configured)
+}
+
+func TestAppBucketPolicy(t *testing.T) {
+	policy, err := appBucketPolicy([]string{"b1", "b2"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Effect": "Allow",
+			"Action": ["s3:*"],
+			"Resource": ["arn:aws:s3:::b1", "arn:aws:s3:::b1/*", "arn:aws:s3:::b2", "arn:aws:s3:::b2/*"]
+		}]
+	}`, string(policy))
+}
+
+func TestSamePolicy(t *testing.T) {
+	policy, err := appBucketPolicy([]string{"b1", "b2"})
+	assert.NoError(t, err)
+
+	stored := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:*"],` +
+		`"Resource":["arn:aws:s3:::b1/*","arn:aws:s3:::b2","arn:aws:s3:::b2/*","arn:aws:s3:::b1"]}]}`
+	assert.True(t, samePolicy([]byte(stored), policy), "the order MinIO stores resources in does not matter")
+
+	fewer, err := appBucketPolicy([]string{"b1"})
+	assert.NoError(t, err)
+	assert.False(t, samePolicy(fewer, policy))
+	assert.False(t, samePolicy(nil, policy))
+}
+
+func TestMinioBucketOptions(t *testing.T) {
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "b1"}, {Name: "b2"}})
+
+	quota := resource.MustParse("1Gi")
+	app.Spec.ObjectStore[1].Expiration = []crd.ObjectStoreExpirationRule{{Prefix: "tmp/", Days: 7}}
+	app.Spec.ObjectStore[1].Quota = &quota
+
+	assert.NoError(t, mp.Provide(app))
+	assert.Equal(t, app.Spec.ObjectStore, handler.ConfigureCalls, "buckets without settings are configured too, which clears any they had")
+}
+
+func TestBucketLifecycle(t *testing.T) {
+	lc := bucketLifecycle(crd.ObjectStoreBucketSpec{
+		Name:       "b1",
+		Expiration: []crd.ObjectStoreExpirationRule{{Days: 30}, {Prefix: "tmp/", Days: 1}},
+	})
+
+	assert.Len(t, lc.Rules, 2)
+	assert.Equal(t, "clowder-expiration-1", lc.Rules[1].ID)
+	assert.Equal(t, "Enabled", lc.Rules[1].Status)
+	assert.Equal(t, "tmp/", lc.Rules[1].RuleFilter.Prefix)
+	assert.Equal(t, lifecycle.ExpirationDays(1), lc.Rules[1].Expiration.Days)
+	assert.True(t, bucketLifecycle(crd.ObjectStoreBucketSpec{Name: "b1"}).Empty())
+}
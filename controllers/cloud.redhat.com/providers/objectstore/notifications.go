@@ -0,0 +1,289 @@
+package objectstore
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+)
+
+// kafkaTargetIDPrefix starts the IDs of the Kafka notification targets Clowder creates, telling
+// them apart from targets set up by hand.
+const kafkaTargetIDPrefix = "clowder"
+
+// minioCAsAnnotation is set on the pod template of MinIO to a checksum of the CAs it trusts, so
+// that it restarts to load new ones.
+const minioCAsAnnotation = "clowder/minio-cas"
+
+// kafkaTarget is a MinIO Kafka notification target, publishing to a single topic. The owner is
+// the app the target was created for. CACert is the CA of brokers connected to over TLS, empty
+// when their certificates are signed by a system CA.
+type kafkaTarget struct {
+	ID      string
+	Owner   string
+	Brokers []string
+	Topic   string
+	SASL    *kafkaTargetSASL
+	TLS     bool
+	CACert  string
+}
+
+// kafkaTargetSASL holds the SASL credentials a Kafka notification target connects with.
+type kafkaTargetSASL struct {
+	Username  string
+	Password  string
+	Mechanism string
+}
+
+// bucketNotification publishes the events on the objects under a prefix of a bucket to a Kafka
+// notification target.
+type bucketNotification struct {
+	TargetID string
+	Events   []crd.ObjectStoreEventType
+	Prefix   string
+}
+
+// setBucketNotifications points the notifications of the bucket at Kafka targets publishing to
+// the app's topics, and returns the actual names of those topics. The notifications Clowder set on
+// a bucket the app declares none for are cleared, so apps sharing a bucket must declare the same
+// notifications.
+func (m *minioProvider) setBucketNotifications(app *crd.ClowdApp, bucket crd.ObjectStoreBucketSpec) ([]string, error) {
+	if len(bucket.Notifications) == 0 {
+		return nil, m.BucketHandler.SetNotifications(m.Ctx, bucket.Name, nil)
+	}
+
+	if m.Config.Kafka == nil {
+		return nil, errors.NewClowderError("bucket notifications need the environment to provide Kafka")
+	}
+
+	topics := []string{}
+	targets := map[string]kafkaTarget{}
+	notifications := []bucketNotification{}
+
+	for _, notification := range bucket.Notifications {
+		topic, err := getActualTopicName(m.Config.Kafka, notification.Topic)
+		if err != nil {
+			return nil, err
+		}
+
+		target, ok := targets[topic]
+		if !ok {
+			target, err = newKafkaTarget(app, m.Config.Kafka, topic)
+			if err != nil {
+				return nil, err
+			}
+			if target.CACert != "" {
+				if err := m.trustCA(target.CACert); err != nil {
+					return nil, err
+				}
+			}
+			if err := m.BucketHandler.SetKafkaTarget(m.Ctx, target); err != nil {
+				return nil, errors.Wrap(fmt.Sprintf("failed to set the kafka target for topic %s", topic), err)
+			}
+			targets[topic] = target
+			topics = append(topics, topic)
+		}
+
+		notifications = append(notifications, bucketNotification{
+			TargetID: target.ID,
+			Events:   notification.Events,
+			Prefix:   notification.Prefix,
+		})
+	}
+
+	if err := m.BucketHandler.SetNotifications(m.Ctx, bucket.Name, notifications); err != nil {
+		return nil, err
+	}
+
+	return topics, nil
+}
+
+// getActualTopicName returns the name on the Kafka server of the topic the app requested.
+func getActualTopicName(kafka *config.KafkaConfig, requestedName string) (string, error) {
+	for _, topic := range kafka.Topics {
+		if topic.RequestedName == requestedName {
+			return topic.Name, nil
+		}
+	}
+	return "", errors.NewClowderError(fmt.Sprintf("topic %s is not one of the app's kafka topics", requestedName))
+}
+
+// newKafkaTarget returns the target publishing to topic through the app's brokers, with the
+// app's credentials. Each app has its own targets, as apps sharing a topic have different
+// credentials.
+func newKafkaTarget(app *crd.ClowdApp, kafka *config.KafkaConfig, topic string) (kafkaTarget, error) {
+	owner := getKafkaTargetOwner(app)
+	target := kafkaTarget{
+		ID:    fmt.Sprintf("%s%x", kafkaTargetIDPrefix, sha256.Sum256([]byte(fmt.Sprintf("%s/%s", owner, topic))))[:23],
+		Owner: owner,
+		Topic: topic,
+	}
+
+	for _, broker := range kafka.Brokers {
+		if broker.SecurityProtocol != nil && strings.HasSuffix(*broker.SecurityProtocol, "SSL") {
+			target.TLS = true
+			if target.CACert == "" && broker.Cacert != nil {
+				target.CACert = *broker.Cacert
+			}
+		}
+
+		port := 9092
+		if broker.Port != nil {
+			port = *broker.Port
+		}
+		target.Brokers = append(target.Brokers, fmt.Sprintf("%s:%d", broker.Hostname, port))
+
+		if target.SASL == nil && broker.Authtype != nil && *broker.Authtype == config.BrokerConfigAuthtypeSasl && broker.Sasl != nil {
+			sasl := &kafkaTargetSASL{Mechanism: "plain"}
+			if broker.Sasl.Username != nil {
+				sasl.Username = *broker.Sasl.Username
+			}
+			if broker.Sasl.Password != nil {
+				sasl.Password = *broker.Sasl.Password
+			}
+			if broker.Sasl.SaslMechanism != nil {
+				switch *broker.Sasl.SaslMechanism {
+				case "SCRAM-SHA-512":
+					sasl.Mechanism = "sha512"
+				case "SCRAM-SHA-256":
+					sasl.Mechanism = "sha256"
+				}
+			}
+			target.SASL = sasl
+		}
+	}
+
+	if len(target.Brokers) == 0 {
+		return target, errors.NewClowderError("no kafka brokers to publish bucket notifications to")
+	}
+
+	return target, nil
+}
+
+// getKafkaTargetOwner returns the owner recorded on the app's Kafka targets.
+func getKafkaTargetOwner(app *crd.ClowdApp) string {
+	return fmt.Sprintf("%s/%s", app.Namespace, app.Name)
+}
+
+// kafkaTargetComment returns the start of the comment of the targets of owner.
+func kafkaTargetComment(owner string) string {
+	return fmt.Sprintf("%s:%s:", kafkaTargetIDPrefix, owner)
+}
+
+// kafkaTargetConfig returns the MinIO config of the target and its comment, which holds the owner
+// and a checksum of the config so that unchanged targets can be left alone, and the targets of an
+// app found when it is deleted.
+func kafkaTargetConfig(target kafkaTarget) (string, string) {
+	kvs := []string{
+		configKV("enable", "on"),
+		configKV("brokers", strings.Join(target.Brokers, ",")),
+		configKV("topic", target.Topic),
+	}
+	if target.TLS {
+		kvs = append(kvs,
+			configKV("tls", "on"),
+			configKV("tls_skip_verify", "off"),
+		)
+	}
+	if target.SASL != nil {
+		kvs = append(kvs,
+			configKV("sasl", "on"),
+			configKV("sasl_username", target.SASL.Username),
+			configKV("sasl_password", target.SASL.Password),
+			configKV("sasl_mechanism", target.SASL.Mechanism),
+		)
+	}
+
+	comment := fmt.Sprintf("%s%x", kafkaTargetComment(target.Owner), sha256.Sum256([]byte(strings.Join(kvs, " "))))
+	kvs = append(kvs, configKV("comment", comment))
+
+	return fmt.Sprintf("notify_kafka:%s %s", target.ID, strings.Join(kvs, " ")), comment
+}
+
+// trustCA adds the CA to the ones MinIO trusts, kept in a secret mounted into its certs directory.
+// MinIO only loads those when it starts, so a new CA restarts it and an error is returned until
+// it is back. The secret is owned by the MinIO deployment rather than the environment, as it is
+// written while reconciling apps.
+func (m *minioProvider) trustCA(caCert string) error {
+	if m.Offline {
+		return nil
+	}
+
+	dd := &apps.Deployment{}
+	if err := m.Client.Get(m.Ctx, providers.GetNamespacedName(m.Env, "minio"), dd); err != nil {
+		return errors.Wrap("couldn't get the minio deployment", err)
+	}
+
+	key := fmt.Sprintf("ca-%x.crt", sha256.Sum256([]byte(caCert)))
+	secret := &core.Secret{}
+	err := m.Client.Get(m.Ctx, getMinioCAsName(m.Env), secret)
+	switch {
+	case k8serr.IsNotFound(err):
+		nn := getMinioCAsName(m.Env)
+		secret = &core.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nn.Name,
+				Namespace: nn.Namespace,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       dd.Name,
+					UID:        dd.UID,
+				}},
+			},
+			Data: map[string][]byte{key: []byte(caCert)},
+		}
+		if err := m.Client.Create(m.Ctx, secret); err != nil {
+			return errors.Wrap("couldn't create the minio CA secret", err)
+		}
+	case err != nil:
+		return errors.Wrap("couldn't get the minio CA secret", err)
+	default:
+		if _, ok := secret.Data[key]; ok {
+			return nil
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key] = []byte(caCert)
+		if err := m.Client.Update(m.Ctx, secret); err != nil {
+			return errors.Wrap("couldn't update the minio CA secret", err)
+		}
+	}
+
+	if dd.Spec.Template.Annotations == nil {
+		dd.Spec.Template.Annotations = map[string]string{}
+	}
+	dd.Spec.Template.Annotations[minioCAsAnnotation] = casChecksum(secret.Data)
+	if err := m.Client.Update(m.Ctx, dd); err != nil {
+		return errors.Wrap("couldn't restart minio", err)
+	}
+
+	newErr := errors.NewClowderError("minio is restarting to trust the CA of the kafka brokers")
+	newErr.Requeue = true
+	return newErr
+}
+
+// casChecksum returns a checksum of the CAs in the secret data.
+func casChecksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(keys, ","))))
+}
+
+func configKV(key string, value string) string {
+	return fmt.Sprintf(`%s="%s"`, key, value)
+}
@@ -0,0 +1,169 @@
+package objectstore
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func TestMinioBucketNotifications(t *testing.T) {
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "uploads"}, {Name: "plain"}})
+	app.Spec.ObjectStore[0].Notifications = []crd.ObjectStoreNotificationSpec{
+		{Events: []crd.ObjectStoreEventType{crd.ObjectStoreEventObjectCreated}, Topic: "uploads", Prefix: "incoming/"},
+		{Events: []crd.ObjectStoreEventType{crd.ObjectStoreEventObjectDeleted}, Topic: "uploads"},
+	}
+
+	err := mp.Provide(app)
+	assert.ErrorContains(t, err, "bucket notifications need the environment to provide Kafka")
+
+	authtype := config.BrokerConfigAuthtypeSasl
+	mp.Config.Kafka = &config.KafkaConfig{
+		Brokers: []config.BrokerConfig{{
+			Hostname: "env-kafka-bootstrap.kafka.svc",
+			Port:     utils.IntPtr(9093),
+			Authtype: &authtype,
+			Sasl: &config.KafkaSASLConfig{
+				Username:      utils.StringPtr("env-app"),
+				Password:      utils.StringPtr("password"),
+				SaslMechanism: utils.StringPtr("SCRAM-SHA-512"),
+			},
+		}},
+		Topics: []config.TopicConfig{{RequestedName: "uploads", Name: "uploads-env"}},
+	}
+
+	reprovide(t, mp, app)
+
+	require.Len(t, handler.KafkaTargets, 1, "notifications to the same topic share a target")
+	for id, target := range handler.KafkaTargets {
+		assert.Equal(t, kafkaTarget{
+			ID:      id,
+			Owner:   "app-ns/app",
+			Brokers: []string{"env-kafka-bootstrap.kafka.svc:9093"},
+			Topic:   "uploads-env",
+			SASL:    &kafkaTargetSASL{Username: "env-app", Password: "password", Mechanism: "sha512"},
+		}, target)
+
+		assert.Equal(t, []bucketNotification{
+			{TargetID: id, Events: []crd.ObjectStoreEventType{crd.ObjectStoreEventObjectCreated}, Prefix: "incoming/"},
+			{TargetID: id, Events: []crd.ObjectStoreEventType{crd.ObjectStoreEventObjectDeleted}},
+		}, handler.Notifications["uploads"])
+	}
+	assert.NotContains(t, handler.Notifications, "plain")
+
+	assert.Equal(t, []string{"uploads-env"}, mp.Config.ObjectStore.Buckets[0].EventTopics)
+	assert.Empty(t, mp.Config.ObjectStore.Buckets[1].EventTopics)
+
+	assert.NoError(t, mp.FinalizeApp(app))
+	assert.Empty(t, handler.KafkaTargets, "the app's targets are removed with it")
+	assert.Empty(t, handler.Notifications["uploads"])
+
+	reprovide(t, mp, app)
+	app.Spec.ObjectStore[0].Notifications = nil
+	reprovide(t, mp, app)
+	assert.NotContains(t, handler.Notifications, "uploads", "notifications the app no longer declares are cleared")
+}
+
+func TestRemoveQueues(t *testing.T) {
+	cfg := notification.Configuration{}
+	for _, id := range []string{"clowder0123", "clowder4567", "manual"} {
+		cfg.AddQueue(notification.NewConfig(notification.NewArn("minio", "sqs", "", id, "kafka")))
+	}
+
+	assert.True(t, removeQueues(&cfg, func(id string) bool { return id == "clowder0123" }))
+	assert.Len(t, cfg.QueueConfigs, 2)
+
+	assert.True(t, removeQueues(&cfg, func(id string) bool { return strings.HasPrefix(id, kafkaTargetIDPrefix) }))
+	require.Len(t, cfg.QueueConfigs, 1)
+	assert.Equal(t, "arn:minio:sqs::manual:kafka", cfg.QueueConfigs[0].Queue, "targets set up by hand are left alone")
+
+	assert.False(t, removeQueues(&cfg, func(id string) bool { return strings.HasPrefix(id, kafkaTargetIDPrefix) }))
+}
+
+func TestNewKafkaTarget(t *testing.T) {
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+	app.Namespace = "app-ns"
+
+	kafka := &config.KafkaConfig{Brokers: []config.BrokerConfig{{Hostname: "kafka"}}}
+	target, err := newKafkaTarget(app, kafka, "events")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kafka:9092"}, target.Brokers)
+	assert.Nil(t, target.SASL)
+
+	other := app.DeepCopy()
+	other.Name = "other"
+	otherTarget, err := newKafkaTarget(other, kafka, "events")
+	require.NoError(t, err)
+	assert.NotEqual(t, target.ID, otherTarget.ID, "apps sharing a topic get their own targets")
+
+	kv, comment := kafkaTargetConfig(target)
+	assert.Equal(t, `notify_kafka:`+target.ID+` enable="on" brokers="kafka:9092" topic="events" comment="`+comment+`"`, kv)
+	assert.True(t, strings.HasPrefix(comment, "clowder:app-ns/app:"), "the comment records the app the target belongs to")
+
+	kafka.Brokers[0].SecurityProtocol = utils.StringPtr("SASL_SSL")
+	kafka.Brokers[0].Cacert = utils.StringPtr("ca")
+	target, err = newKafkaTarget(app, kafka, "events")
+	require.NoError(t, err)
+	assert.True(t, target.TLS)
+	assert.Equal(t, "ca", target.CACert)
+
+	kv, _ = kafkaTargetConfig(target)
+	assert.Contains(t, kv, `tls="on" tls_skip_verify="off"`)
+}
+
+func TestMinioBucketNotificationsTLS(t *testing.T) {
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "uploads"}})
+	app.Spec.ObjectStore[0].Notifications = []crd.ObjectStoreNotificationSpec{
+		{Events: []crd.ObjectStoreEventType{crd.ObjectStoreEventObjectCreated}, Topic: "uploads"},
+	}
+	mp.Config.Kafka = &config.KafkaConfig{
+		Brokers: []config.BrokerConfig{{
+			Hostname:         "env-kafka-bootstrap.kafka.svc",
+			Port:             utils.IntPtr(9093),
+			SecurityProtocol: utils.StringPtr("SSL"),
+			Cacert:           utils.StringPtr("cluster-ca"),
+		}},
+		Topics: []config.TopicConfig{{RequestedName: "uploads", Name: "uploads-env"}},
+	}
+
+	dd := &apps.Deployment{ObjectMeta: v1.ObjectMeta{Name: "test-minio", Namespace: mp.Env.Status.TargetNamespace}}
+	require.NoError(t, mp.Client.Create(context.TODO(), dd))
+
+	err := mp.Provide(app)
+	assert.ErrorContains(t, err, "minio is restarting to trust the CA of the kafka brokers")
+	assert.Empty(t, handler.KafkaTargets, "targets wait for minio to trust the CA")
+
+	secret := &core.Secret{}
+	require.NoError(t, mp.Client.Get(context.TODO(), getMinioCAsName(mp.Env), secret))
+	require.Len(t, secret.Data, 1)
+	for _, ca := range secret.Data {
+		assert.Equal(t, "cluster-ca", string(ca))
+	}
+	assert.Equal(t, "Deployment", secret.OwnerReferences[0].Kind)
+
+	require.NoError(t, mp.Client.Get(context.TODO(), providers.GetNamespacedName(mp.Env, "minio"), dd))
+	restartedWith := dd.Spec.Template.Annotations[minioCAsAnnotation]
+	assert.NotEmpty(t, restartedWith)
+
+	reprovide(t, mp, app)
+	require.Len(t, handler.KafkaTargets, 1)
+	for _, target := range handler.KafkaTargets {
+		assert.True(t, target.TLS)
+	}
+
+	require.NoError(t, mp.Client.Get(context.TODO(), providers.GetNamespacedName(mp.Env, "minio"), dd))
+	assert.Equal(t, restartedWith, dd.Spec.Template.Annotations[minioCAsAnnotation], "minio is only restarted for new CAs")
+}
@@ -33,7 +33,9 @@ func GetObjectStore(c *providers.Provider) (providers.ClowderProvider, error) {
 	case "minio":
 		return NewMinIO(c)
 	case "app-interface":
-		return &appInterfaceObjectstoreProvider{Provider: *c}, nil
+		return NewAppInterfaceObjectstore(c)
+	case "s3":
+		return NewS3(c)
 	case "none", "":
 		return NewNoneObjectStore(c)
 	default:
@@ -43,5 +45,5 @@ func GetObjectStore(c *providers.Provider) (providers.ClowderProvider, error) {
 }
 
 func init() {
-	providers.ProvidersRegistration.Register(GetObjectStore, 5, ProvName)
+	providers.ProvidersRegistration.Register(GetObjectStore, 7, ProvName)
 }
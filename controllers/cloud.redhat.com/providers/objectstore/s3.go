@@ -0,0 +1,320 @@
+package objectstore
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+// s3Provider is an object store provider that uses an existing S3 compatible server, such as
+// Ceph RGW, with credentials from a secret referenced by the environment.
+type s3Provider struct {
+	providers.Provider
+	BucketHandler bucketHandler
+	accessKey     string
+	secretKey     string
+}
+
+// NewS3 returns a new s3 object store provider object.
+func NewS3(p *providers.Provider) (providers.ClowderProvider, error) {
+	cfg := p.Env.Spec.Providers.ObjectStore
+	if cfg.Endpoint == "" {
+		return nil, errors.NewClowderError("no endpoint defined for s3 object store")
+	}
+
+	sp := &s3Provider{Provider: *p}
+
+	secret, err := sp.getSecret()
+	if err != nil {
+		raisedErr := errors.Wrap("Couldn't get s3 credentials secret", err)
+		raisedErr.Requeue = true
+		return nil, raisedErr
+	}
+
+	sp.accessKey = string(secret.Data["accessKey"])
+	sp.secretKey = string(secret.Data["secretKey"])
+	if sp.accessKey == "" || sp.secretKey == "" {
+		return nil, errors.NewClowderError("s3 credentials secret must have an accessKey and a secretKey")
+	}
+
+	if p.Offline {
+		sp.BucketHandler = &offlineHandler{}
+		return sp, nil
+	}
+
+	tlsConfig, err := sp.getTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	cl, err := newS3Client(cfg.Endpoint, cfg.TLS, cfg.Region, sp.accessKey, sp.secretKey, tlsConfig)
+	if err != nil {
+		return nil, errors.Wrap("error creating s3 client", err)
+	}
+	sp.BucketHandler = &minioHandler{Client: cl}
+
+	return sp, nil
+}
+
+func (s *s3Provider) EnvProvide() error {
+	return nil
+}
+
+// Provide creates the app's buckets if the environment allows it, otherwise checks that they
+// exist, and passes the app its own credentials if the environment defines them, those from the
+// environment's secret otherwise. Buckets are named after the environment, the app finds them by
+// the name it requested.
+func (s *s3Provider) Provide(app *crd.ClowdApp) error {
+	if len(app.Spec.ObjectStore) == 0 {
+		return nil
+	}
+
+	cfg := s.Env.Spec.Providers.ObjectStore
+
+	hostname, port, err := splitS3Endpoint(cfg.Endpoint, cfg.TLS)
+	if err != nil {
+		return errors.Wrap(fmt.Sprintf("invalid s3 endpoint %s", cfg.Endpoint), err)
+	}
+
+	accessKey, secretKey, err := s.getAppCredentials(app)
+	if err != nil {
+		return err
+	}
+
+	s.Config.ObjectStore = &config.ObjectStoreConfig{
+		Hostname:  hostname,
+		Port:      port,
+		AccessKey: utils.StringPtr(accessKey),
+		SecretKey: utils.StringPtr(secretKey),
+		Tls:       cfg.TLS,
+		Buckets:   []config.ObjectStoreBucket{},
+	}
+
+	for _, bucket := range app.Spec.ObjectStore {
+		requestedName := bucket.Name
+		bucket.Name = getS3BucketName(s.Env, requestedName)
+
+		found, err := s.BucketHandler.Exists(s.Ctx, bucket.Name)
+		if err != nil {
+			return newBucketError(bucketCheckErrorMsg, bucket.Name, err)
+		}
+
+		if !found {
+			if !cfg.CreateBuckets {
+				newErr := errors.NewClowderError(fmt.Sprintf("bucket %q does not exist and the environment does not create buckets", bucket.Name))
+				newErr.Requeue = true
+				return newErr
+			}
+
+			if err := s.BucketHandler.Make(s.Ctx, bucket.Name); err != nil {
+				return newBucketError(bucketCreateErrorMsg, bucket.Name, err)
+			}
+		}
+
+		// Buckets Clowder may not create are not its to configure either. The ones it manages are
+		// all configured, so that settings removed from the spec are removed from the bucket too.
+		if cfg.CreateBuckets {
+			if err := s.BucketHandler.Configure(s.Ctx, bucket); err != nil {
+				return newBucketError(bucketConfigureErrorMsg, bucket.Name, err)
+			}
+		}
+
+		newBucket := config.ObjectStoreBucket{
+			Name:          bucket.Name,
+			RequestedName: requestedName,
+			Endpoint:      utils.StringPtr(cfg.Endpoint),
+			AccessKey:     s.Config.ObjectStore.AccessKey,
+			SecretKey:     s.Config.ObjectStore.SecretKey,
+			Tls:           utils.BoolPtr(cfg.TLS),
+		}
+
+		if cfg.Region != "" {
+			newBucket.Region = utils.StringPtr(cfg.Region)
+		}
+
+		s.Config.ObjectStore.Buckets = append(s.Config.ObjectStore.Buckets, newBucket)
+	}
+
+	return nil
+}
+
+func (s *s3Provider) getSecret() (*core.Secret, error) {
+	secretRef := types.NamespacedName{
+		Name:      s.Env.Spec.Providers.ObjectStore.CredentialsSecretRef.Name,
+		Namespace: s.Env.Spec.Providers.ObjectStore.CredentialsSecretRef.Namespace,
+	}
+	if secretRef == (types.NamespacedName{}) {
+		return nil, errors.NewClowderError("no credentials secret ref defined for s3 object store")
+	}
+
+	secret := &core.Secret{}
+	if err := s.Client.Get(s.Ctx, secretRef, secret); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.HashCache.CreateOrUpdateObject(secret, true); err != nil {
+		return nil, err
+	}
+
+	if err := s.HashCache.AddClowdObjectToObject(s.Env, secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// getAppCredentials returns the keys the app is given, those of its own secret when the
+// environment sets appCredentialsSecretSuffix, otherwise those of the environment. The keys
+// Clowder manages the buckets with are only handed out in the latter case.
+func (s *s3Provider) getAppCredentials(app *crd.ClowdApp) (string, string, error) {
+	suffix := s.Env.Spec.Providers.ObjectStore.AppCredentialsSecretSuffix
+	if suffix == "" {
+		return s.accessKey, s.secretKey, nil
+	}
+
+	secretRef := types.NamespacedName{
+		Name:      fmt.Sprintf("%s-%s", app.Name, suffix),
+		Namespace: app.Namespace,
+	}
+
+	secret := &core.Secret{}
+	if err := s.Client.Get(s.Ctx, secretRef, secret); err != nil {
+		raisedErr := errors.Wrap(fmt.Sprintf("Couldn't get s3 credentials secret %s for app", secretRef.Name), err)
+		raisedErr.Requeue = true
+		return "", "", raisedErr
+	}
+
+	if _, err := s.HashCache.CreateOrUpdateObject(secret, true); err != nil {
+		return "", "", err
+	}
+
+	if err := s.HashCache.AddClowdObjectToObject(app, secret); err != nil {
+		return "", "", err
+	}
+
+	accessKey := string(secret.Data["accessKey"])
+	secretKey := string(secret.Data["secretKey"])
+	if accessKey == "" || secretKey == "" {
+		return "", "", errors.NewClowderError(fmt.Sprintf("s3 credentials secret %s must have an accessKey and a secretKey", secretRef.Name))
+	}
+
+	return accessKey, secretKey, nil
+}
+
+// getTLSConfig returns the TLS settings used to reach the server, nil when the defaults are
+// used. A CA bundle from caSecretRef replaces the system's roots.
+func (s *s3Provider) getTLSConfig() (*tls.Config, error) {
+	cfg := s.Env.Spec.Providers.ObjectStore
+	if !cfg.TLS || (cfg.CASecretRef.Name == "" && !cfg.TLSSkipVerify) {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSSkipVerify, // nolint:gosec
+	}
+
+	if cfg.CASecretRef.Name == "" {
+		return tlsConfig, nil
+	}
+
+	secretRef := types.NamespacedName{
+		Name:      cfg.CASecretRef.Name,
+		Namespace: cfg.CASecretRef.Namespace,
+	}
+
+	secret := &core.Secret{}
+	if err := s.Client.Get(s.Ctx, secretRef, secret); err != nil {
+		raisedErr := errors.Wrap("Couldn't get s3 CA secret", err)
+		raisedErr.Requeue = true
+		return nil, raisedErr
+	}
+
+	if _, err := s.HashCache.CreateOrUpdateObject(secret, true); err != nil {
+		return nil, err
+	}
+
+	if err := s.HashCache.AddClowdObjectToObject(s.Env, secret); err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(secret.Data["ca.crt"]) {
+		return nil, errors.NewClowderError(fmt.Sprintf("s3 CA secret %s has no certificates under ca.crt", secretRef.Name))
+	}
+	tlsConfig.RootCAs = pool
+
+	return tlsConfig, nil
+}
+
+// getS3BucketName returns the name of the bucket an app requests in the environment, prefixed
+// with the environment's name so that environments do not collide on a shared server. Names that
+// would be longer than S3 allows are shortened and made unique with a hash.
+func getS3BucketName(env *crd.ClowdEnvironment, requestedName string) string {
+	name := fmt.Sprintf("%s-%s", env.Name, requestedName)
+	if len(name) <= maxS3BucketNameLength {
+		return name
+	}
+
+	nameHash := fmt.Sprintf("%x", sha256.Sum256([]byte(name)))[:8]
+	prefix := strings.TrimRight(name[:maxS3BucketNameLength-len(nameHash)-1], "-.")
+	return fmt.Sprintf("%s-%s", prefix, nameHash)
+}
+
+// maxS3BucketNameLength is the longest name S3 allows for a bucket.
+const maxS3BucketNameLength = 63
+
+// splitS3Endpoint returns the hostname and port of an endpoint, defaulting the port to the one
+// of the scheme in use.
+func splitS3Endpoint(endpoint string, tls bool) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		if tls {
+			return endpoint, 443, nil
+		}
+		return endpoint, 80, nil
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, err
+	}
+	return host, port, nil
+}
+
+// newS3Client returns a client for the S3 compatible server at endpoint, a hostname with an
+// optional port. A nil tlsConfig keeps the client's defaults.
+func newS3Client(endpoint string, secure bool, region string, accessKey string, secretKey string, tlsConfig *tls.Config) (*minio.Client, error) {
+	opts := &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: secure,
+		Region: region,
+	}
+
+	if tlsConfig != nil {
+		transport, err := minio.DefaultTransport(secure)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+		opts.Transport = transport
+	}
+
+	return minio.New(endpoint, opts)
+}
@@ -0,0 +1,179 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
+
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func getTestS3Provider(t *testing.T) *s3Provider {
+	t.Helper()
+	p := getTestProvider(t)
+	hc := hashcache.NewHashCache()
+	p.HashCache = &hc
+	p.Offline = true
+	p.Env.Spec.Providers.ObjectStore = crd.ObjectStoreConfig{
+		Mode:                 "s3",
+		CredentialsSecretRef: crd.NamespacedName{Name: "rgw", Namespace: "storage"},
+		Endpoint:             "rgw.example.com:8443",
+		TLS:                  true,
+		Region:               "us-east-1",
+	}
+
+	require.NoError(t, p.Client.Create(context.TODO(), &core.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "rgw", Namespace: "storage"},
+		Data:       map[string][]byte{"accessKey": []byte("access"), "secretKey": []byte("secret")},
+	}))
+
+	sp, err := NewS3(&p)
+	require.NoError(t, err)
+	return sp.(*s3Provider)
+}
+
+func TestS3(t *testing.T) {
+	sp := getTestS3Provider(t)
+	handler := &mockBucketHandler{MockBuckets: []mockBucket{{Name: "test-existing", Exists: true}, {Name: "test-new"}}}
+	sp.BucketHandler = handler
+
+	app := &crd.ClowdApp{
+		ObjectMeta: v1.ObjectMeta{Name: "app", Namespace: "app-ns"},
+		Spec: crd.ClowdAppSpec{ObjectStore: []crd.ObjectStoreBucketSpec{
+			{Name: "existing", Versioning: true},
+			{Name: "new"},
+		}},
+	}
+
+	err := sp.Provide(app)
+	assert.ErrorContains(t, err, `bucket "test-new" does not exist and the environment does not create buckets`)
+	assert.Empty(t, handler.MakeCalls)
+	assert.Empty(t, handler.ConfigureCalls, "buckets are only configured where Clowder creates them")
+
+	sp.Env.Spec.Providers.ObjectStore.CreateBuckets = true
+	require.NoError(t, sp.Provide(app))
+	assert.Equal(t, []string{"test-new"}, handler.MakeCalls, "buckets are prefixed with the environment")
+	assert.Equal(t, []crd.ObjectStoreBucketSpec{{Name: "test-existing", Versioning: true}, {Name: "test-new"}}, handler.ConfigureCalls)
+
+	assert.Equal(t, "rgw.example.com", sp.Config.ObjectStore.Hostname)
+	assert.Equal(t, 8443, sp.Config.ObjectStore.Port)
+	assert.True(t, sp.Config.ObjectStore.Tls)
+	assert.Equal(t, "access", *sp.Config.ObjectStore.AccessKey)
+	assert.Contains(t, sp.Config.ObjectStore.Buckets, config.ObjectStoreBucket{
+		Name:          "test-new",
+		RequestedName: "new",
+		Endpoint:      utils.StringPtr("rgw.example.com:8443"),
+		AccessKey:     utils.StringPtr("access"),
+		SecretKey:     utils.StringPtr("secret"),
+		Region:        utils.StringPtr("us-east-1"),
+		Tls:           utils.TruePtr(),
+	})
+}
+
+func TestS3AppCredentials(t *testing.T) {
+	sp := getTestS3Provider(t)
+	sp.Env.Spec.Providers.ObjectStore.AppCredentialsSecretSuffix = "s3"
+	sp.BucketHandler = &mockBucketHandler{MockBuckets: []mockBucket{{Name: "test-logs", Exists: true}}}
+
+	app := &crd.ClowdApp{
+		ObjectMeta: v1.ObjectMeta{Name: "app", Namespace: "app-ns"},
+		Spec:       crd.ClowdAppSpec{ObjectStore: []crd.ObjectStoreBucketSpec{{Name: "logs"}}},
+	}
+
+	err := sp.Provide(app)
+	assert.ErrorContains(t, err, "Couldn't get s3 credentials secret app-s3 for app")
+
+	require.NoError(t, sp.Client.Create(context.TODO(), &core.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "app-s3", Namespace: "app-ns"},
+		Data:       map[string][]byte{"accessKey": []byte("app-access"), "secretKey": []byte("app-secret")},
+	}))
+
+	require.NoError(t, sp.Provide(app))
+	assert.Equal(t, "app-access", *sp.Config.ObjectStore.AccessKey)
+	assert.Equal(t, "app-secret", *sp.Config.ObjectStore.Buckets[0].SecretKey)
+	assert.Equal(t, "access", sp.accessKey, "buckets are still managed with the environment's keys")
+}
+
+func TestS3TLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	endpoint := strings.TrimPrefix(srv.URL, "https://")
+
+	sp := getTestS3Provider(t)
+	tlsConfig, err := sp.getTLSConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig, "the client's defaults are kept")
+
+	cl, err := newS3Client(endpoint, true, "us-east-1", "access", "secret", tlsConfig)
+	require.NoError(t, err)
+	_, err = cl.BucketExists(context.TODO(), "logs")
+	assert.ErrorContains(t, err, "certificate")
+
+	sp.Env.Spec.Providers.ObjectStore.CASecretRef = crd.NamespacedName{Name: "rgw-ca", Namespace: "storage"}
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, sp.Client.Create(context.TODO(), &core.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "rgw-ca", Namespace: "storage"},
+		Data:       map[string][]byte{"ca.crt": caPEM},
+	}))
+
+	tlsConfig, err = sp.getTLSConfig()
+	require.NoError(t, err)
+	cl, err = newS3Client(endpoint, true, "us-east-1", "access", "secret", tlsConfig)
+	require.NoError(t, err)
+	found, err := cl.BucketExists(context.TODO(), "logs")
+	assert.NoError(t, err, "the server is trusted through the CA bundle")
+	assert.True(t, found)
+
+	sp.Env.Spec.Providers.ObjectStore.CASecretRef = crd.NamespacedName{}
+	sp.Env.Spec.Providers.ObjectStore.TLSSkipVerify = true
+	tlsConfig, err = sp.getTLSConfig()
+	require.NoError(t, err)
+	assert.True(t, tlsConfig.InsecureSkipVerify)
+}
+
+func TestGetS3BucketName(t *testing.T) {
+	env := &crd.ClowdEnvironment{ObjectMeta: v1.ObjectMeta{Name: "env-stage"}}
+	assert.Equal(t, "env-stage-logs", getS3BucketName(env, "logs"))
+
+	env.Name = strings.Repeat("e", 40)
+	first := getS3BucketName(env, strings.Repeat("b", 30)+"-one")
+	second := getS3BucketName(env, strings.Repeat("b", 30)+"-two")
+	assert.Len(t, first, 63)
+	assert.NotEqual(t, first, second, "shortened names stay unique")
+}
+
+func TestS3MissingSecret(t *testing.T) {
+	p := getTestProvider(t)
+	p.Env.Spec.Providers.ObjectStore = crd.ObjectStoreConfig{Mode: "s3", Endpoint: "rgw.example.com"}
+
+	_, err := NewS3(&p)
+	assert.ErrorContains(t, err, "no credentials secret ref defined for s3 object store")
+}
+
+func TestSplitS3Endpoint(t *testing.T) {
+	host, port, err := splitS3Endpoint("rgw.example.com", true)
+	assert.NoError(t, err)
+	assert.Equal(t, "rgw.example.com", host)
+	assert.Equal(t, 443, port)
+
+	_, port, err = splitS3Endpoint("rgw.example.com", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 80, port)
+
+	_, _, err = splitS3Endpoint("rgw.example.com:https", true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,360 @@
+package objectstore
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+)
+
+// seedRevisionTag is the bucket tag recording the revision of the seed last uploaded to it.
+const seedRevisionTag = "clowder-seed-revision"
+
+// SeedTimeout bounds the seeding of a bucket, the download of its archive included.
+var SeedTimeout = 2 * time.Minute
+
+// SeedPollInterval is how often an app whose buckets are being seeded is reconciled to report on
+// them.
+var SeedPollInterval = 10 * time.Second
+
+// SeedRetryInterval is how long a failed seed waits before it is tried again.
+var SeedRetryInterval = 5 * time.Minute
+
+// seedMaxBytes caps the total size of the entries of a seed archive and seedMaxEntries their
+// number.
+var (
+	seedMaxBytes   int64 = 256 << 20
+	seedMaxEntries       = 10000
+)
+
+// seedHTTPClient fetches seed archives, it is replaced in tests. Redirects are held to the same
+// hosts as the seed URL itself.
+var seedHTTPClient = &http.Client{
+	Timeout: SeedTimeout,
+	CheckRedirect: func(req *http.Request, _ []*http.Request) error {
+		return crd.ValidateSeedURL(req.URL.String())
+	},
+}
+
+// seedState is the last seed of a bucket, started or finished.
+type seedState struct {
+	revision string
+	running  bool
+	err      error
+	finished time.Time
+}
+
+// seeds holds the state of the seed of each bucket, keyed by the environment and bucket name, so
+// that a bucket's tag is only read again once its seed changes or it is recreated. wg tracks the
+// seeds that are running.
+var seeds = struct {
+	sync.Mutex
+	wg     sync.WaitGroup
+	states map[string]*seedState
+}{states: map[string]*seedState{}}
+
+func seedKey(env *crd.ClowdEnvironment, bucketName string) string {
+	return fmt.Sprintf("%s/%s", env.Name, bucketName)
+}
+
+// seedBucket starts the upload of the seed of the bucket, in the background, unless the bucket
+// already holds its current revision or is being seeded with it. Objects are uploaded over those of
+// a previous revision, none are removed. The bucket's revision is only read when the bucket was
+// just created or the seed changed since it was last read. A failed seed is tried again once
+// SeedRetryInterval has passed; GetSeedStatus reports on the seeds.
+func (m *minioProvider) seedBucket(app *crd.ClowdApp, bucket crd.ObjectStoreBucketSpec, created bool) error {
+	if bucket.Seed == nil || m.Offline {
+		return nil
+	}
+
+	var revision string
+	var upload func(ctx context.Context) error
+
+	if bucket.Seed.ConfigMap != "" {
+		cm, err := m.getSeedConfigMap(app, bucket.Seed.ConfigMap)
+		if err != nil {
+			return err
+		}
+		revision, err = configMapRevision(cm)
+		if err != nil {
+			return err
+		}
+		upload = func(ctx context.Context) error {
+			return uploadConfigMap(ctx, m.BucketHandler, bucket.Name, cm)
+		}
+	} else {
+		if err := crd.ValidateSeedURL(bucket.Seed.URL); err != nil {
+			return errors.Wrap(fmt.Sprintf("seed archive %s is not allowed", bucket.Seed.URL), err)
+		}
+		revision = archiveRevision(bucket.Seed.URL)
+		url := bucket.Seed.URL
+		upload = func(ctx context.Context) error {
+			return uploadArchive(ctx, m.BucketHandler, bucket.Name, url)
+		}
+	}
+
+	key := seedKey(m.Env, bucket.Name)
+
+	seeds.Lock()
+	defer seeds.Unlock()
+
+	if state, ok := seeds.states[key]; ok && state.revision == revision {
+		switch {
+		case state.running:
+			return nil
+		case state.err == nil && !created:
+			return nil
+		case state.err != nil && time.Since(state.finished) < SeedRetryInterval:
+			return nil
+		}
+	}
+
+	state := &seedState{revision: revision, running: true}
+	seeds.states[key] = state
+	seeds.wg.Add(1)
+
+	handler := m.BucketHandler
+	log := m.Log
+	bucketName := bucket.Name
+	go func() {
+		defer seeds.wg.Done()
+
+		// the seed outlives the reconciliation that started it
+		ctx, cancel := context.WithTimeout(context.Background(), SeedTimeout)
+		defer cancel()
+
+		err := seed(ctx, handler, bucketName, revision, upload)
+		if err != nil {
+			log.Error(err, "Could not seed bucket", "bucket", bucketName)
+		}
+
+		seeds.Lock()
+		state.running = false
+		state.err = err
+		state.finished = time.Now()
+		seeds.Unlock()
+	}()
+
+	return nil
+}
+
+// seed uploads the seed unless the bucket's tag shows it already holds the revision, and then
+// records the revision in the tag.
+func seed(ctx context.Context, handler bucketHandler, bucketName string, revision string, upload func(ctx context.Context) error) error {
+	current, err := handler.GetSeedRevision(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+
+	if current == revision {
+		return nil
+	}
+
+	if err := upload(ctx); err != nil {
+		return err
+	}
+
+	return handler.SetSeedRevision(ctx, bucketName, revision)
+}
+
+// GetSeedStatus returns the app's buckets whose seed is still to be uploaded, and a description of
+// each failed seed. checked is false when none of the app's buckets is seeded by Clowder.
+func GetSeedStatus(env *crd.ClowdEnvironment, app *crd.ClowdApp) (pending []string, failed []string, checked bool) {
+	if env.Spec.Providers.ObjectStore.Mode != "minio" {
+		return nil, nil, false
+	}
+
+	seeds.Lock()
+	defer seeds.Unlock()
+
+	pending, failed = []string{}, []string{}
+	for _, bucket := range app.Spec.ObjectStore {
+		if bucket.Seed == nil {
+			continue
+		}
+		checked = true
+
+		state, ok := seeds.states[seedKey(env, bucket.Name)]
+		switch {
+		case !ok || state.running:
+			pending = append(pending, bucket.Name)
+		case state.err != nil:
+			failed = append(failed, fmt.Sprintf("%s: %s", bucket.Name, state.err))
+		}
+	}
+	return pending, failed, checked
+}
+
+// getSeedConfigMap reads the seed ConfigMap from the app's namespace and tracks it so that the
+// app is reconciled, and the bucket reseeded, when it changes.
+func (m *minioProvider) getSeedConfigMap(app *crd.ClowdApp, name string) (*core.ConfigMap, error) {
+	cm := &core.ConfigMap{}
+	if err := m.Client.Get(m.Ctx, types.NamespacedName{Name: name, Namespace: app.Namespace}, cm); err != nil {
+		return nil, errors.Wrap(fmt.Sprintf("could not get seed configmap %s", name), err)
+	}
+
+	if _, err := m.HashCache.CreateOrUpdateObject(cm, true); err != nil {
+		return nil, err
+	}
+
+	if err := m.HashCache.AddClowdObjectToObject(app, cm); err != nil {
+		return nil, err
+	}
+
+	return cm, nil
+}
+
+// configMapRevision returns a hash of the contents of the ConfigMap.
+func configMapRevision(cm *core.ConfigMap) (string, error) {
+	data, err := json.Marshal([]any{cm.Data, cm.BinaryData})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+func uploadConfigMap(ctx context.Context, handler bucketHandler, bucketName string, cm *core.ConfigMap) error {
+	keys := []string{}
+	for key := range cm.Data {
+		keys = append(keys, key)
+	}
+	for key := range cm.BinaryData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		data := []byte(cm.Data[key])
+		if binary, ok := cm.BinaryData[key]; ok {
+			data = binary
+		}
+		if err := handler.Upload(ctx, bucketName, key, bytes.NewReader(data), int64(len(data))); err != nil {
+			return errors.Wrap(fmt.Sprintf("could not upload %s", key), err)
+		}
+	}
+	return nil
+}
+
+// archiveRevision returns a hash of the archive's URL. Archives are not downloaded to check
+// whether they changed, a new version is expected to be served at a new URL.
+func archiveRevision(url string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(url)))
+}
+
+func getArchive(ctx context.Context, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := seedHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// archiveMaxBytes caps the size of a seed archive as a stream, which also holds a header and
+// padding for each entry. Headers of long names take a few more blocks.
+func archiveMaxBytes() int64 {
+	return seedMaxBytes + int64(seedMaxEntries+1)*4*512
+}
+
+// seedTooLarge is the error of a seed archive that goes over seedMaxBytes.
+func seedTooLarge() error {
+	return errors.NewClowderError(fmt.Sprintf("seed archive is larger than %d bytes", seedMaxBytes))
+}
+
+// cappedReader reads up to n bytes from r and fails once r holds more, rather than ending the
+// stream as io.LimitReader does.
+type cappedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > c.n+1 {
+		p = p[:c.n+1]
+	}
+	n, err := c.r.Read(p)
+	c.n -= int64(n)
+	if c.n < 0 {
+		return n, seedTooLarge()
+	}
+	return n, err
+}
+
+// uploadArchive uploads every regular file of the tar archive served at url, which may be
+// gzipped, as an object named after its path in the archive. The archive is held to
+// seedMaxEntries entries of seedMaxBytes in all, and is read no further, whether compressed or not,
+// than such an archive would be.
+func uploadArchive(ctx context.Context, handler bucketHandler, bucketName string, url string) error {
+	resp, err := getArchive(ctx, http.MethodGet, url)
+	if err != nil {
+		return errors.Wrap(fmt.Sprintf("could not download seed archive %s", url), err)
+	}
+	defer resp.Body.Close() // nolint:errcheck  // no need to check error return value
+
+	var reader io.Reader = bufio.NewReader(&cappedReader{r: resp.Body, n: archiveMaxBytes()})
+	if magic, err := reader.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return errors.Wrap("could not decompress seed archive", err)
+		}
+		defer gz.Close() // nolint:errcheck  // no need to check error return value
+		reader = &cappedReader{r: gz, n: archiveMaxBytes()}
+	}
+
+	entries := 0
+	var size int64
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap("could not read seed archive", err)
+		}
+
+		entries++
+		size += header.Size
+		if entries > seedMaxEntries {
+			return errors.NewClowderError(fmt.Sprintf("seed archive has more than %d entries", seedMaxEntries))
+		}
+		if size > seedMaxBytes {
+			return seedTooLarge()
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if err := handler.Upload(ctx, bucketName, name, tr, header.Size); err != nil {
+			return errors.Wrap(fmt.Sprintf("could not upload %s", name), err)
+		}
+	}
+}
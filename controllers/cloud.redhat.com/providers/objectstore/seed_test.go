@@ -0,0 +1,209 @@
+package objectstore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	core "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+)
+
+// reprovide runs Provide as a new reconciliation would, with an empty resource cache.
+func reprovide(t *testing.T, mp *minioProvider, app *crd.ClowdApp) {
+	t.Helper()
+	log := logr.Discard()
+	cache := rc.NewObjectCache(context.TODO(), mp.Client, &log, rc.NewCacheConfig(mp.Client.Scheme(), nil, nil, rc.Options{}))
+	mp.Cache = &cache
+	require.NoError(t, mp.Provide(app))
+	seeds.wg.Wait()
+}
+
+// forgetSeeds clears the seeds of earlier tests.
+func forgetSeeds() {
+	seeds.wg.Wait()
+	seeds.Lock()
+	defer seeds.Unlock()
+	seeds.states = map[string]*seedState{}
+}
+
+func TestMinioSeedConfigMap(t *testing.T) {
+	forgetSeeds()
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "fixtures"}})
+	app.Spec.ObjectStore[0].Seed = &crd.ObjectStoreSeedSpec{ConfigMap: "fixtures"}
+
+	cm := &core.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "fixtures", Namespace: "app-ns"},
+		Data:       map[string]string{"a.json": "{}"},
+		BinaryData: map[string][]byte{"b.bin": {0x1}},
+	}
+	require.NoError(t, mp.Client.Create(context.TODO(), cm))
+
+	require.NoError(t, mp.Provide(app))
+	seeds.wg.Wait()
+	assert.Equal(t, map[string]string{"fixtures/a.json": "{}", "fixtures/b.bin": "\x01"}, handler.Uploads)
+	assert.NotEmpty(t, handler.SeedRevisions["fixtures"])
+
+	handler.Uploads = nil
+	reprovide(t, mp, app)
+	assert.Empty(t, handler.Uploads, "the same revision is only uploaded once")
+
+	handler.MockBuckets[0].Exists = true
+	reads := handler.SeedRevisionReads
+	reprovide(t, mp, app)
+	assert.Equal(t, reads, handler.SeedRevisionReads, "the revision of an existing bucket is not read again")
+
+	cm.Data["a.json"] = `{"changed": true}`
+	require.NoError(t, mp.Client.Update(context.TODO(), cm))
+	reprovide(t, mp, app)
+	assert.Equal(t, `{"changed": true}`, handler.Uploads["fixtures/a.json"], "a changed source is reseeded")
+}
+
+func TestMinioSeedArchive(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./data/", Typeflag: tar.TypeDir, Mode: 0755}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "./data/one.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 3}))
+	_, err := tw.Write([]byte("one"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+	t.Setenv(crd.SeedURLHostsEnv, "127.0.0.1")
+
+	forgetSeeds()
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "fixtures", Exists: true}})
+	app.Spec.ObjectStore[0].Seed = &crd.ObjectStoreSeedSpec{URL: server.URL + "/fixtures-v1.tar.gz"}
+
+	require.NoError(t, mp.Provide(app))
+	seeds.wg.Wait()
+	assert.Equal(t, map[string]string{"fixtures/data/one.txt": "one"}, handler.Uploads)
+	assert.Equal(t, 1, downloads)
+	assert.Equal(t, 1, handler.SeedRevisionReads)
+
+	reprovide(t, mp, app)
+	assert.Equal(t, 1, downloads, "an unchanged archive is not downloaded again")
+	assert.Equal(t, 1, handler.SeedRevisionReads, "nor is the bucket's revision read again")
+
+	app.Spec.ObjectStore[0].Seed.URL = server.URL + "/fixtures-v2.tar.gz"
+	reprovide(t, mp, app)
+	assert.Equal(t, 2, downloads, "a changed archive URL is reseeded")
+}
+
+func TestMinioSeedArchiveNotAllowed(t *testing.T) {
+	forgetSeeds()
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "fixtures", Exists: true}})
+	app.Spec.ObjectStore[0].Seed = &crd.ObjectStoreSeedSpec{URL: "http://169.254.169.254/latest/meta-data"}
+
+	assert.ErrorContains(t, mp.Provide(app), "is not allowed")
+	assert.Empty(t, handler.Uploads)
+}
+
+// tarball returns a gzipped tar archive holding a file of each size.
+func tarball(t *testing.T, sizes ...int) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for i, size := range sizes {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("%d.txt", i), Typeflag: tar.TypeReg, Mode: 0644, Size: int64(size)}))
+		_, err := tw.Write(bytes.Repeat([]byte("x"), size))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestUploadArchiveLimits(t *testing.T) {
+	maxBytes, maxEntries := seedMaxBytes, seedMaxEntries
+	seedMaxBytes, seedMaxEntries = 1024, 3
+	defer func() { seedMaxBytes, seedMaxEntries = maxBytes, maxEntries }()
+
+	archives := map[string][]byte{
+		"/fits.tar.gz":    tarball(t, 500, 524),
+		"/large.tar.gz":   tarball(t, 1025),
+		"/entries.tar.gz": tarball(t, 1, 1, 1, 1),
+		"/bomb.tar.gz":    tarball(t, 64<<10),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(archives[r.URL.Path])
+	}))
+	defer server.Close()
+
+	handler := &mockBucketHandler{}
+	assert.NoError(t, uploadArchive(context.TODO(), handler, "fixtures", server.URL+"/fits.tar.gz"))
+	assert.Len(t, handler.Uploads, 2)
+	assert.ErrorContains(t, uploadArchive(context.TODO(), handler, "fixtures", server.URL+"/large.tar.gz"), "larger than 1024 bytes")
+	assert.ErrorContains(t, uploadArchive(context.TODO(), handler, "fixtures", server.URL+"/entries.tar.gz"), "more than 3 entries")
+	assert.ErrorContains(t, uploadArchive(context.TODO(), handler, "fixtures", server.URL+"/bomb.tar.gz"), "larger than 1024 bytes")
+}
+
+func TestMinioSeedStatus(t *testing.T) {
+	forgetSeeds()
+	release := make(chan struct{})
+	fail := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		if fail {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(tarball(t, 3))
+	}))
+	defer server.Close()
+	t.Setenv(crd.SeedURLHostsEnv, "127.0.0.1")
+
+	handler, app, mp := setupBucketTest(t, []mockBucket{{Name: "fixtures", Exists: true}, {Name: "plain", Exists: true}})
+	app.Spec.ObjectStore[0].Seed = &crd.ObjectStoreSeedSpec{URL: server.URL + "/fixtures.tar.gz"}
+	mp.Env.Spec.Providers.ObjectStore.Mode = "minio"
+
+	_, _, checked := GetSeedStatus(mp.Env, &crd.ClowdApp{})
+	assert.False(t, checked, "apps without seeds are not reported on")
+
+	require.NoError(t, mp.Provide(app))
+	pending, failed, checked := GetSeedStatus(mp.Env, app)
+	assert.True(t, checked)
+	assert.Equal(t, []string{"fixtures"}, pending, "the seed is uploaded in the background")
+	assert.Empty(t, failed)
+
+	close(release)
+	seeds.wg.Wait()
+	pending, failed, _ = GetSeedStatus(mp.Env, app)
+	assert.Empty(t, pending)
+	require.Len(t, failed, 1)
+	assert.Contains(t, failed[0], "404")
+
+	fail = false
+	reprovide(t, mp, app)
+	assert.Empty(t, handler.Uploads, "a failed seed waits before it is tried again")
+
+	seeds.Lock()
+	seeds.states[seedKey(mp.Env, "fixtures")].finished = time.Now().Add(-SeedRetryInterval)
+	seeds.Unlock()
+	reprovide(t, mp, app)
+	assert.Equal(t, map[string]string{"fixtures/0.txt": "xxx"}, handler.Uploads)
+	pending, failed, _ = GetSeedStatus(mp.Env, app)
+	assert.Empty(t, pending)
+	assert.Empty(t, failed)
+}
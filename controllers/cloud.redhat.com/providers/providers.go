@@ -85,6 +85,9 @@ type Provider struct {
 	Log       logr.Logger
 	Config    *config.AppConfig
 	HashCache *hashcache.HashCache
+	// Offline is set when the providers are run by the render command against an in-memory
+	// client, providers must not reach out to services outside of Kubernetes when it is set.
+	Offline bool
 }
 
 // GetClient returns the Kubernetes client
@@ -136,6 +139,12 @@ type ClowderProvider interface {
 	GetConfig() *config.AppConfig
 }
 
+// AppFinalizer is an optional interface a ClowderProvider can implement when it manages state
+// outside of Kubernetes objects that must be cleaned up when a ClowdApp is deleted.
+type AppFinalizer interface {
+	FinalizeApp(app *crd.ClowdApp) error
+}
+
 type makeFnCache func(env *crd.ClowdEnvironment, o obj.ClowdObject, objMap ObjectMap, usePVC bool, nodePort bool) error
 
 func createResource(cache *rc.ObjectCache, resourceIdent rc.ResourceIdent, nn types.NamespacedName) (client.Object, error) {
@@ -4,8 +4,6 @@ package servicemesh
 import (
 	"fmt"
 
-	apps "k8s.io/api/apps/v1"
-
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
 	deployProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/deployment"
@@ -31,20 +29,19 @@ func (ch *servicemeshProvider) Provide(_ *crd.ClowdApp) error {
 		return nil
 	}
 
-	dList := apps.DeploymentList{}
-	if err := ch.Cache.List(deployProvider.CoreDeployment, &dList); err != nil {
+	workloads, err := deployProvider.ListWorkloads(ch.Cache)
+	if err != nil {
 		return err
 	}
 
-	for _, deployment := range dList.Items {
-		innerDeployment := deployment
+	for _, w := range workloads {
 		annotations := map[string]string{
 			"sidecar.istio.io/inject":                       "true",
 			"traffic.sidecar.istio.io/excludeOutboundPorts": "443,9093,5432,10000",
 		}
-		utils.UpdateAnnotations(&innerDeployment.Spec.Template, annotations)
+		utils.UpdateAnnotations(w.Template, annotations)
 
-		err := ch.Cache.Update(deployProvider.CoreDeployment, &innerDeployment)
+		err := deployProvider.UpdateWorkload(ch.Cache, w)
 		if err != nil {
 			return fmt.Errorf("could not update annotations: %w", err)
 		}
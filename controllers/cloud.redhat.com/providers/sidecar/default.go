@@ -12,7 +12,6 @@ import (
 	deployProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/deployment"
 	provutils "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/utils"
 
-	apps "k8s.io/api/apps/v1"
 	batch "k8s.io/api/batch/v1"
 	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -35,9 +34,8 @@ func (sc *sidecarProvider) EnvProvide() error {
 func (sc *sidecarProvider) Provide(app *crd.ClowdApp) error {
 	for _, deployment := range app.Spec.Deployments {
 		innerDeployment := deployment
-		d := &apps.Deployment{}
-
-		if err := sc.Cache.Get(deployProvider.CoreDeployment, d, app.GetDeploymentNamespacedName(&innerDeployment)); err != nil {
+		w, err := deployProvider.GetWorkload(sc.Cache, app, &innerDeployment)
+		if err != nil {
 			return err
 		}
 
@@ -47,7 +45,7 @@ func (sc *sidecarProvider) Provide(app *crd.ClowdApp) error {
 				if sidecar.Enabled && sc.Env.Spec.Providers.Sidecars.TokenRefresher.Enabled {
 					cont := getTokenRefresher(app.Name, &sidecar)
 					if cont != nil {
-						d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, *cont)
+						w.Template.Spec.Containers = append(w.Template.Spec.Containers, *cont)
 					}
 				}
 			case "otel-collector":
@@ -58,8 +56,8 @@ func (sc *sidecarProvider) Provide(app *crd.ClowdApp) error {
 					cont := getOtelCollector(app.Name, sc.Env, mergedEnvVars, &sidecar)
 					if cont != nil {
 						configMapName := GetOtelCollectorConfigMap(sc.Env, app.Name, &sidecar)
-						d.Spec.Template.Spec.InitContainers = append(d.Spec.Template.Spec.InitContainers, *cont)
-						d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, core.Volume{
+						w.Template.Spec.InitContainers = append(w.Template.Spec.InitContainers, *cont)
+						w.Template.Spec.Volumes = append(w.Template.Spec.Volumes, core.Volume{
 							Name: fmt.Sprintf("%s-otel-config", app.Name),
 							VolumeSource: core.VolumeSource{
 								ConfigMap: &core.ConfigMapVolumeSource{
@@ -77,7 +75,7 @@ func (sc *sidecarProvider) Provide(app *crd.ClowdApp) error {
 			}
 		}
 
-		if err := sc.Cache.Update(deployProvider.CoreDeployment, d); err != nil {
+		if err := deployProvider.UpdateWorkload(sc.Cache, w); err != nil {
 			return err
 		}
 	}
@@ -1,7 +1,6 @@
 package web
 
 import (
-	apps "k8s.io/api/apps/v1"
 	batch "k8s.io/api/batch/v1"
 
 	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
@@ -60,16 +59,14 @@ func (web *webProvider) Provide(app *crd.ClowdApp) error {
 		// mount CA cert volume on Deployments if TLS is configured in the environment
 		// (whether it is globally enabled or not, we will always mount the volume)
 		if provutils.IsTLSConfiguredForEnv(envTLSConfig) {
-			d := &apps.Deployment{}
-			dnn := app.GetDeploymentNamespacedName(&innerDeployment)
-
-			if err := web.Cache.Get(provDeploy.CoreDeployment, d, dnn); err != nil {
+			w, err := provDeploy.GetWorkload(web.Cache, app, &innerDeployment)
+			if err != nil {
 				return errors.Wrap("getting core deployment", err)
 			}
 
-			provutils.AddCertVolume(&d.Spec.Template.Spec, dnn.Name)
+			provutils.AddCertVolume(&w.Template.Spec, w.GetName())
 
-			if err := web.Cache.Update(provDeploy.CoreDeployment, d); err != nil {
+			if err := provDeploy.UpdateWorkload(web.Cache, w); err != nil {
 				return errors.Wrap("updating core deployment", err)
 			}
 		}
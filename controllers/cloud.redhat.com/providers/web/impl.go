@@ -7,7 +7,6 @@ import (
 	deployProvider "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/deployment"
 	provutils "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/utils"
 
-	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -35,9 +34,8 @@ func makeService(cache *rc.ObjectCache, deployment *crd.Deployment, app *crd.Clo
 		return err
 	}
 
-	d := &apps.Deployment{}
-
-	if err := cache.Get(deployProvider.CoreDeployment, d, app.GetDeploymentNamespacedName(deployment)); err != nil {
+	w, err := deployProvider.GetWorkload(cache, app, deployment)
+	if err != nil {
 		return err
 	}
 
@@ -232,19 +230,19 @@ func makeService(cache *rc.ObjectCache, deployment *crd.Deployment, app *crd.Clo
 		if err := generateCaddyConfigMap(cache, nn, app, pubTLS, privTLS, pubPort, privPort, pubH2CTLS, privH2CTLS, pubH2CPort, privH2CPort, env, appH2CTargetPort, appH2CPrivateTargetPort); err != nil {
 			return err
 		}
-		populateSideCar(d, nn.Name, env.Spec.Providers.Web.TLS.Port, env.Spec.Providers.Web.TLS.PrivatePort, env.Spec.Providers.Web.TLS.H2CPort, env.Spec.Providers.Web.TLS.H2CPrivatePort, pubTLS, privTLS, pubH2CTLS, privH2CTLS, env)
+		populateSideCar(w.Template, nn.Name, env.Spec.Providers.Web.TLS.Port, env.Spec.Providers.Web.TLS.PrivatePort, env.Spec.Providers.Web.TLS.H2CPort, env.Spec.Providers.Web.TLS.H2CPrivatePort, pubTLS, privTLS, pubH2CTLS, privH2CTLS, env)
 		setServiceTLSAnnotations(s, nn.Name)
 	}
 
 	utils.MakeService(s, nn, map[string]string{"pod": nn.Name}, servicePorts, app, env.IsNodePort())
 
-	d.Spec.Template.Spec.Containers[0].Ports = containerPorts
+	w.Template.Spec.Containers[0].Ports = containerPorts
 
 	if err := cache.Update(CoreService, s); err != nil {
 		return err
 	}
 
-	return cache.Update(deployProvider.CoreDeployment, d)
+	return deployProvider.UpdateWorkload(cache, w)
 }
 
 func generateCaddyConfigMap(cache *rc.ObjectCache, nn types.NamespacedName, app *crd.ClowdApp, pub bool, priv bool, pubPort int32, privPort int32, pubH2C bool, privH2C bool, pubH2CPort int32, privH2CPort int32, env *crd.ClowdEnvironment, appH2CTargetPort int32, appH2CPrivateTargetPort int32) error {
@@ -274,7 +272,7 @@ func generateCaddyConfigMap(cache *rc.ObjectCache, nn types.NamespacedName, app
 	return cache.Update(CoreCaddyConfigMap, cm)
 }
 
-func populateSideCar(d *apps.Deployment, name string, port int32, privatePort int32, h2cPort int32, h2cPrivatePort int32, pub bool, priv bool, pubH2C bool, privH2C bool, env *crd.ClowdEnvironment) {
+func populateSideCar(t *core.PodTemplateSpec, name string, port int32, privatePort int32, h2cPort int32, h2cPrivatePort int32, pub bool, priv bool, pubH2C bool, privH2C bool, env *crd.ClowdEnvironment) {
 	ports := []core.ContainerPort{}
 	if pub {
 		ports = append(ports, core.ContainerPort{
@@ -339,13 +337,13 @@ func populateSideCar(d *apps.Deployment, name string, port int32, privatePort in
 		VolumeSource: core.VolumeSource{
 			ConfigMap: &core.ConfigMapVolumeSource{
 				LocalObjectReference: core.LocalObjectReference{
-					Name: caddyConfigName(d.Name),
+					Name: caddyConfigName(name),
 				},
 			},
 		},
 	}
-	d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, container)
-	d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, caddyConfigVol, caddyTLSVol)
+	t.Spec.Containers = append(t.Spec.Containers, container)
+	t.Spec.Volumes = append(t.Spec.Volumes, caddyConfigVol, caddyTLSVol)
 }
 
 func setServiceTLSAnnotations(s *core.Service, name string) {
@@ -13,7 +13,6 @@ import (
 	provDeploy "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/deployment"
 	provutils "github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/utils"
 
-	apps "k8s.io/api/apps/v1"
 	core "k8s.io/api/core/v1"
 	networking "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -155,25 +154,24 @@ func (web *localWebProvider) Provide(app *crd.ClowdApp) error {
 		h.Write([]byte(jsonData))
 		hash := fmt.Sprintf("%x", h.Sum(nil))
 
-		d := &apps.Deployment{}
-		dnn := app.GetDeploymentNamespacedName(&innerDeployment)
-		if err := web.Cache.Get(provDeploy.CoreDeployment, d, dnn); err != nil {
+		w, err := provDeploy.GetWorkload(web.Cache, app, &innerDeployment)
+		if err != nil {
 			return err
 		}
 
 		if provutils.IsTLSConfiguredForEnv(envTLSConfig) {
 			// mount CA cert volume on Deployments if TLS is configured in the environment
 			// (whether it is globally enabled or not, we will always mount the volume)
-			provutils.AddCertVolume(&d.Spec.Template.Spec, dnn.Name)
+			provutils.AddCertVolume(&w.Template.Spec, w.GetName())
 		}
 
 		annotations := map[string]string{
 			"clowder/authsidecar-confighash": hash,
 		}
 
-		utils.UpdateAnnotations(&d.Spec.Template, annotations)
+		utils.UpdateAnnotations(w.Template, annotations)
 
-		if err := web.Cache.Update(provDeploy.CoreDeployment, d); err != nil {
+		if err := provDeploy.UpdateWorkload(web.Cache, w); err != nil {
 			return err
 		}
 
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/go-logr/logr"
+	core "k8s.io/api/core/v1"
+	k8serr "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers"
+
+	rc "github.com/RedHatInsights/rhc-osdk-utils/resourceCache"
+)
+
+// renderAttempts is how many times the providers are run for an object before giving up. Some
+// providers wait on operators to fill in the status of resources written by an earlier provider,
+// in the cluster that is handled by requeueing, here the status is filled in and the providers are
+// run again.
+const renderAttempts = 3
+
+// renderClient records every object written through it, in the order they were first written.
+type renderClient struct {
+	client.Client
+	keys    []string
+	objects map[string]client.Object
+}
+
+func (c *renderClient) record(obj client.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return err
+	}
+
+	copied := obj.DeepCopyObject().(client.Object)
+	copied.GetObjectKind().SetGroupVersionKind(gvk)
+	copied.SetResourceVersion("")
+	copied.SetManagedFields(nil)
+
+	key := fmt.Sprintf("%s/%s/%s", gvk.String(), obj.GetNamespace(), obj.GetName())
+	if _, ok := c.objects[key]; !ok {
+		c.keys = append(c.keys, key)
+	}
+	c.objects[key] = copied
+	return nil
+}
+
+func (c *renderClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	convertStringData(obj)
+	if err := c.Client.Create(ctx, obj, opts...); err != nil {
+		return err
+	}
+	return c.record(obj)
+}
+
+func (c *renderClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	convertStringData(obj)
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	return c.record(obj)
+}
+
+func (c *renderClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	convertStringData(obj)
+	if err := c.Client.Patch(ctx, obj, patch, opts...); err != nil {
+		return err
+	}
+	return c.record(obj)
+}
+
+// convertStringData moves the stringData of a secret into its data, as the API server would.
+func convertStringData(obj client.Object) {
+	secret, ok := obj.(*core.Secret)
+	if !ok || len(secret.StringData) == 0 {
+		return
+	}
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	for k, v := range secret.StringData {
+		secret.Data[k] = []byte(v)
+	}
+	secret.StringData = nil
+}
+
+// Objects returns the recorded objects in the order they were first written.
+func (c *renderClient) Objects() []client.Object {
+	objs := []client.Object{}
+	for _, key := range c.keys {
+		objs = append(objs, c.objects[key])
+	}
+	return objs
+}
+
+// Render runs the registered providers for the environment and then for each of the apps against
+// an in-memory client, the way the reconcilers would, and returns every object that applying the
+// caches would have written. No cluster is needed, providers are run with Offline set and the
+// status that operators such as Strimzi would normally fill in is faked.
+func Render(ctx context.Context, log logr.Logger, env *crd.ClowdEnvironment, apps []crd.ClowdApp) ([]client.Object, error) {
+	ctx = context.WithValue(ctx, errors.ClowdKey("log"), &log)
+
+	env.Status.TargetNamespace = env.Spec.TargetNamespace
+	if env.Status.TargetNamespace == "" {
+		env.Status.TargetNamespace = env.Name
+	}
+
+	seed := []client.Object{env}
+	namespaces := map[string]bool{env.Status.TargetNamespace: true}
+	for i := range apps {
+		apps[i].Spec.EnvName = env.Name
+		seed = append(seed, &apps[i])
+		namespaces[apps[i].Namespace] = true
+	}
+	for name := range namespaces {
+		ns := &core.Namespace{}
+		ns.Name = name
+		seed = append(seed, ns)
+	}
+
+	envNameIndex := func(o client.Object) []string {
+		switch obj := o.(type) {
+		case *crd.ClowdApp:
+			return []string{obj.Spec.EnvName}
+		case *crd.ClowdAppRef:
+			return []string{obj.Spec.EnvName}
+		}
+		return nil
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(Scheme).
+		WithObjects(seed...).
+		WithStatusSubresource(&crd.ClowdEnvironment{}, &crd.ClowdApp{}, &strimzi.Kafka{}, &strimzi.KafkaUser{}).
+		WithIndex(&crd.ClowdApp{}, "spec.envName", envNameIndex).
+		WithIndex(&crd.ClowdAppRef{}, "spec.envName", envNameIndex).
+		Build()
+
+	rClient := &renderClient{Client: fakeClient, objects: map[string]client.Object{}}
+	hashCache := hashcache.NewHashCache()
+
+	newCache := func() *rc.ObjectCache {
+		cacheConfig := rc.NewCacheConfig(Scheme, nil, ProtectedGVKs, rc.Options{StrictGVK: true, Ordering: applyOrder})
+		cache := rc.NewObjectCache(ctx, rClient, &log, cacheConfig)
+		return &cache
+	}
+
+	err := renderWithRetries(ctx, fakeClient, func() error {
+		provider := providers.Provider{
+			Ctx:       ctx,
+			Client:    rClient,
+			Env:       env,
+			Cache:     newCache(),
+			Log:       log,
+			HashCache: &hashCache,
+			Offline:   true,
+		}
+		if err := runProvidersForEnv(log, provider); err != nil {
+			return err
+		}
+		return provider.Cache.ApplyAll()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("env %s: %w", env.Name, err)
+	}
+
+	for i := range apps {
+		app := &apps[i]
+		err := renderWithRetries(ctx, fakeClient, func() error {
+			provider := providers.Provider{
+				Ctx:       ctx,
+				Client:    rClient,
+				Env:       env,
+				Cache:     newCache(),
+				Log:       log,
+				Config:    &config.AppConfig{},
+				HashCache: &hashCache,
+				Offline:   true,
+			}
+			r := ClowdAppReconciliation{
+				ctx:       ctx,
+				client:    rClient,
+				app:       app,
+				env:       env,
+				log:       &log,
+				config:    provider.Config,
+				cache:     provider.Cache,
+				hashCache: &hashCache,
+			}
+			if err := r.runProvidersImplementation(&provider); err != nil {
+				return err
+			}
+			return provider.Cache.ApplyAll()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("app %s: %w", app.Name, err)
+		}
+	}
+
+	return rClient.Objects(), nil
+}
+
+func renderWithRetries(ctx context.Context, c client.Client, run func() error) error {
+	var err error
+	for attempt := 0; attempt < renderAttempts; attempt++ {
+		if err = run(); err == nil {
+			return nil
+		}
+		if fakeErr := fakeOperatorStatus(ctx, c); fakeErr != nil {
+			return fakeErr
+		}
+	}
+	return err
+}
+
+// fakeOperatorStatus fills in the status of Strimzi resources, and the secrets Strimzi would
+// create, as if the operator had reconciled them. It writes to the underlying client so none of
+// it is rendered.
+func fakeOperatorStatus(ctx context.Context, c client.Client) error {
+	kafkas := strimzi.KafkaList{}
+	if err := c.List(ctx, &kafkas); err != nil {
+		return err
+	}
+	for i := range kafkas.Items {
+		kafka := &kafkas.Items[i]
+		if kafka.Status != nil && kafka.Status.Listeners != nil {
+			continue
+		}
+		host := fmt.Sprintf("%s-kafka-bootstrap.%s.svc", kafka.Name, kafka.Namespace)
+		kafka.Status = &strimzi.KafkaStatus{}
+		for _, listener := range kafka.Spec.Kafka.Listeners {
+			kafka.Status.Listeners = append(kafka.Status.Listeners, strimzi.KafkaStatusListenersElem{
+				Name: ptr.To(listener.Name),
+				Addresses: []strimzi.KafkaStatusListenersElemAddressesElem{{
+					Host: ptr.To(host),
+					Port: ptr.To(listener.Port),
+				}},
+			})
+		}
+		if err := c.Status().Update(ctx, kafka); err != nil {
+			return err
+		}
+		caSecret := types.NamespacedName{Name: fmt.Sprintf("%s-cluster-ca-cert", kafka.Name), Namespace: kafka.Namespace}
+		if err := ensureFakeSecret(ctx, c, caSecret, map[string][]byte{"ca.crt": []byte("rendered-ca")}); err != nil {
+			return err
+		}
+	}
+
+	users := strimzi.KafkaUserList{}
+	if err := c.List(ctx, &users); err != nil {
+		return err
+	}
+	for i := range users.Items {
+		user := &users.Items[i]
+		if user.Status != nil && user.Status.Username != nil {
+			continue
+		}
+		user.Status = &strimzi.KafkaUserStatus{
+			Username: ptr.To(user.Name),
+			Secret:   ptr.To(user.Name),
+		}
+		if err := c.Status().Update(ctx, user); err != nil {
+			return err
+		}
+		userSecret := types.NamespacedName{Name: user.Name, Namespace: user.Namespace}
+		if err := ensureFakeSecret(ctx, c, userSecret, map[string][]byte{"password": []byte("rendered-password")}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ensureFakeSecret(ctx context.Context, c client.Client, nn types.NamespacedName, data map[string][]byte) error {
+	secret := &core.Secret{}
+	err := c.Get(ctx, nn, secret)
+	if err == nil {
+		return nil
+	}
+	if !k8serr.IsNotFound(err) {
+		return err
+	}
+	secret.Name = nn.Name
+	secret.Namespace = nn.Namespace
+	secret.Data = data
+	return c.Create(ctx, secret)
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/config"
+)
+
+func TestRender(t *testing.T) {
+	env := &crd.ClowdEnvironment{}
+	env.Name = "render-env"
+	env.Spec.TargetNamespace = "render"
+	env.Spec.Providers = crd.ProvidersConfig{
+		Web:         crd.WebConfig{Port: 8000, Mode: "operator"},
+		Metrics:     crd.MetricsConfig{Port: 9000, Mode: "operator", Path: "/metrics"},
+		Kafka:       crd.KafkaConfig{Mode: "none"},
+		Database:    crd.DatabaseConfig{Mode: "local"},
+		Logging:     crd.LoggingConfig{Mode: "none"},
+		ObjectStore: crd.ObjectStoreConfig{Mode: "minio"},
+		InMemoryDB:  crd.InMemoryDBConfig{Mode: "none"},
+	}
+
+	app := crd.ClowdApp{}
+	app.Name = "hello"
+	app.Namespace = "render"
+	app.Spec.EnvName = env.Name
+	app.Spec.Deployments = []crd.Deployment{{
+		Name:    "app",
+		PodSpec: crd.PodSpec{Image: "quay.io/psav/clowder-hello"},
+	}}
+	app.Spec.Database = crd.DatabaseSpec{Name: "hello-db", Version: ptr.To(int32(16))}
+	app.Spec.ObjectStore = []crd.ObjectStoreBucketSpec{{Name: "hello-bucket"}}
+
+	objs, err := Render(context.Background(), logr.Discard(), env, []crd.ClowdApp{app})
+	require.NoError(t, err)
+
+	var deployments []string
+	var appConfig *config.AppConfig
+	for _, obj := range objs {
+		assert.NotEmpty(t, obj.GetObjectKind().GroupVersionKind().Kind, "rendered objects carry their kind")
+		switch o := obj.(type) {
+		case *apps.Deployment:
+			deployments = append(deployments, o.Name)
+		case *core.Secret:
+			if o.Name == "hello" && o.Namespace == "render" {
+				appConfig = &config.AppConfig{}
+				require.NoError(t, json.Unmarshal(o.Data["cdappconfig.json"], appConfig))
+			}
+		}
+	}
+
+	assert.Contains(t, deployments, "hello-app")
+	assert.Contains(t, deployments, "hello-db", "the local db is rendered")
+	assert.Contains(t, deployments, "render-env-minio", "the local minio is rendered")
+
+	require.NotNil(t, appConfig, "the cdappconfig.json secret is rendered")
+	require.NotNil(t, appConfig.Database)
+	assert.Equal(t, "hello-db.render.svc", appConfig.Database.Hostname)
+	require.NotNil(t, appConfig.ObjectStore)
+	assert.Equal(t, "hello-bucket", appConfig.ObjectStore.Buckets[0].Name)
+}
+
+func TestRenderKafkaConnectors(t *testing.T) {
+	env := &crd.ClowdEnvironment{}
+	env.Name = "render-env"
+	env.Spec.TargetNamespace = "render"
+	env.Spec.Providers = crd.ProvidersConfig{
+		Web:         crd.WebConfig{Port: 8000, Mode: "operator"},
+		Metrics:     crd.MetricsConfig{Port: 9000, Mode: "operator", Path: "/metrics"},
+		Kafka:       crd.KafkaConfig{Mode: "operator"},
+		Database:    crd.DatabaseConfig{Mode: "local"},
+		Logging:     crd.LoggingConfig{Mode: "none"},
+		ObjectStore: crd.ObjectStoreConfig{Mode: "minio"},
+		InMemoryDB:  crd.InMemoryDBConfig{Mode: "none"},
+	}
+
+	app := crd.ClowdApp{}
+	app.Name = "hello"
+	app.Namespace = "render"
+	app.Spec.ObjectStore = []crd.ObjectStoreBucketSpec{{Name: "hello-bucket"}}
+	app.Spec.KafkaConnectors = []crd.KafkaConnectorSpec{{
+		Name:  "archive",
+		Class: "io.confluent.connect.s3.S3SinkConnector",
+		Config: map[string]string{
+			"s3.bucket.name": `{{ (bucket "hello-bucket").Name }}`,
+			"topics":         "hello.archive",
+		},
+	}}
+
+	// the connectors are rendered by the registered providers in their real order, after the
+	// object store provider has filled in the app's buckets
+	objs, err := Render(context.Background(), logr.Discard(), env, []crd.ClowdApp{app})
+	require.NoError(t, err)
+
+	var connector *strimzi.KafkaConnector
+	var secret *core.Secret
+	for _, obj := range objs {
+		switch o := obj.(type) {
+		case *strimzi.KafkaConnector:
+			connector = o
+		case *core.Secret:
+			if o.Name == "hello-kafka-connectors" {
+				secret = o
+			}
+		}
+	}
+
+	require.NotNil(t, connector)
+	cfg := map[string]string{}
+	require.NoError(t, json.Unmarshal(connector.Spec.Config.Raw, &cfg))
+	assert.Equal(t, "${secrets:render/hello-kafka-connectors:archive.s3.bucket.name}", cfg["s3.bucket.name"])
+	assert.Equal(t, "hello.archive", cfg["topics"])
+
+	require.NotNil(t, secret)
+	assert.Equal(t, "hello-bucket", string(secret.Data["archive.s3.bucket.name"]))
+}
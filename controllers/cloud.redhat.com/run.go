@@ -4,6 +4,7 @@ import (
 	"context"
 	_ "embed"
 	"os"
+	"time"
 
 	cyndi "github.com/RedHatInsights/cyndi-operator/api/v1alpha1"
 	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
@@ -20,6 +21,7 @@ import (
 
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/clowderconfig"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/hashcache"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
 
 	core "k8s.io/api/core/v1"
 	"k8s.io/client-go/rest"
@@ -147,6 +149,7 @@ func addControllersToManager(mgr manager.Manager) error {
 
 	if err := (&ClowdAppReconciler{
 		Client:    mgr.GetClient(),
+		APIReader: mgr.GetAPIReader(),
 		Log:       ctrl.Log.WithName("controllers").WithName("ClowdApp"),
 		Scheme:    mgr.GetScheme(),
 		HashCache: &AppHashCache,
@@ -172,6 +175,15 @@ func addControllersToManager(mgr manager.Manager) error {
 		setupLog.Error(err, "unable to create controller", "controller", "ClowdJobInvocation")
 		return err
 	}
+	if err := mgr.Add(&ConsumerLagCollector{
+		Client:   mgr.GetClient(),
+		Log:      ctrl.Log.WithName("collectors").WithName("ConsumerLag"),
+		Interval: time.Minute,
+		Query:    kafka.QueryPrometheus,
+	}); err != nil {
+		setupLog.Error(err, "unable to add collector", "collector", "ConsumerLag")
+		return err
+	}
 	return nil
 }
 
@@ -181,6 +193,14 @@ func setupWebhooks(mgr manager.Manager, enableWebHooks bool) error {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Captain")
 			return err
 		}
+		if err := (&crd.ClowdEnvironment{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClowdEnvironment")
+			return err
+		}
+		if err := (&crd.ClowdJobInvocation{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClowdJobInvocation")
+			return err
+		}
 		mgr.GetWebhookServer().Register(
 			"/mutate-pod",
 			&webhook.Admission{
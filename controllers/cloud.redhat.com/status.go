@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"sort"
@@ -10,7 +11,10 @@ import (
 
 	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
 	apps "k8s.io/api/apps/v1"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -21,6 +25,7 @@ import (
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/clowderconfig"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/errors"
 	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/object"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
 )
 
 func deploymentStatusChecker(deployment apps.Deployment) bool {
@@ -42,6 +47,22 @@ func deploymentStatusChecker(deployment apps.Deployment) bool {
 	return false
 }
 
+func statefulSetStatusChecker(statefulSet apps.StatefulSet) bool {
+	if statefulSet.Generation > statefulSet.Status.ObservedGeneration {
+		// The status on this resource needs to update
+		return false
+	}
+
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+
+	return statefulSet.Status.ReadyReplicas == replicas &&
+		statefulSet.Status.UpdatedReplicas == replicas &&
+		statefulSet.Status.CurrentRevision == statefulSet.Status.UpdateRevision
+}
+
 func kafkaStatusChecker(kafka strimzi.Kafka) bool {
 	// nil checks needed since these are all pointers in strimzi-client-go
 	if kafka.Status == nil {
@@ -144,6 +165,181 @@ func countDeployments(ctx context.Context, pClient client.Client, o object.Clowd
 	return managedDeployments, readyDeployments, msg, nil
 }
 
+// describeDeployments returns a readiness breakdown of every Deployment and StatefulSet owned by the
+// ClowdObject. Pods are only inspected for workloads that are not ready, to find out why.
+func describeDeployments(ctx context.Context, pClient client.Client, podReader client.Reader, o object.ClowdObject, namespaces []string) ([]crd.DeploymentReadiness, error) {
+	details := []crd.DeploymentReadiness{}
+
+	for _, namespace := range namespaces {
+		deployments := apps.DeploymentList{}
+		if err := pClient.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+
+		for _, deployment := range deployments.Items {
+			if !isOwnedBy(&deployment, o) {
+				continue
+			}
+
+			detail := crd.DeploymentReadiness{
+				Name:               deployment.Name,
+				ReadyReplicas:      deployment.Status.ReadyReplicas,
+				UpdatedReplicas:    deployment.Status.UpdatedReplicas,
+				ObservedGeneration: deployment.Status.ObservedGeneration,
+				Ready:              deploymentStatusChecker(deployment),
+			}
+			if deployment.Spec.Replicas != nil {
+				detail.DesiredReplicas = *deployment.Spec.Replicas
+			}
+
+			if err := describeFailure(ctx, podReader, &detail, deployment.Namespace, deployment.Spec.Selector); err != nil {
+				return nil, err
+			}
+
+			details = append(details, detail)
+		}
+
+		statefulSets := apps.StatefulSetList{}
+		if err := pClient.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+
+		for _, statefulSet := range statefulSets.Items {
+			if !isOwnedBy(&statefulSet, o) {
+				continue
+			}
+
+			detail := crd.DeploymentReadiness{
+				Name:               statefulSet.Name,
+				ReadyReplicas:      statefulSet.Status.ReadyReplicas,
+				UpdatedReplicas:    statefulSet.Status.UpdatedReplicas,
+				ObservedGeneration: statefulSet.Status.ObservedGeneration,
+				Ready:              statefulSetStatusChecker(statefulSet),
+			}
+			if statefulSet.Spec.Replicas != nil {
+				detail.DesiredReplicas = *statefulSet.Spec.Replicas
+			}
+
+			if err := describeFailure(ctx, podReader, &detail, statefulSet.Namespace, statefulSet.Spec.Selector); err != nil {
+				return nil, err
+			}
+
+			details = append(details, detail)
+		}
+	}
+
+	sort.Slice(details, func(i, j int) bool {
+		return details[i].Name < details[j].Name
+	})
+
+	return details, nil
+}
+
+// describeFailurePodLimit caps the pods listed to describe the failure of a single workload.
+const describeFailurePodLimit = 50
+
+// describeFailure fills in the failure reason of a workload that is not ready from its pods.
+func describeFailure(ctx context.Context, podReader client.Reader, detail *crd.DeploymentReadiness, namespace string, selector *metav1.LabelSelector) error {
+	if detail.Ready || selector == nil {
+		return nil
+	}
+
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return err
+	}
+
+	pods := core.PodList{}
+	err = podReader.List(
+		ctx, &pods,
+		client.InNamespace(namespace),
+		client.MatchingLabelsSelector{Selector: labelSelector},
+		client.Limit(describeFailurePodLimit),
+	)
+	if err != nil {
+		return err
+	}
+
+	detail.FailureReason, detail.FailureMessage = podFailureReason(pods.Items)
+	return nil
+}
+
+func isOwnedBy(obj metav1.Object, o object.ClowdObject) bool {
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.UID == o.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// podFailureReason returns the reason and message of the most recent failure found in the given
+// pods, looking at scheduling first and then at the state of each container.
+func podFailureReason(pods []core.Pod) (string, string) {
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+
+	for _, pod := range pods {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == core.PodScheduled && condition.Status == core.ConditionFalse && condition.Reason != "" {
+				return condition.Reason, condition.Message
+			}
+		}
+
+		statuses := append([]core.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if waiting := status.State.Waiting; waiting != nil {
+				switch waiting.Reason {
+				case "", "ContainerCreating", "PodInitializing":
+				default:
+					return waiting.Reason, waiting.Message
+				}
+			}
+			if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				return terminated.Reason, terminated.Message
+			}
+		}
+	}
+
+	return "", ""
+}
+
+func countStatefulSets(ctx context.Context, pClient client.Client, o object.ClowdObject, namespaces []string) (int32, int32, string, error) {
+	var managedStatefulSets int32
+	var readyStatefulSets int32
+	var brokenStatefulSets []string
+	var msg = ""
+
+	for _, namespace := range namespaces {
+		statefulSets := apps.StatefulSetList{}
+		if err := pClient.List(ctx, &statefulSets, client.InNamespace(namespace)); err != nil {
+			return 0, 0, "", err
+		}
+
+		// filter for resources owned by the ClowdObject and check their status
+		for _, statefulSet := range statefulSets.Items {
+			if !isOwnedBy(&statefulSet, o) {
+				continue
+			}
+			managedStatefulSets++
+			if statefulSetStatusChecker(statefulSet) {
+				readyStatefulSets++
+			} else {
+				brokenStatefulSets = append(brokenStatefulSets, fmt.Sprintf("%s/%s", statefulSet.Name, statefulSet.Namespace))
+			}
+		}
+	}
+
+	if len(brokenStatefulSets) > 0 {
+		sort.Strings(brokenStatefulSets)
+		msg = fmt.Sprintf("broken statefulsets: [%s]", strings.Join(brokenStatefulSets, ", "))
+	}
+
+	return managedStatefulSets, readyStatefulSets, msg, nil
+}
+
 func countKafkas(ctx context.Context, pClient client.Client, o object.ClowdObject, namespaces []string) (int32, int32, string, error) {
 	var managedKafkas int32
 	var readyKafka int32
@@ -362,16 +558,29 @@ func GetAppResourceStatus(ctx context.Context, client client.Client, o *crd.Clow
 	return false, nil
 }
 
-// SetAppResourceStatus the status on the passed ClowdObject interface.
-func SetAppResourceStatus(ctx context.Context, client client.Client, o *crd.ClowdApp) error {
+// SetAppResourceStatus the status on the passed ClowdObject interface. Pods are read through
+// podReader, which should not be backed by the manager's cache, so that pods are not cached
+// cluster wide just to describe failures.
+func SetAppResourceStatus(ctx context.Context, client client.Client, podReader client.Reader, o *crd.ClowdApp) error {
 	stats, _, err := GetAppResourceFigures(ctx, client, o)
 	if err != nil {
 		return err
 	}
 
+	namespaces, err := o.GetNamespacesInEnv(ctx, client)
+	if err != nil {
+		return errors.Wrap("get namespaces: ", err)
+	}
+
+	details, err := describeDeployments(ctx, client, podReader, o, namespaces)
+	if err != nil {
+		return errors.Wrap("describe deploys: ", err)
+	}
+
 	status := o.GetDeploymentStatus()
 	status.ManagedDeployments = stats.ManagedDeployments
 	status.ReadyDeployments = stats.ReadyDeployments
+	status.Details = details
 
 	return nil
 }
@@ -400,6 +609,16 @@ func GetAppResourceFigures(ctx context.Context, client client.Client, o *crd.Clo
 		msgs = append(msgs, msg)
 	}
 
+	managedDeployments, readyDeployments, msg, err = countStatefulSets(ctx, client, o, namespaces)
+	if err != nil {
+		return crd.AppResourceStatus{}, "", errors.Wrap("count statefulsets: ", err)
+	}
+	totalManagedDeployments += managedDeployments
+	totalReadyDeployments += readyDeployments
+	if msg != "" {
+		msgs = append(msgs, msg)
+	}
+
 	msg = fmt.Sprintf("dependency failure: [%s]", strings.Join(msgs, ","))
 	deploymentStats.ManagedDeployments = totalManagedDeployments
 	deploymentStats.ReadyDeployments = totalReadyDeployments
@@ -552,6 +771,10 @@ func SetClowdAppConditions(ctx context.Context, client client.Client, o *crd.Clo
 
 	o.Status.Ready = deploymentStatus
 
+	if err := SetKafkaConnectorStatus(ctx, client, o); err != nil {
+		return err
+	}
+
 	if !equality.Semantic.DeepEqual(*oldStatus, o.Status) {
 		if err := client.Status().Update(ctx, o); err != nil {
 			return err
@@ -648,3 +871,392 @@ func SetClowdJobInvocationConditions(ctx context.Context, client client.Client,
 	}
 	return nil
 }
+
+// providerConditionRefresh is how old the timing on an otherwise unchanged provider condition may
+// get before it is rewritten. Every status write requeues the ClowdEnvironment, so refreshing the
+// timing on every run would keep the environment reconciling in a loop.
+const providerConditionRefresh = 5 * time.Minute
+
+// SetProviderCondition records the outcome of running the named provider on the ClowdEnvironment
+// status. The status is written along with the rest of the conditions in SetClowdEnvConditions.
+func SetProviderCondition(o *crd.ClowdEnvironment, name string, duration time.Duration, err error) {
+	now := metav1.Now()
+
+	condition := crd.ProviderCondition{
+		Name:               name,
+		Status:             metav1.ConditionTrue,
+		Reason:             "ProviderSucceeded",
+		LastSuccessTime:    &now,
+		LastTransitionTime: now,
+		Duration:           metav1.Duration{Duration: duration.Round(time.Millisecond)},
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ProviderFailed"
+		condition.LastError = err.Error()
+		condition.LastSuccessTime = nil
+	}
+
+	existing := getProviderCondition(o, name)
+	if existing != nil {
+		if existing.Status == condition.Status && existing.LastError == condition.LastError {
+			fresh := existing.LastSuccessTime != nil && now.Sub(existing.LastSuccessTime.Time) < providerConditionRefresh
+			if err != nil || fresh {
+				return
+			}
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		if err != nil {
+			condition.LastSuccessTime = existing.LastSuccessTime
+		}
+	}
+
+	setProviderCondition(o, condition)
+}
+
+// SetProviderNotRun records that the named provider was not run because an earlier provider
+// failed. The time and duration of its last successful run are kept.
+func SetProviderNotRun(o *crd.ClowdEnvironment, name string) {
+	condition := crd.ProviderCondition{
+		Name:               name,
+		Status:             metav1.ConditionUnknown,
+		Reason:             "ProviderNotRun",
+		LastTransitionTime: metav1.Now(),
+	}
+
+	if existing := getProviderCondition(o, name); existing != nil {
+		if existing.Status == condition.Status {
+			return
+		}
+		condition.LastSuccessTime = existing.LastSuccessTime
+		condition.Duration = existing.Duration
+	}
+
+	setProviderCondition(o, condition)
+}
+
+func getProviderCondition(o *crd.ClowdEnvironment, name string) *crd.ProviderCondition {
+	for i := range o.Status.ProviderConditions {
+		if o.Status.ProviderConditions[i].Name == name {
+			return &o.Status.ProviderConditions[i]
+		}
+	}
+	return nil
+}
+
+func setProviderCondition(o *crd.ClowdEnvironment, condition crd.ProviderCondition) {
+	if existing := getProviderCondition(o, condition.Name); existing != nil {
+		*existing = condition
+		return
+	}
+	o.Status.ProviderConditions = append(o.Status.ProviderConditions, condition)
+}
+
+// SetPreDeployCondition records the state of the app's preDeploy jobs on the app and returns true
+// once every one of them has succeeded, at which point the deployments may roll out. When released
+// the deployments roll out whatever the state of the jobs.
+func SetPreDeployCondition(o *crd.ClowdApp, jobs []batch.Job, released bool) bool {
+	if len(jobs) == 0 {
+		cond.Delete(o, crd.PreDeployJobsComplete)
+		return true
+	}
+
+	condition := metav1.Condition{
+		Type:    crd.PreDeployJobsComplete,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PreDeployJobsSucceeded",
+		Message: "All preDeploy jobs succeeded",
+	}
+
+	var running []string
+	for _, job := range jobs {
+		if failed, msg := preDeployJobFailed(job); failed && !released {
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "PreDeployJobFailed"
+			condition.Message = fmt.Sprintf("preDeploy job [%s] failed, deployments will not be updated: %s", job.Name, msg)
+			cond.Set(o, condition)
+			return false
+		}
+		if !preDeployJobSucceeded(job) {
+			running = append(running, job.Name)
+		}
+	}
+
+	switch {
+	case len(running) > 0 && released:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "PreDeployHoldReleased"
+		condition.Message = fmt.Sprintf("preDeploy jobs [%s] have not succeeded, deployments are updated as the hold was released", strings.Join(running, ","))
+	case len(running) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "PreDeployJobsRunning"
+		condition.Message = fmt.Sprintf("Waiting on preDeploy jobs: [%s]", strings.Join(running, ","))
+	}
+
+	cond.Set(o, condition)
+	return condition.Status == metav1.ConditionTrue || released
+}
+
+// SetKafkaTopicDriftCondition records on the app whether the live settings of its topics match
+// those declared for them. The condition is dropped when the topics could not be checked.
+func SetKafkaTopicDriftCondition(o *crd.ClowdApp, drift []kafka.TopicDrift, checked bool) {
+	if !checked {
+		cond.Delete(o, crd.KafkaTopicsInSync)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:    crd.KafkaTopicsInSync,
+		Status:  metav1.ConditionTrue,
+		Reason:  "KafkaTopicsInSync",
+		Message: "All kafka topics match their declared settings",
+	}
+
+	if len(drift) > 0 {
+		differences := []string{}
+		for _, d := range drift {
+			differences = append(differences, d.String())
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "KafkaTopicsDrifted"
+		condition.Message = fmt.Sprintf("Kafka topics differ from their declared settings: [%s]", strings.Join(differences, "; "))
+	}
+
+	cond.Set(o, condition)
+}
+
+// SetKafkaTopicDriftUnchecked records on the app that its topics could not be checked for drift.
+func SetKafkaTopicDriftUnchecked(o *crd.ClowdApp, err error) {
+	cond.Set(o, metav1.Condition{
+		Type:    crd.KafkaTopicsInSync,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "KafkaTopicsUnchecked",
+		Message: fmt.Sprintf("Could not check kafka topics: %s", err),
+	})
+}
+
+// SetKafkaConsumerLagCondition records on the app whether the lag of each of its consumer groups
+// is within the environment's threshold. The condition is dropped when no threshold is set.
+func SetKafkaConsumerLagCondition(o *crd.ClowdApp, lags []kafka.ConsumerLag, threshold int64) {
+	if threshold <= 0 {
+		cond.Delete(o, crd.KafkaConsumersCaughtUp)
+		return
+	}
+
+	lagging := []string{}
+	for _, lag := range lags {
+		if lag.Lag > threshold {
+			lagging = append(lagging, lag.String())
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:    crd.KafkaConsumersCaughtUp,
+		Status:  metav1.ConditionTrue,
+		Reason:  "KafkaConsumersCaughtUp",
+		Message: fmt.Sprintf("All kafka consumer groups have a lag of at most %d", threshold),
+	}
+
+	if len(lagging) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "KafkaConsumerLagExceeded"
+		condition.Message = fmt.Sprintf("Kafka consumer groups have a lag over %d: [%s]", threshold, strings.Join(lagging, "; "))
+	}
+
+	cond.Set(o, condition)
+}
+
+// SetKafkaConsumerLagUnchecked records on the app that the lag of its consumer groups could not be
+// read.
+func SetKafkaConsumerLagUnchecked(o *crd.ClowdApp, err error) {
+	cond.Set(o, metav1.Condition{
+		Type:    crd.KafkaConsumersCaughtUp,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "KafkaConsumerLagUnchecked",
+		Message: fmt.Sprintf("Could not read the kafka consumer lag: %s", err),
+	})
+}
+
+// SetObjectStoreBucketsCondition records on the app whether the settings of its provisioned
+// buckets match the options declared for them. The condition is dropped when there is nothing to
+// check.
+func SetObjectStoreBucketsCondition(o *crd.ClowdApp, mismatches []string, checked bool) {
+	if !checked {
+		cond.Delete(o, crd.ObjectStoreBucketsInSync)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:    crd.ObjectStoreBucketsInSync,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ObjectStoreBucketsInSync",
+		Message: "All buckets match their declared options",
+	}
+
+	if len(mismatches) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ObjectStoreBucketsMismatched"
+		condition.Message = fmt.Sprintf("Buckets from app-interface do not match the ClowdApp: [%s]", strings.Join(mismatches, "; "))
+	}
+
+	cond.Set(o, condition)
+}
+
+// SetObjectStoreSeedCondition records on the app whether the seeds of its buckets have been
+// uploaded. The condition is dropped when none of its buckets is seeded.
+func SetObjectStoreSeedCondition(o *crd.ClowdApp, pending []string, failed []string, checked bool) {
+	if !checked {
+		cond.Delete(o, crd.ObjectStoreBucketsSeeded)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:    crd.ObjectStoreBucketsSeeded,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ObjectStoreBucketsSeeded",
+		Message: "All bucket seeds have been uploaded",
+	}
+
+	switch {
+	case len(failed) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ObjectStoreSeedFailed"
+		condition.Message = fmt.Sprintf("Bucket seeds failed: [%s]", strings.Join(failed, "; "))
+	case len(pending) > 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ObjectStoreSeeding"
+		condition.Message = fmt.Sprintf("Seeding buckets: [%s]", strings.Join(pending, ","))
+	}
+
+	cond.Set(o, condition)
+}
+
+func preDeployJobSucceeded(job batch.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batch.JobComplete && c.Status == core.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func preDeployJobFailed(job batch.Job) (bool, string) {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batch.JobFailed && c.Status == core.ConditionTrue {
+			return true, c.Message
+		}
+	}
+	return false, ""
+}
+
+// SetKafkaConnectorStatus records the state of the app's KafkaConnectors in its status and in the
+// KafkaConnectorsReady condition.
+func SetKafkaConnectorStatus(ctx context.Context, c client.Client, o *crd.ClowdApp) error {
+	if len(o.Spec.KafkaConnectors) == 0 {
+		setKafkaConnectorStatus(o, nil)
+		return nil
+	}
+
+	connectors := strimzi.KafkaConnectorList{}
+	if err := c.List(ctx, &connectors, kafka.GetKafkaConnectorSelector(o)); err != nil && !meta.IsNoMatchError(err) {
+		return err
+	}
+
+	setKafkaConnectorStatus(o, connectors.Items)
+	return nil
+}
+
+func setKafkaConnectorStatus(o *crd.ClowdApp, connectors []strimzi.KafkaConnector) {
+	if len(o.Spec.KafkaConnectors) == 0 {
+		o.Status.KafkaConnectors = nil
+		cond.Delete(o, crd.KafkaConnectorsReady)
+		return
+	}
+
+	byName := map[string]strimzi.KafkaConnector{}
+	for _, connector := range connectors {
+		byName[connector.Name] = connector
+	}
+
+	statuses := []crd.KafkaConnectorReadiness{}
+	var notReady []string
+	for i := range o.Spec.KafkaConnectors {
+		name := kafka.GetKafkaConnectorName(o, &o.Spec.KafkaConnectors[i])
+		status := crd.KafkaConnectorReadiness{Name: name}
+
+		if connector, ok := byName[name]; ok {
+			status.Ready, status.Message = kafkaConnectorReady(connector)
+			status.State = kafkaConnectorState(connector)
+		} else {
+			status.Message = "KafkaConnector has not been created"
+		}
+
+		if !status.Ready {
+			notReady = append(notReady, name)
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	o.Status.KafkaConnectors = statuses
+
+	condition := metav1.Condition{
+		Type:    crd.KafkaConnectorsReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "KafkaConnectorsReady",
+		Message: "All kafka connectors ready",
+	}
+	if len(notReady) > 0 {
+		sort.Strings(notReady)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "KafkaConnectorsNotReady"
+		condition.Message = fmt.Sprintf("Waiting on kafka connectors: [%s]", strings.Join(notReady, ","))
+	}
+	cond.Set(o, condition)
+}
+
+func kafkaConnectorReady(connector strimzi.KafkaConnector) (bool, string) {
+	// nil checks needed since these are all pointers in strimzi-client-go
+	if connector.Status == nil {
+		return false, ""
+	}
+
+	if connector.Status.ObservedGeneration != nil && connector.Generation > int64(*connector.Status.ObservedGeneration) {
+		return false, ""
+	}
+
+	for _, condition := range connector.Status.Conditions {
+		if condition.Type == nil || condition.Status == nil {
+			continue
+		}
+		if *condition.Type == "Ready" && *condition.Status == "True" {
+			return true, ""
+		}
+		if *condition.Status == "True" && condition.Message != nil {
+			return false, *condition.Message
+		}
+	}
+
+	return false, ""
+}
+
+// kafkaConnectorState returns the connector state Kafka Connect reported to Strimzi.
+func kafkaConnectorState(connector strimzi.KafkaConnector) string {
+	if connector.Status == nil || connector.Status.ConnectorStatus == nil {
+		return ""
+	}
+
+	connectorStatus := struct {
+		Connector struct {
+			State string `json:"state"`
+		} `json:"connector"`
+	}{}
+	if err := json.Unmarshal(connector.Status.ConnectorStatus.Raw, &connectorStatus); err != nil {
+		return ""
+	}
+	return connectorStatus.Connector.State
+}
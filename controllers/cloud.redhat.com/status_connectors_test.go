@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"testing"
+
+	strimzi "github.com/RedHatInsights/strimzi-client-go/apis/kafka.strimzi.io/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cond "sigs.k8s.io/cluster-api/util/conditions"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/rhc-osdk-utils/utils"
+)
+
+func kafkaConnector(name string, ready bool, state string) strimzi.KafkaConnector {
+	status := "False"
+	if ready {
+		status = "True"
+	}
+
+	connector := strimzi.KafkaConnector{}
+	connector.Name = name
+	connector.Status = &strimzi.KafkaConnectorStatus{
+		Conditions: []strimzi.KafkaConnectorStatusConditionsElem{{
+			Type:   utils.StringPtr("Ready"),
+			Status: utils.StringPtr(status),
+		}},
+		ConnectorStatus: &apiextensions.JSON{Raw: []byte(`{"connector":{"state":"` + state + `"}}`)},
+	}
+	return connector
+}
+
+func TestSetKafkaConnectorStatus(t *testing.T) {
+	app := &crd.ClowdApp{}
+	app.Name = "app"
+
+	setKafkaConnectorStatus(app, nil)
+	assert.Nil(t, app.Status.KafkaConnectors)
+	assert.Nil(t, cond.Get(app, crd.KafkaConnectorsReady), "apps without connectors have no condition")
+
+	app.Spec.KafkaConnectors = []crd.KafkaConnectorSpec{{Name: "source"}, {Name: "sink"}}
+
+	setKafkaConnectorStatus(app, []strimzi.KafkaConnector{
+		kafkaConnector("app-source", true, "RUNNING"),
+	})
+	require.Len(t, app.Status.KafkaConnectors, 2)
+	assert.Equal(t, crd.KafkaConnectorReadiness{Name: "app-sink", Message: "KafkaConnector has not been created"}, app.Status.KafkaConnectors[0])
+	assert.Equal(t, crd.KafkaConnectorReadiness{Name: "app-source", State: "RUNNING", Ready: true}, app.Status.KafkaConnectors[1])
+
+	notReady := cond.Get(app, crd.KafkaConnectorsReady)
+	assert.Equal(t, metav1.ConditionFalse, notReady.Status)
+	assert.Equal(t, "KafkaConnectorsNotReady", notReady.Reason)
+	assert.Equal(t, "Waiting on kafka connectors: [app-sink]", notReady.Message)
+
+	setKafkaConnectorStatus(app, []strimzi.KafkaConnector{
+		kafkaConnector("app-source", true, "RUNNING"),
+		kafkaConnector("app-sink", true, "PAUSED"),
+	})
+	assert.Equal(t, "PAUSED", app.Status.KafkaConnectors[0].State)
+	assert.Equal(t, metav1.ConditionTrue, cond.Get(app, crd.KafkaConnectorsReady).Status)
+
+	app.Spec.KafkaConnectors = nil
+	setKafkaConnectorStatus(app, nil)
+	assert.Nil(t, app.Status.KafkaConnectors)
+	assert.Nil(t, cond.Get(app, crd.KafkaConnectorsReady), "the condition is dropped with the last connector")
+}
@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+func failingPod(name string, age time.Duration, status core.PodStatus) core.Pod {
+	return core.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "test",
+			Labels:            map[string]string{"pod": "puptoo-processor"},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+		},
+		Status: status,
+	}
+}
+
+func waitingStatus(reason string) core.PodStatus {
+	return core.PodStatus{
+		ContainerStatuses: []core.ContainerStatus{{
+			State: core.ContainerState{
+				Waiting: &core.ContainerStateWaiting{Reason: reason, Message: reason + " message"},
+			},
+		}},
+	}
+}
+
+func TestPodFailureReason(t *testing.T) {
+	unschedulable := core.PodStatus{
+		Conditions: []core.PodCondition{{
+			Type:    core.PodScheduled,
+			Status:  core.ConditionFalse,
+			Reason:  "Unschedulable",
+			Message: "0/3 nodes are available",
+		}},
+	}
+
+	tests := []struct {
+		name   string
+		pods   []core.Pod
+		reason string
+	}{
+		{
+			name:   "no pods",
+			reason: "",
+		},
+		{
+			name:   "container still creating",
+			pods:   []core.Pod{failingPod("a", time.Minute, waitingStatus("ContainerCreating"))},
+			reason: "",
+		},
+		{
+			name:   "unschedulable",
+			pods:   []core.Pod{failingPod("a", time.Minute, unschedulable)},
+			reason: "Unschedulable",
+		},
+		{
+			name: "newest pod wins",
+			pods: []core.Pod{
+				failingPod("old", time.Hour, waitingStatus("CrashLoopBackOff")),
+				failingPod("new", time.Minute, waitingStatus("ImagePullBackOff")),
+			},
+			reason: "ImagePullBackOff",
+		},
+		{
+			name: "failed init container",
+			pods: []core.Pod{failingPod("a", time.Minute, core.PodStatus{
+				InitContainerStatuses: []core.ContainerStatus{{
+					State: core.ContainerState{
+						Terminated: &core.ContainerStateTerminated{ExitCode: 1, Reason: "Error"},
+					},
+				}},
+			})},
+			reason: "Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, _ := podFailureReason(tt.pods)
+			assert.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestDescribeDeployments(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, crd.AddToScheme(scheme))
+	assert.NoError(t, apps.AddToScheme(scheme))
+	assert.NoError(t, core.AddToScheme(scheme))
+
+	app := &crd.ClowdApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "puptoo", Namespace: "test", UID: "app-uid"},
+	}
+
+	deployment := func(name string, ready bool) *apps.Deployment {
+		d := &apps.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "test",
+				Generation:      2,
+				OwnerReferences: []metav1.OwnerReference{{UID: app.UID}},
+			},
+			Spec: apps.DeploymentSpec{
+				Replicas: ptr.To(int32(2)),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"pod": name}},
+			},
+			Status: apps.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 2},
+		}
+		if ready {
+			d.Status.ReadyReplicas = 2
+			d.Status.AvailableReplicas = 2
+			d.Status.Conditions = []apps.DeploymentCondition{{Type: "Available", Status: "True"}}
+		}
+		return d
+	}
+
+	unowned := deployment("someone-else", false)
+	unowned.OwnerReferences = nil
+	pod := failingPod("puptoo-processor-abc", time.Minute, waitingStatus("CrashLoopBackOff"))
+
+	pClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		deployment("puptoo-processor", false), deployment("puptoo-api", true), unowned, &pod,
+	).Build()
+
+	details, err := describeDeployments(context.Background(), pClient, pClient, app, []string{"test"})
+	assert.NoError(t, err)
+	assert.Equal(t, []crd.DeploymentReadiness{{
+		Name:               "puptoo-api",
+		DesiredReplicas:    2,
+		ReadyReplicas:      2,
+		UpdatedReplicas:    2,
+		ObservedGeneration: 2,
+		Ready:              true,
+	}, {
+		Name:               "puptoo-processor",
+		DesiredReplicas:    2,
+		UpdatedReplicas:    2,
+		ObservedGeneration: 2,
+		FailureReason:      "CrashLoopBackOff",
+		FailureMessage:     "CrashLoopBackOff message",
+	}}, details)
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cond "sigs.k8s.io/cluster-api/util/conditions"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+	"github.com/RedHatInsights/clowder/controllers/cloud.redhat.com/providers/kafka"
+)
+
+func TestSetKafkaTopicDriftCondition(t *testing.T) {
+	app := &crd.ClowdApp{}
+
+	SetKafkaTopicDriftCondition(app, nil, true)
+	assert.Equal(t, metav1.ConditionTrue, cond.Get(app, crd.KafkaTopicsInSync).Status)
+
+	SetKafkaTopicDriftCondition(app, []kafka.TopicDrift{
+		{Topic: "events", Setting: "partitions", Declared: "6", Live: "3"},
+		{Topic: "events", Setting: "retention.ms", Declared: "86400000", Live: "unset"},
+	}, true)
+	drifted := cond.Get(app, crd.KafkaTopicsInSync)
+	assert.Equal(t, metav1.ConditionFalse, drifted.Status)
+	assert.Equal(t, "KafkaTopicsDrifted", drifted.Reason)
+	assert.Equal(t, "Kafka topics differ from their declared settings: [events partitions: declared 6, live 3; events retention.ms: declared 86400000, live unset]", drifted.Message)
+
+	SetKafkaTopicDriftUnchecked(app, errors.New("sasl mechanism OAUTHBEARER is not supported"))
+	unchecked := cond.Get(app, crd.KafkaTopicsInSync)
+	assert.Equal(t, metav1.ConditionUnknown, unchecked.Status)
+	assert.Equal(t, "KafkaTopicsUnchecked", unchecked.Reason)
+	assert.Contains(t, unchecked.Message, "OAUTHBEARER")
+
+	SetKafkaTopicDriftCondition(app, nil, false)
+	assert.Nil(t, cond.Get(app, crd.KafkaTopicsInSync), "the condition is dropped when the topics cannot be checked")
+}
+
+func TestSetObjectStoreBucketsCondition(t *testing.T) {
+	app := &crd.ClowdApp{}
+
+	SetObjectStoreBucketsCondition(app, []string{}, true)
+	assert.Equal(t, metav1.ConditionTrue, cond.Get(app, crd.ObjectStoreBucketsInSync).Status)
+
+	SetObjectStoreBucketsCondition(app, []string{"logs: versioning is not enabled"}, true)
+	mismatched := cond.Get(app, crd.ObjectStoreBucketsInSync)
+	assert.Equal(t, metav1.ConditionFalse, mismatched.Status)
+	assert.Equal(t, "ObjectStoreBucketsMismatched", mismatched.Reason)
+	assert.Equal(t, "Buckets from app-interface do not match the ClowdApp: [logs: versioning is not enabled]", mismatched.Message)
+
+	SetObjectStoreBucketsCondition(app, nil, false)
+	assert.Nil(t, cond.Get(app, crd.ObjectStoreBucketsInSync), "the condition is dropped when there is nothing to check")
+}
+
+func TestSetObjectStoreSeedCondition(t *testing.T) {
+	app := &crd.ClowdApp{}
+
+	SetObjectStoreSeedCondition(app, []string{"fixtures"}, []string{}, true)
+	seeding := cond.Get(app, crd.ObjectStoreBucketsSeeded)
+	assert.Equal(t, metav1.ConditionFalse, seeding.Status)
+	assert.Equal(t, "ObjectStoreSeeding", seeding.Reason)
+	assert.Equal(t, "Seeding buckets: [fixtures]", seeding.Message)
+
+	SetObjectStoreSeedCondition(app, []string{}, []string{"fixtures: unexpected status 404 Not Found"}, true)
+	failed := cond.Get(app, crd.ObjectStoreBucketsSeeded)
+	assert.Equal(t, metav1.ConditionFalse, failed.Status)
+	assert.Equal(t, "ObjectStoreSeedFailed", failed.Reason)
+
+	SetObjectStoreSeedCondition(app, []string{}, []string{}, true)
+	assert.Equal(t, metav1.ConditionTrue, cond.Get(app, crd.ObjectStoreBucketsSeeded).Status)
+
+	SetObjectStoreSeedCondition(app, nil, nil, false)
+	assert.Nil(t, cond.Get(app, crd.ObjectStoreBucketsSeeded), "the condition is dropped when no bucket is seeded")
+}
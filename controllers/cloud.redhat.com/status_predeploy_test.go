@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batch "k8s.io/api/batch/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cond "sigs.k8s.io/cluster-api/util/conditions"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+func preDeployJob(name string, conditionType batch.JobConditionType, msg string) batch.Job {
+	job := batch.Job{}
+	job.Name = name
+	if conditionType != "" {
+		job.Status.Conditions = []batch.JobCondition{{
+			Type:    conditionType,
+			Status:  core.ConditionTrue,
+			Message: msg,
+		}}
+	}
+	return job
+}
+
+func TestSetPreDeployCondition(t *testing.T) {
+	app := &crd.ClowdApp{}
+
+	assert.True(t, SetPreDeployCondition(app, nil, false), "apps without preDeploy jobs are never held")
+	assert.Nil(t, cond.Get(app, crd.PreDeployJobsComplete))
+
+	assert.False(t, SetPreDeployCondition(app, []batch.Job{
+		preDeployJob("app-migrate-1234abcd", batch.JobComplete, ""),
+		preDeployJob("app-seed-1234abcd", "", ""),
+	}, false))
+	running := cond.Get(app, crd.PreDeployJobsComplete)
+	assert.Equal(t, metav1.ConditionFalse, running.Status)
+	assert.Equal(t, "PreDeployJobsRunning", running.Reason)
+	assert.Contains(t, running.Message, "app-seed-1234abcd")
+
+	assert.False(t, SetPreDeployCondition(app, []batch.Job{
+		preDeployJob("app-migrate-1234abcd", batch.JobFailed, "BackoffLimitExceeded"),
+	}, false))
+	failed := cond.Get(app, crd.PreDeployJobsComplete)
+	assert.Equal(t, "PreDeployJobFailed", failed.Reason)
+	assert.Contains(t, failed.Message, "BackoffLimitExceeded")
+
+	assert.True(t, SetPreDeployCondition(app, []batch.Job{
+		preDeployJob("app-migrate-1234abcd", batch.JobComplete, ""),
+	}, false))
+	assert.Equal(t, metav1.ConditionTrue, cond.Get(app, crd.PreDeployJobsComplete).Status)
+
+	assert.True(t, SetPreDeployCondition(app, []batch.Job{}, false))
+	assert.Nil(t, cond.Get(app, crd.PreDeployJobsComplete), "the condition is dropped with the last preDeploy job")
+}
+
+func TestSetPreDeployConditionReleased(t *testing.T) {
+	app := &crd.ClowdApp{}
+
+	assert.True(t, SetPreDeployCondition(app, []batch.Job{
+		preDeployJob("app-migrate-1234abcd", batch.JobFailed, "BackoffLimitExceeded"),
+	}, true), "a released hold lets the deployments roll out")
+	released := cond.Get(app, crd.PreDeployJobsComplete)
+	assert.Equal(t, metav1.ConditionFalse, released.Status)
+	assert.Equal(t, "PreDeployHoldReleased", released.Reason)
+	assert.Contains(t, released.Message, "app-migrate-1234abcd")
+
+	assert.True(t, SetPreDeployCondition(app, []batch.Job{
+		preDeployJob("app-migrate-1234abcd", batch.JobComplete, ""),
+	}, true))
+	assert.Equal(t, metav1.ConditionTrue, cond.Get(app, crd.PreDeployJobsComplete).Status)
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	crd "github.com/RedHatInsights/clowder/apis/cloud.redhat.com/v1alpha1"
+)
+
+func TestSetProviderCondition(t *testing.T) {
+	env := &crd.ClowdEnvironment{}
+
+	SetProviderCondition(env, "kafka", 1500*time.Microsecond, nil)
+	SetProviderCondition(env, "database", time.Second, nil)
+	assert.Len(t, env.Status.ProviderConditions, 2)
+
+	kafka := *getProviderCondition(env, "kafka")
+	assert.Equal(t, metav1.ConditionTrue, kafka.Status)
+	assert.Equal(t, 2*time.Millisecond, kafka.Duration.Duration)
+	assert.NotNil(t, kafka.LastSuccessTime)
+
+	SetProviderCondition(env, "kafka", time.Minute, nil)
+	assert.Equal(t, kafka, *getProviderCondition(env, "kafka"), "a recent success is not rewritten")
+
+	SetProviderCondition(env, "kafka", time.Second, errors.New("broker unreachable"))
+	failed := *getProviderCondition(env, "kafka")
+	assert.Equal(t, metav1.ConditionFalse, failed.Status)
+	assert.Equal(t, "broker unreachable", failed.LastError)
+	assert.Equal(t, kafka.LastSuccessTime, failed.LastSuccessTime, "the last success is kept on failure")
+
+	SetProviderNotRun(env, "database")
+	database := *getProviderCondition(env, "database")
+	assert.Equal(t, metav1.ConditionUnknown, database.Status)
+	assert.Equal(t, time.Second, database.Duration.Duration)
+
+	stale := metav1.NewTime(time.Now().Add(-providerConditionRefresh))
+	env.Status.ProviderConditions[0] = kafka
+	env.Status.ProviderConditions[0].LastSuccessTime = &stale
+	SetProviderCondition(env, "kafka", time.Minute, nil)
+	refreshed := *getProviderCondition(env, "kafka")
+	assert.Equal(t, time.Minute, refreshed.Duration.Duration, "a stale success is refreshed")
+	assert.Equal(t, kafka.LastTransitionTime, refreshed.LastTransitionTime)
+}
@@ -2,9 +2,15 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -14,11 +20,15 @@ import (
 	"go.uber.org/zap"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
 
 	"github.com/RedHatInsights/rhc-osdk-utils/logging"
 
@@ -53,7 +63,113 @@ func runAPIServer() {
 	_ = server.ListenAndServe()
 }
 
+// readObjects decodes every YAML document in the file.
+func readObjects(path string) ([]runtime.Object, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint:errcheck  // no need to check error return value
+
+	decoder := serializer.NewCodecFactory(controllers.Scheme).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+
+	objs := []runtime.Object{}
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// render prints every object Clowder would write for the ClowdEnvironment and ClowdApps in the
+// given files, without a cluster.
+func render(args []string) error {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "Log provider output to stderr.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s render [-v] FILE...\n\n", os.Args[0])
+		fmt.Fprintln(fs.Output(), "Renders the objects Clowder would create for the ClowdEnvironment and ClowdApps in FILE.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no files given")
+	}
+
+	var env *crd.ClowdEnvironment
+	apps := []crd.ClowdApp{}
+	for _, path := range fs.Args() {
+		objs, err := readObjects(path)
+		if err != nil {
+			return err
+		}
+		for _, obj := range objs {
+			switch o := obj.(type) {
+			case *crd.ClowdEnvironment:
+				if env != nil {
+					return fmt.Errorf("%s: only one ClowdEnvironment can be rendered at a time", path)
+				}
+				env = o
+			case *crd.ClowdApp:
+				apps = append(apps, *o)
+			default:
+				return fmt.Errorf("%s: cannot render %s", path, obj.GetObjectKind().GroupVersionKind().Kind)
+			}
+		}
+	}
+	if env == nil {
+		return fmt.Errorf("no ClowdEnvironment given")
+	}
+
+	log := logr.Discard()
+	if *verbose {
+		logger, err := zap.NewDevelopment()
+		if err != nil {
+			return err
+		}
+		log = zapr.NewLogger(logger)
+	}
+
+	objs, err := controllers.Render(context.Background(), log, env, apps)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n%s", out)
+	}
+	return nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := render(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
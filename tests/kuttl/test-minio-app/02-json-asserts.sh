@@ -29,3 +29,10 @@ jq -r '.objectStore.hostname == "test-minio-app-minio.test-minio-app.svc"' -e <
 jq -r '.objectStore.port == 9000' -e < ${TMP_DIR}/test-minio-app-json
 jq -r '.objectStore.accessKey != ""' -e < ${TMP_DIR}/test-minio-app-json
 jq -r '.objectStore.secretKey != ""' -e < ${TMP_DIR}/test-minio-app-json
+
+# The app gets its own MinIO keys, kept in an app-owned secret, rather than the root ones
+kubectl get secret --namespace=test-minio-app puptoo-objectstore -o json > ${TMP_DIR}/test-minio-app-credentials
+APP_ACCESS_KEY=$(jq -r '.data.accessKey' < ${TMP_DIR}/test-minio-app-credentials | base64 -d)
+ROOT_ACCESS_KEY=$(kubectl get secret --namespace=test-minio-app test-minio-app-minio -o json | jq -r '.data.accessKey' | base64 -d)
+jq -r --arg key "${APP_ACCESS_KEY}" '.objectStore.accessKey == $key' -e < ${TMP_DIR}/test-minio-app-json
+jq -r --arg key "${ROOT_ACCESS_KEY}" '.objectStore.accessKey != $key' -e < ${TMP_DIR}/test-minio-app-json